@@ -0,0 +1,33 @@
+// Command mockoidc is an OpenID Connect provider for trying social login locally. It signs in
+// whoever fills in its form, so never expose it. Point the server at it with
+//
+//	OIDC_PROVIDERS=mock
+//	OIDC_MOCK_ISSUER=http://localhost:9000
+//	OIDC_MOCK_CLIENT_ID=vibewriter
+//	OIDC_MOCK_CLIENT_SECRET=secret
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+
+	"github.com/harry713j/vibe_writer/internal/identity/oidctest"
+)
+
+func main() {
+	addr := flag.String("addr", ":9000", "address to listen on")
+	issuer := flag.String("issuer", "http://localhost:9000", "issuer url, must be how the server reaches this provider")
+	clientId := flag.String("client-id", "vibewriter", "accepted client id")
+	clientSecret := flag.String("client-secret", "secret", "accepted client secret")
+	flag.Parse()
+
+	provider, err := oidctest.NewProvider(*issuer, *clientId, *clientSecret)
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	log.Println("Mock OIDC provider listening on ", *addr)
+	log.Fatal(http.ListenAndServe(*addr, provider.Handler()))
+}
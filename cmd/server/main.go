@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"log"
 	"os"
 	"time"
@@ -9,6 +10,10 @@ import (
 	"github.com/harry713j/vibe_writer/internal/config"
 	"github.com/harry713j/vibe_writer/internal/db"
 	"github.com/harry713j/vibe_writer/internal/handler"
+	"github.com/harry713j/vibe_writer/internal/identity"
+	"github.com/harry713j/vibe_writer/internal/keyring"
+	"github.com/harry713j/vibe_writer/internal/limiter"
+	"github.com/harry713j/vibe_writer/internal/mailer"
 	"github.com/harry713j/vibe_writer/internal/repo"
 	"github.com/harry713j/vibe_writer/internal/server"
 	"github.com/harry713j/vibe_writer/internal/service"
@@ -28,38 +33,98 @@ func main() {
 	defer db.Close()
 
 	serverConfig := server.LoadServerConfig()
+	siteConfig := config.LoadSiteConfig()
 
 	userRepo := repo.NewUserRepository(db)
 	refreshTokenRepo := repo.NewRefreshTokenRepository(db)
-	jwtSecret := os.Getenv("ACCESS_TOKEN_SECRET")
+	sessionRepo := repo.NewSessionRepository(db)
 	accessTokenTTL := 15 * time.Minute
+	keys, err := keyring.Load(config.LoadKeyConfig())
+
+	if err != nil {
+		log.Fatal("Failed to load jwt keys: ", err)
+	}
+
 	profileRepo := repo.NewUserProfileRepository(db)
 	blogRepo := repo.NewBlogRepository(db)
 	commentRepo := repo.NewCommentRepository(db)
 	likeRepo := repo.NewLikeRepository(db)
 	bookmarkRepo := repo.NewBookmarkRepository(db)
 	followRepo := repo.NewFollowRepository(db)
-
-	authService := service.NewAuthService(userRepo, profileRepo, refreshTokenRepo, jwtSecret, accessTokenTTL)
-	userProfileService := service.NewUserProfileService(profileRepo, userRepo, blogRepo, commentRepo, followRepo)
-	blogService := service.NewBlogService(blogRepo, userRepo, commentRepo, likeRepo, bookmarkRepo)
-	commentService := service.NewCommentService(commentRepo, userRepo, likeRepo)
+	revisionRepo := repo.NewBlogRevisionRepository(db)
+	tagRepo := repo.NewTagRepository(db)
+	trendingRepo := repo.NewTrendingRepository(db)
+	verificationRepo := repo.NewEmailVerificationRepository(db)
+	resetRepo := repo.NewPasswordResetRepository(db)
+	twoFactorRepo := repo.NewTwoFactorRepository(db)
+	moderationRepo := repo.NewModerationRepository(db)
+	personalTokenRepo := repo.NewPersonalTokenRepository(db)
+	identityRepo := repo.NewUserIdentityRepository(db)
+	accountRepo := repo.NewAccountRepository(db)
+	emailChangeRepo := repo.NewEmailChangeRepository(db)
+	mentionRepo := repo.NewMentionRepository(db)
+	notificationRepo := repo.NewNotificationRepository(db)
+	mail := mailer.New(config.LoadMailConfig())
+	attemptStore := limiter.NewMemoryStore()
+
+	authService := service.NewAuthService(userRepo, profileRepo, refreshTokenRepo, sessionRepo, verificationRepo, resetRepo,
+		twoFactorRepo, personalTokenRepo, identityRepo, limiter.New(attemptStore), mail, siteConfig, config.LoadAuthConfig(), keys, accessTokenTTL)
+	notificationService := service.NewNotificationService(notificationRepo, userRepo)
+	mentionService := service.NewMentionService(mentionRepo, userRepo, notificationService, mail, siteConfig)
+	userProfileService := service.NewUserProfileService(profileRepo, userRepo, blogRepo, commentRepo, followRepo,
+		mentionService, notificationService)
+	blogService := service.NewBlogService(blogRepo, userRepo, commentRepo, likeRepo, bookmarkRepo, revisionRepo, tagRepo,
+		followRepo, mentionService, notificationService)
+	commentService := service.NewCommentService(commentRepo, userRepo, likeRepo, blogRepo, mentionService)
 	uploadService := service.NewUploadService()
+	tagService := service.NewTagService(tagRepo, blogRepo)
+	trendingService := service.NewTrendingService(trendingRepo, config.LoadTrendingConfig())
+	syndicationService := service.NewSyndicationService(userRepo, profileRepo, blogRepo, tagRepo, siteConfig)
+	adminService := service.NewAdminService(userRepo, moderationRepo, sessionRepo)
+	socialLoginService := service.NewSocialLoginService(authService, userRepo, profileRepo, identityRepo,
+		identity.NewRegistry(config.LoadOIDCConfig()))
+	accountService := service.NewAccountService(userRepo, profileRepo, accountRepo, sessionRepo, emailChangeRepo, mail,
+		siteConfig, config.LoadAccountConfig())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go blogService.RunScheduledPublisher(ctx, time.Minute)
+	go blogService.RenderMissingHtml()
+	go trendingService.RunRefresher(ctx)
+	go attemptStore.RunJanitor(ctx, time.Minute)
+	go accountService.RunExportWorker(ctx, time.Minute)
+	go accountService.RunDeletionWorker(ctx, time.Hour)
+	go mentionService.RunNotifier(ctx, time.Minute)
 
 	userProfileHandler := handler.NewUserProfileHandler(userProfileService, blogService)
 
 	app := &app.App{
-		AuthService:        authService,
-		UserProfileService: userProfileService,
-		BlogService:        blogService,
-		CommentService:     commentService,
-		UploadService:      uploadService,
-
-		AuthHandler:        handler.NewAuthHandler(authService),
-		UserProfileHandler: userProfileHandler,
-		BlogHandler:        handler.NewBlogHandler(blogService),
-		CommentHandler:     handler.NewCommentHandler(commentService),
-		UploadHandler:      handler.NewUploadHandler(uploadService),
+		AuthService:         authService,
+		SocialLoginService:  socialLoginService,
+		UserProfileService:  userProfileService,
+		BlogService:         blogService,
+		CommentService:      commentService,
+		UploadService:       uploadService,
+		TagService:          tagService,
+		TrendingService:     trendingService,
+		SyndicationService:  syndicationService,
+		AdminService:        adminService,
+		AccountService:      accountService,
+		MentionService:      mentionService,
+		NotificationService: notificationService,
+
+		AuthHandler:         handler.NewAuthHandler(authService),
+		SocialLoginHandler:  handler.NewSocialLoginHandler(socialLoginService, siteConfig),
+		UserProfileHandler:  userProfileHandler,
+		BlogHandler:         handler.NewBlogHandler(blogService, trendingService),
+		CommentHandler:      handler.NewCommentHandler(commentService),
+		UploadHandler:       handler.NewUploadHandler(uploadService),
+		TagHandler:          handler.NewTagHandler(tagService),
+		SyndicationHandler:  handler.NewSyndicationHandler(syndicationService),
+		AdminHandler:        handler.NewAdminHandler(adminService),
+		AccountHandler:      handler.NewAccountHandler(accountService, authService),
+		NotificationHandler: handler.NewNotificationHandler(notificationService),
 	}
 
 	srv := server.NewServer(serverConfig, app)
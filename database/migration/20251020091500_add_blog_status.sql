@@ -0,0 +1,29 @@
+-- +goose Up
+CREATE TYPE blog_status AS ENUM('draft', 'scheduled', 'published', 'unlisted', 'archived');
+
+ALTER TABLE blogs
+    ADD COLUMN status BLOG_STATUS NOT NULL DEFAULT 'draft',
+    ADD COLUMN publish_at TIMESTAMP;
+
+-- visible blogs were published, hidden ones were still reachable by link
+UPDATE blogs SET
+    status = CASE WHEN visibility THEN 'published'::BLOG_STATUS ELSE 'unlisted'::BLOG_STATUS END,
+    publish_at = created_at;
+
+ALTER TABLE blogs DROP COLUMN visibility;
+
+CREATE INDEX IF NOT EXISTS idx_blogs_user_status ON blogs(user_id, status);
+CREATE INDEX IF NOT EXISTS idx_blogs_scheduled ON blogs(publish_at) WHERE status = 'scheduled';
+
+-- +goose Down
+ALTER TABLE blogs ADD COLUMN visibility BOOLEAN DEFAULT true;
+UPDATE blogs SET visibility = (status = 'published');
+
+DROP INDEX IF EXISTS idx_blogs_scheduled;
+DROP INDEX IF EXISTS idx_blogs_user_status;
+
+ALTER TABLE blogs
+    DROP COLUMN publish_at,
+    DROP COLUMN status;
+
+DROP TYPE blog_status;
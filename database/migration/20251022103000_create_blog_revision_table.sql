@@ -0,0 +1,34 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS blog_revisions(
+    id BIGSERIAL PRIMARY KEY,
+    blog_id BIGINT NOT NULL,
+    revision INT NOT NULL,
+    title TEXT NOT NULL,
+    content TEXT NOT NULL,
+    photo_urls TEXT[] NOT NULL DEFAULT '{}',
+    added_photo_urls TEXT[] NOT NULL DEFAULT '{}',
+    removed_photo_urls TEXT[] NOT NULL DEFAULT '{}',
+    restored_from INT, -- revision this one was restored from
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT fk_blog
+    FOREIGN KEY(blog_id) REFERENCES blogs(id) ON DELETE CASCADE,
+    CONSTRAINT unique_blog_revision UNIQUE(blog_id, revision)
+);
+
+-- current content of existing blogs becomes their first revision
+INSERT INTO blog_revisions(blog_id, revision, title, content, photo_urls, added_photo_urls, created_at)
+SELECT
+    b.id,
+    1,
+    b.title,
+    b.content,
+    COALESCE(array_agg(bp.photo_url ORDER BY bp.id) FILTER (WHERE bp.photo_url IS NOT NULL), '{}'),
+    COALESCE(array_agg(bp.photo_url ORDER BY bp.id) FILTER (WHERE bp.photo_url IS NOT NULL), '{}'),
+    COALESCE(b.updated_at, b.created_at)
+FROM blogs b
+LEFT JOIN blog_photos bp ON bp.blog_id = b.id
+GROUP BY b.id;
+
+-- +goose Down
+DROP TABLE IF EXISTS blog_revisions;
@@ -0,0 +1,11 @@
+-- +goose Up
+ALTER TABLE blogs ADD COLUMN search_vector TSVECTOR GENERATED ALWAYS AS (
+    setweight(to_tsvector('english', coalesce(title, '')), 'A') ||
+    setweight(to_tsvector('english', coalesce(content, '')), 'B')
+) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_blogs_search_vector ON blogs USING GIN(search_vector);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_blogs_search_vector;
+ALTER TABLE blogs DROP COLUMN search_vector;
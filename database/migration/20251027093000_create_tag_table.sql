@@ -0,0 +1,24 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS tags(
+    id BIGSERIAL PRIMARY KEY,
+    name TEXT NOT NULL,
+    slug TEXT NOT NULL UNIQUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS blog_tags(
+    blog_id BIGINT NOT NULL,
+    tag_id BIGINT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT pk_blog_tag PRIMARY KEY(blog_id, tag_id),
+    CONSTRAINT fk_blog FOREIGN KEY(blog_id) REFERENCES blogs(id) ON DELETE CASCADE,
+    CONSTRAINT fk_tag FOREIGN KEY(tag_id) REFERENCES tags(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_blog_tags_tag_id ON blog_tags(tag_id);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_blog_tags_tag_id;
+DROP TABLE IF EXISTS blog_tags;
+DROP TABLE IF EXISTS tags;
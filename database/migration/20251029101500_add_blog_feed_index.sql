@@ -0,0 +1,5 @@
+-- +goose Up
+CREATE INDEX IF NOT EXISTS idx_blogs_feed ON blogs(user_id, publish_at DESC, id DESC) WHERE status = 'published';
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_blogs_feed;
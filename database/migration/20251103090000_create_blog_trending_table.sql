@@ -0,0 +1,27 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS blog_trending_scores(
+    blog_id BIGINT PRIMARY KEY,
+    score DOUBLE PRECISION NOT NULL,
+    likes_count INT NOT NULL DEFAULT 0,
+    dislikes_count INT NOT NULL DEFAULT 0,
+    comments_count INT NOT NULL DEFAULT 0,
+    bookmarks_count INT NOT NULL DEFAULT 0,
+    computed_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT fk_blog
+    FOREIGN KEY(blog_id) REFERENCES blogs(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_trending_score ON blog_trending_scores(score DESC);
+
+-- recompute only looks at recent activity
+CREATE INDEX IF NOT EXISTS idx_likes_updated_at ON likes(updated_at) WHERE blog_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_comments_created_at ON comments(created_at);
+CREATE INDEX IF NOT EXISTS idx_bookmarks_created_at ON bookmarks(created_at);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_bookmarks_created_at;
+DROP INDEX IF EXISTS idx_comments_created_at;
+DROP INDEX IF EXISTS idx_likes_updated_at;
+DROP INDEX IF EXISTS idx_trending_score;
+DROP TABLE IF EXISTS blog_trending_scores;
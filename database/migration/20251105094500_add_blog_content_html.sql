@@ -0,0 +1,9 @@
+-- +goose Up
+-- rendered from the markdown in content, NULL until the server renders it
+ALTER TABLE blogs ADD COLUMN content_html TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_blogs_content_html_missing ON blogs(id) WHERE content_html IS NULL;
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_blogs_content_html_missing;
+ALTER TABLE blogs DROP COLUMN content_html;
@@ -0,0 +1,30 @@
+-- +goose Up
+ALTER TABLE refresh_tokens ADD COLUMN token_hash TEXT;
+ALTER TABLE refresh_tokens ADD COLUMN family_id UUID;
+ALTER TABLE refresh_tokens ADD COLUMN rotated_at TIMESTAMP;
+ALTER TABLE refresh_tokens ADD COLUMN revoked_at TIMESTAMP;
+
+-- tokens already handed out keep working, each one starts its own family
+UPDATE refresh_tokens
+SET token_hash = encode(sha256(convert_to(token::TEXT, 'UTF8')), 'hex'),
+    family_id = gen_random_uuid();
+
+ALTER TABLE refresh_tokens ALTER COLUMN token_hash SET NOT NULL;
+ALTER TABLE refresh_tokens ALTER COLUMN family_id SET NOT NULL;
+ALTER TABLE refresh_tokens ADD CONSTRAINT uq_refresh_tokens_token_hash UNIQUE(token_hash);
+ALTER TABLE refresh_tokens DROP COLUMN token;
+
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_family ON refresh_tokens(family_id);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_user ON refresh_tokens(user_id);
+
+-- +goose Down
+-- the raw tokens are gone, everyone has to log in again
+DELETE FROM refresh_tokens;
+DROP INDEX IF EXISTS idx_refresh_tokens_user;
+DROP INDEX IF EXISTS idx_refresh_tokens_family;
+ALTER TABLE refresh_tokens ADD COLUMN token UUID NOT NULL UNIQUE;
+ALTER TABLE refresh_tokens DROP CONSTRAINT uq_refresh_tokens_token_hash;
+ALTER TABLE refresh_tokens DROP COLUMN revoked_at;
+ALTER TABLE refresh_tokens DROP COLUMN rotated_at;
+ALTER TABLE refresh_tokens DROP COLUMN family_id;
+ALTER TABLE refresh_tokens DROP COLUMN token_hash;
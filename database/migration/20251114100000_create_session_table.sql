@@ -0,0 +1,34 @@
+-- +goose Up
+-- one row per signed in device, the refresh token family of the device uses the session id
+CREATE TABLE IF NOT EXISTS sessions(
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL,
+    user_agent TEXT NOT NULL DEFAULT '',
+    ip_address TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_used_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    revoked_at TIMESTAMP,
+
+    CONSTRAINT fk_user
+    FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_sessions_user ON sessions(user_id);
+
+-- every existing token family becomes a session we know nothing about
+INSERT INTO sessions(id, user_id, created_at, last_used_at, revoked_at)
+SELECT
+    family_id,
+    MIN(user_id::TEXT)::UUID,
+    MIN(created_at),
+    MAX(created_at),
+    CASE WHEN bool_and(revoked_at IS NOT NULL) THEN MAX(revoked_at) END
+FROM refresh_tokens
+GROUP BY family_id;
+
+ALTER TABLE refresh_tokens ADD CONSTRAINT fk_refresh_tokens_session
+FOREIGN KEY(family_id) REFERENCES sessions(id) ON DELETE CASCADE;
+
+-- +goose Down
+ALTER TABLE refresh_tokens DROP CONSTRAINT fk_refresh_tokens_session;
+DROP TABLE IF EXISTS sessions;
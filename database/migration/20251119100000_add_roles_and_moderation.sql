@@ -0,0 +1,36 @@
+-- +goose Up
+CREATE TYPE user_role AS ENUM('user', 'moderator', 'admin');
+
+-- the first admin is promoted by hand: UPDATE users SET role = 'admin' WHERE username = '...';
+ALTER TABLE users ADD COLUMN role user_role NOT NULL DEFAULT 'user';
+ALTER TABLE users ADD COLUMN suspended_at TIMESTAMP;
+
+-- hidden content is left out everywhere but for its author
+ALTER TABLE blogs ADD COLUMN hidden_at TIMESTAMP;
+ALTER TABLE comments ADD COLUMN hidden_at TIMESTAMP;
+
+-- every moderator and admin action
+CREATE TABLE IF NOT EXISTS audit_logs(
+    id BIGSERIAL PRIMARY KEY,
+    actor_id UUID,
+    action TEXT NOT NULL,
+    target_type TEXT NOT NULL,
+    target_id TEXT NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    details TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT fk_actor
+    FOREIGN KEY(actor_id) REFERENCES users(id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_logs_created ON audit_logs(created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_target ON audit_logs(target_type, target_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS audit_logs;
+ALTER TABLE comments DROP COLUMN hidden_at;
+ALTER TABLE blogs DROP COLUMN hidden_at;
+ALTER TABLE users DROP COLUMN suspended_at;
+ALTER TABLE users DROP COLUMN role;
+DROP TYPE user_role;
@@ -0,0 +1,21 @@
+-- +goose Up
+-- accounts at OpenID Connect providers the user signs in with
+CREATE TABLE IF NOT EXISTS user_identities(
+    id BIGSERIAL PRIMARY KEY,
+    user_id UUID NOT NULL,
+    provider TEXT NOT NULL,
+    subject TEXT NOT NULL,
+    email TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    last_login_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    UNIQUE(provider, subject),
+
+    CONSTRAINT fk_user
+    FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_identities_user ON user_identities(user_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS user_identities;
@@ -0,0 +1,30 @@
+-- +goose Up
+-- the account and everything it owns is deleted after this, logging in clears it
+ALTER TABLE users ADD COLUMN delete_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_users_delete_at ON users(delete_at) WHERE delete_at IS NOT NULL;
+
+CREATE TYPE export_status AS ENUM('pending', 'processing', 'ready', 'downloaded', 'failed');
+
+-- zip archives of a user's data, built in the background and handed out once
+CREATE TABLE IF NOT EXISTS data_exports(
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL,
+    status export_status NOT NULL DEFAULT 'pending',
+    file_path TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    ready_at TIMESTAMP,
+    expire_at TIMESTAMP,
+
+    CONSTRAINT fk_user
+    FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_data_exports_user ON data_exports(user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_data_exports_pending ON data_exports(created_at) WHERE status = 'pending';
+
+-- +goose Down
+DROP TABLE IF EXISTS data_exports;
+DROP TYPE export_status;
+DROP INDEX IF EXISTS idx_users_delete_at;
+ALTER TABLE users DROP COLUMN delete_at;
@@ -0,0 +1,34 @@
+-- +goose Up
+-- usernames a user gave up, they redirect to the new one and stay reserved until hold_until
+CREATE TABLE IF NOT EXISTS username_history(
+    id BIGSERIAL PRIMARY KEY,
+    user_id UUID NOT NULL,
+    username TEXT NOT NULL,
+    released_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    hold_until TIMESTAMP NOT NULL,
+
+    CONSTRAINT fk_user
+    FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_username_history_username ON username_history(username, hold_until DESC);
+CREATE INDEX IF NOT EXISTS idx_username_history_user ON username_history(user_id, released_at DESC);
+
+CREATE TABLE IF NOT EXISTS email_changes(
+    id BIGSERIAL PRIMARY KEY,
+    user_id UUID NOT NULL,
+    new_email TEXT NOT NULL,
+    token_hash TEXT NOT NULL UNIQUE, -- sha256 of the mailed token
+    expire_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT fk_user
+    FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_email_changes_user ON email_changes(user_id, created_at DESC);
+
+-- +goose Down
+DROP TABLE IF EXISTS email_changes;
+DROP TABLE IF EXISTS username_history;
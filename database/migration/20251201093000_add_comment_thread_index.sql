@@ -0,0 +1,8 @@
+-- +goose Up
+-- top level comments of a blog newest first, and the replies of a comment oldest first
+CREATE INDEX IF NOT EXISTS idx_comments_blog_roots ON comments(blog_id, created_at DESC, id DESC) WHERE parent_id IS NULL;
+CREATE INDEX IF NOT EXISTS idx_comments_parent ON comments(parent_id, created_at, id) WHERE parent_id IS NOT NULL;
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_comments_parent;
+DROP INDEX IF EXISTS idx_comments_blog_roots;
@@ -0,0 +1,22 @@
+-- +goose Up
+ALTER TABLE comments ADD COLUMN edit_count INT NOT NULL DEFAULT 0;
+ALTER TABLE comments ADD COLUMN edited_at TIMESTAMP;
+
+-- versions a comment had before it was edited, revision 1 is the original
+CREATE TABLE IF NOT EXISTS comment_revisions(
+    id BIGSERIAL PRIMARY KEY,
+    comment_id BIGINT NOT NULL,
+    revision INT NOT NULL,
+    content TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL, -- when this version was written
+    replaced_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT fk_comment
+    FOREIGN KEY(comment_id) REFERENCES comments(id) ON DELETE CASCADE,
+    CONSTRAINT unique_comment_revision UNIQUE(comment_id, revision)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS comment_revisions;
+ALTER TABLE comments DROP COLUMN edited_at;
+ALTER TABLE comments DROP COLUMN edit_count;
@@ -0,0 +1,13 @@
+-- +goose Up
+ALTER TABLE blogs ADD COLUMN comments_locked BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE blogs ADD COLUMN comments_followers_only BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE blogs ADD COLUMN pinned_comment_id BIGINT REFERENCES comments(id) ON DELETE SET NULL;
+
+-- the blog author can only take back a hide of their own, not one of a moderator
+ALTER TABLE comments ADD COLUMN hidden_by_author BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- +goose Down
+ALTER TABLE comments DROP COLUMN hidden_by_author;
+ALTER TABLE blogs DROP COLUMN pinned_comment_id;
+ALTER TABLE blogs DROP COLUMN comments_followers_only;
+ALTER TABLE blogs DROP COLUMN comments_locked;
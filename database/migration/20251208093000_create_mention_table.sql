@@ -0,0 +1,29 @@
+-- +goose Up
+-- users mentioned in a blog or a comment, a row is never made twice so each user is notified once
+CREATE TABLE IF NOT EXISTS mentions(
+    id BIGSERIAL PRIMARY KEY,
+    user_id UUID NOT NULL, -- the mentioned user
+    author_id UUID NOT NULL,
+    blog_id BIGINT,
+    comment_id BIGINT,
+    username TEXT NOT NULL, -- as it was written
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    notified_at TIMESTAMP,
+
+    CONSTRAINT fk_user
+    FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT fk_author
+    FOREIGN KEY(author_id) REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT fk_blog
+    FOREIGN KEY(blog_id) REFERENCES blogs(id) ON DELETE CASCADE,
+    CONSTRAINT fk_comment
+    FOREIGN KEY(comment_id) REFERENCES comments(id) ON DELETE CASCADE,
+    CONSTRAINT mention_content CHECK ((blog_id IS NULL) <> (comment_id IS NULL))
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_mentions_blog_user ON mentions(blog_id, user_id) WHERE blog_id IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_mentions_comment_user ON mentions(comment_id, user_id) WHERE comment_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_mentions_pending ON mentions(id) WHERE notified_at IS NULL;
+
+-- +goose Down
+DROP TABLE IF EXISTS mentions;
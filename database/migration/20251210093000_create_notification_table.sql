@@ -0,0 +1,59 @@
+-- +goose Up
+CREATE TYPE notification_type AS ENUM('blog_like', 'comment', 'reply', 'bookmark', 'follow', 'mention');
+
+-- similar activity is grouped into one unread notification, "12 people liked your post"
+CREATE TABLE IF NOT EXISTS notifications(
+    id BIGSERIAL PRIMARY KEY,
+    user_id UUID NOT NULL, -- who is notified
+    type NOTIFICATION_TYPE NOT NULL,
+    blog_id BIGINT,
+    comment_id BIGINT,
+    actor_count INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP, -- latest activity in the group
+    read_at TIMESTAMP,
+
+    CONSTRAINT fk_user
+    FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT fk_blog
+    FOREIGN KEY(blog_id) REFERENCES blogs(id) ON DELETE CASCADE,
+    CONSTRAINT fk_comment
+    FOREIGN KEY(comment_id) REFERENCES comments(id) ON DELETE CASCADE
+);
+
+-- one unread group per kind of activity on the same thing, reading it starts a new one
+CREATE UNIQUE INDEX IF NOT EXISTS idx_notifications_unread_group
+ON notifications(user_id, type, COALESCE(blog_id, 0), COALESCE(comment_id, 0))
+WHERE read_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_notifications_user_updated ON notifications(user_id, updated_at DESC, id DESC);
+
+-- who is behind a grouped notification, each user counts once
+CREATE TABLE IF NOT EXISTS notification_actors(
+    notification_id BIGINT NOT NULL,
+    actor_id UUID NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT pk_notification_actor PRIMARY KEY(notification_id, actor_id),
+    CONSTRAINT fk_notification
+    FOREIGN KEY(notification_id) REFERENCES notifications(id) ON DELETE CASCADE,
+    CONSTRAINT fk_actor
+    FOREIGN KEY(actor_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- every type is on until the user turns it off
+CREATE TABLE IF NOT EXISTS notification_preferences(
+    user_id UUID NOT NULL,
+    type NOTIFICATION_TYPE NOT NULL,
+    enabled BOOLEAN NOT NULL,
+
+    CONSTRAINT pk_notification_preference PRIMARY KEY(user_id, type),
+    CONSTRAINT fk_user
+    FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS notification_preferences;
+DROP TABLE IF EXISTS notification_actors;
+DROP TABLE IF EXISTS notifications;
+DROP TYPE notification_type;
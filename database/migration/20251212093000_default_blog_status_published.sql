@@ -0,0 +1,6 @@
+-- +goose Up
+-- the service publishes a blog created without a status, inserts that skip it do the same
+ALTER TABLE blogs ALTER COLUMN status SET DEFAULT 'published';
+
+-- +goose Down
+ALTER TABLE blogs ALTER COLUMN status SET DEFAULT 'draft';
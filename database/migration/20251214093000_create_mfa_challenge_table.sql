@@ -0,0 +1,15 @@
+-- +goose Up
+-- the token handed out after the password step, stored hashed so it works only once
+CREATE TABLE IF NOT EXISTS mfa_challenges(
+    id BIGSERIAL PRIMARY KEY,
+    user_id UUID NOT NULL,
+    token_hash TEXT NOT NULL UNIQUE,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT fk_user
+    FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS mfa_challenges;
@@ -6,14 +6,28 @@ import (
 )
 
 type App struct {
-	AuthService        *service.AuthService
-	AuthHandler        *handler.AuthHandler
-	UserProfileService *service.UserProfileService
-	UserProfileHandler *handler.UserProfileHandler
-	BlogService        *service.BlogService
-	BlogHandler        *handler.BlogHandler
-	CommentService     *service.CommentService
-	CommentHandler     *handler.CommentHandler
-	UploadService      *service.UploadService
-	UploadHandler      *handler.UploadHandler
+	AuthService         *service.AuthService
+	AuthHandler         *handler.AuthHandler
+	SocialLoginService  *service.SocialLoginService
+	SocialLoginHandler  *handler.SocialLoginHandler
+	UserProfileService  *service.UserProfileService
+	UserProfileHandler  *handler.UserProfileHandler
+	BlogService         *service.BlogService
+	BlogHandler         *handler.BlogHandler
+	CommentService      *service.CommentService
+	CommentHandler      *handler.CommentHandler
+	UploadService       *service.UploadService
+	UploadHandler       *handler.UploadHandler
+	TagService          *service.TagService
+	TrendingService     *service.TrendingService
+	SyndicationService  *service.SyndicationService
+	SyndicationHandler  *handler.SyndicationHandler
+	TagHandler          *handler.TagHandler
+	AdminService        *service.AdminService
+	AdminHandler        *handler.AdminHandler
+	AccountService      *service.AccountService
+	AccountHandler      *handler.AccountHandler
+	MentionService      *service.MentionService
+	NotificationService *service.NotificationService
+	NotificationHandler *handler.NotificationHandler
 }
@@ -1,7 +1,11 @@
 package config
 
 import (
+	"log"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/cloudinary/cloudinary-go/v2"
 )
@@ -15,6 +19,125 @@ func LoadDBConfig() *DBConfig {
 	return &DBConfig{URL: dbUrl}
 }
 
+type SiteConfig struct {
+	URL   string // public address of the frontend, used for links we hand out
+	Title string
+}
+
+func LoadSiteConfig() *SiteConfig {
+	siteUrl := os.Getenv("SITE_URL")
+
+	if siteUrl == "" {
+		siteUrl = os.Getenv("ALLOWED_ORIGIN")
+	}
+
+	return &SiteConfig{URL: strings.TrimSuffix(siteUrl, "/"), Title: "Vibe Writer"}
+}
+
+type TrendingConfig struct {
+	Window          time.Duration // only activity this recent counts
+	HalfLife        time.Duration // activity loses half its weight after this long
+	RefreshInterval time.Duration
+}
+
+func LoadTrendingConfig() *TrendingConfig {
+	return &TrendingConfig{
+		Window:          durationFromEnv("TRENDING_WINDOW", 7*24*time.Hour),
+		HalfLife:        durationFromEnv("TRENDING_HALF_LIFE", 24*time.Hour),
+		RefreshInterval: durationFromEnv("TRENDING_REFRESH_INTERVAL", 10*time.Minute),
+	}
+}
+
+// parse a duration like 90m or 168h, falling back when unset or invalid
+func durationFromEnv(key string, fallback time.Duration) time.Duration {
+	value := os.Getenv(key)
+
+	if value == "" {
+		return fallback
+	}
+
+	duration, err := time.ParseDuration(value)
+
+	if err != nil || duration <= 0 {
+		log.Printf("Invalid %s value %q, using %s\n", key, value, fallback)
+		return fallback
+	}
+
+	return duration
+}
+
+type MailConfig struct {
+	Driver   string // smtp, or file for local development
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+	Dir      string // where the file driver writes mails, empty logs them instead
+}
+
+func LoadMailConfig() *MailConfig {
+	driver := os.Getenv("MAIL_DRIVER")
+
+	if driver == "" {
+		driver = "file"
+	}
+
+	port := os.Getenv("SMTP_PORT")
+
+	if port == "" {
+		port = "587"
+	}
+
+	return &MailConfig{
+		Driver:   driver,
+		Host:     os.Getenv("SMTP_HOST"),
+		Port:     port,
+		Username: os.Getenv("SMTP_USERNAME"),
+		Password: os.Getenv("SMTP_PASSWORD"),
+		From:     os.Getenv("MAIL_FROM"),
+		Dir:      os.Getenv("MAIL_DIR"),
+	}
+}
+
+type AuthConfig struct {
+	RequireVerifiedEmail bool          // block posting and commenting until the email is verified
+	VerificationTokenTTL time.Duration // how long an email verification link works
+	ResetTokenTTL        time.Duration // how long a password reset link works
+	LoginMaxAttempts     int           // failures before an identifier is locked out
+	LoginMaxAttemptsIP   int           // failures before a client address is locked out
+	LoginLockout         time.Duration // how long a lockout lasts
+}
+
+func LoadAuthConfig() *AuthConfig {
+	return &AuthConfig{
+		RequireVerifiedEmail: os.Getenv("REQUIRE_VERIFIED_EMAIL") == "true",
+		VerificationTokenTTL: durationFromEnv("VERIFICATION_TOKEN_TTL", 24*time.Hour),
+		ResetTokenTTL:        durationFromEnv("RESET_TOKEN_TTL", time.Hour),
+		LoginMaxAttempts:     intFromEnv("LOGIN_MAX_ATTEMPTS", 10),
+		LoginMaxAttemptsIP:   intFromEnv("LOGIN_MAX_ATTEMPTS_PER_IP", 100),
+		LoginLockout:         durationFromEnv("LOGIN_LOCKOUT", 15*time.Minute),
+	}
+}
+
+// parse a positive number, falling back when unset or invalid
+func intFromEnv(key string, fallback int) int {
+	value := os.Getenv(key)
+
+	if value == "" {
+		return fallback
+	}
+
+	number, err := strconv.Atoi(value)
+
+	if err != nil || number <= 0 {
+		log.Printf("Invalid %s value %q, using %d\n", key, value, fallback)
+		return fallback
+	}
+
+	return number
+}
+
 func NewCloud() (*cloudinary.Cloudinary, error) {
 	cloudName := os.Getenv("CLOUD_NAME")
 	apiKey := os.Getenv("CLOUDINARY_API_KEY")
@@ -22,3 +145,103 @@ func NewCloud() (*cloudinary.Cloudinary, error) {
 
 	return cloudinary.NewFromParams(cloudName, apiKey, apiSecret)
 }
+
+// keys signing the jwts. Rotating means adding the new key as the signing key and
+// moving the old one to the verify keys until the tokens it signed have expired
+type KeyConfig struct {
+	SigningKeyFile string   // PEM private key, RSA for RS256 or Ed25519 for EdDSA
+	VerifyKeyFiles []string // PEM keys of retired signing keys, still accepted for verification
+	Secret         string   // HS256 secret, used to sign when there is no signing key
+}
+
+func LoadKeyConfig() *KeyConfig {
+	var verifyKeyFiles []string
+
+	for _, file := range strings.Split(os.Getenv("JWT_VERIFY_KEY_FILES"), ",") {
+		if file = strings.TrimSpace(file); file != "" {
+			verifyKeyFiles = append(verifyKeyFiles, file)
+		}
+	}
+
+	return &KeyConfig{
+		SigningKeyFile: os.Getenv("JWT_SIGNING_KEY_FILE"),
+		VerifyKeyFiles: verifyKeyFiles,
+		Secret:         os.Getenv("ACCESS_TOKEN_SECRET"),
+	}
+}
+
+type OIDCProviderConfig struct {
+	Name         string // used in the login and callback paths
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+}
+
+// providers for social login, OIDC_PROVIDERS=google,mock names them and each one reads
+// OIDC_<NAME>_ISSUER, OIDC_<NAME>_CLIENT_ID and OIDC_<NAME>_CLIENT_SECRET
+type OIDCConfig struct {
+	CallbackURL string // public address of the api, providers send the user back to {CallbackURL}/auth/oidc/{name}/callback
+	Providers   []OIDCProviderConfig
+}
+
+func LoadOIDCConfig() *OIDCConfig {
+	apiUrl := os.Getenv("API_URL")
+
+	if apiUrl == "" {
+		apiUrl = "http://localhost:" + os.Getenv("PORT") + "/api/v1"
+	}
+
+	oidcConfig := &OIDCConfig{CallbackURL: strings.TrimSuffix(apiUrl, "/")}
+
+	for _, name := range strings.Split(os.Getenv("OIDC_PROVIDERS"), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+
+		if name == "" {
+			continue
+		}
+
+		prefix := "OIDC_" + strings.ToUpper(name) + "_"
+		provider := OIDCProviderConfig{
+			Name:         name,
+			Issuer:       os.Getenv(prefix + "ISSUER"),
+			ClientID:     os.Getenv(prefix + "CLIENT_ID"),
+			ClientSecret: os.Getenv(prefix + "CLIENT_SECRET"),
+		}
+
+		if provider.Issuer == "" || provider.ClientID == "" {
+			log.Printf("OIDC provider %s needs %sISSUER and %sCLIENT_ID, skipping it\n", name, prefix, prefix)
+			continue
+		}
+
+		oidcConfig.Providers = append(oidcConfig.Providers, provider)
+	}
+
+	return oidcConfig
+}
+
+type AccountConfig struct {
+	ExportDir      string        // where export archives wait to be downloaded, required
+	ExportTTL      time.Duration // how long a finished export can be downloaded
+	DeletionGrace  time.Duration // how long a deleted account can still be restored by logging in
+	UsernameHold   time.Duration // how long a released username redirects and can't be taken by others
+	EmailChangeTTL time.Duration // how long an email change confirmation link works
+}
+
+func LoadAccountConfig() *AccountConfig {
+	// every instance has to see the archives the export worker wrote, a shared volume
+	// or mount, so there is no node local default
+	exportDir := os.Getenv("EXPORT_DIR")
+
+	if exportDir == "" {
+		log.Println("Please Add EXPORT_DIR value to .env file or environment")
+		os.Exit(1)
+	}
+
+	return &AccountConfig{
+		ExportDir:      exportDir,
+		ExportTTL:      durationFromEnv("EXPORT_TTL", 7*24*time.Hour),
+		DeletionGrace:  durationFromEnv("ACCOUNT_DELETION_GRACE", 14*24*time.Hour),
+		UsernameHold:   durationFromEnv("USERNAME_HOLD", 90*24*time.Hour),
+		EmailChangeTTL: durationFromEnv("EMAIL_CHANGE_TOKEN_TTL", 24*time.Hour),
+	}
+}
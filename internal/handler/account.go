@@ -0,0 +1,344 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/harry713j/vibe_writer/internal/middleware"
+	"github.com/harry713j/vibe_writer/internal/service"
+	"github.com/harry713j/vibe_writer/internal/utils"
+)
+
+type AccountHandler struct {
+	service     *service.AccountService
+	authService *service.AuthService
+}
+
+func NewAccountHandler(service *service.AccountService, authService *service.AuthService) *AccountHandler {
+	return &AccountHandler{
+		service:     service,
+		authService: authService,
+	}
+}
+
+type deletionResponse struct {
+	DeleteAt time.Time `json:"delete_at"`
+}
+
+// one of them, depending on how the account signs in
+type deleteAccountRequest struct {
+	Password string `json:"password"`
+	Code     string `json:"code"` // from the authenticator app
+}
+
+type changeUsernameRequest struct {
+	Username string `json:"username"`
+}
+
+type changeEmailRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"` // current password
+	Code     string `json:"code"`     // from the authenticator app
+}
+
+type confirmEmailChangeRequest struct {
+	Token string `json:"token"`
+}
+
+// queue a data export, poll it until it's ready
+func (h *AccountHandler) HandleRequestExport(w http.ResponseWriter, r *http.Request) {
+	userId, ok := middleware.GetUserID(r)
+
+	if !ok {
+		utils.RespondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	export, err := h.service.RequestExport(userId)
+
+	if err != nil {
+		if errors.Is(err, service.ErrUserNotExists) {
+			utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to request data export")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusAccepted, export)
+}
+
+func (h *AccountHandler) HandleGetExport(w http.ResponseWriter, r *http.Request) {
+	userId, ok := middleware.GetUserID(r)
+
+	if !ok {
+		utils.RespondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	exportId, err := uuid.Parse(chi.URLParam(r, "exportId"))
+
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid export id")
+		return
+	}
+
+	export, err := h.service.GetExport(userId, exportId)
+
+	if err != nil {
+		utils.RespondWithError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, export)
+}
+
+// the archive can be downloaded once, it's removed afterwards
+func (h *AccountHandler) HandleDownloadExport(w http.ResponseWriter, r *http.Request) {
+	userId, ok := middleware.GetUserID(r)
+
+	if !ok {
+		utils.RespondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	exportId, err := uuid.Parse(chi.URLParam(r, "exportId"))
+
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid export id")
+		return
+	}
+
+	file, err := h.service.OpenExport(userId, exportId)
+
+	if err != nil {
+		if errors.Is(err, service.ErrExportNotExists) {
+			utils.RespondWithError(w, http.StatusNotFound, err.Error())
+			return
+		}
+
+		if errors.Is(err, service.ErrExportNotReady) {
+			utils.RespondWithError(w, http.StatusConflict, err.Error())
+			return
+		}
+
+		if errors.Is(err, service.ErrExportGone) {
+			utils.RespondWithError(w, http.StatusGone, err.Error())
+			return
+		}
+
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to download data export")
+		return
+	}
+
+	defer file.Close()
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", `attachment; filename="vibewriter-export.zip"`)
+	w.Header().Set("Cache-Control", "no-store")
+
+	if _, err := io.Copy(w, file); err != nil {
+		log.Println("Failed to send data export: ", err)
+	}
+}
+
+// schedule the account for deletion, logging in before the date cancels it. The user confirms
+// it with their password or a two factor code first
+func (h *AccountHandler) HandleDeleteAccount(w http.ResponseWriter, r *http.Request) {
+	userId, ok := middleware.GetUserID(r)
+
+	if !ok {
+		utils.RespondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req deleteAccountRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	// a stolen access token alone can't delete the account
+	if err := h.authService.Reauthenticate(userId, middleware.GetSessionID(r), req.Password, req.Code); err != nil {
+		respondReauthError(w, err, "Failed to delete account")
+		return
+	}
+
+	deleteAt, err := h.service.ScheduleDeletion(userId)
+
+	if err != nil {
+		if errors.Is(err, service.ErrUserNotExists) {
+			utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to delete account")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusAccepted, deletionResponse{DeleteAt: deleteAt})
+}
+
+func (h *AccountHandler) HandleChangeUsername(w http.ResponseWriter, r *http.Request) {
+	userId, ok := middleware.GetUserID(r)
+
+	if !ok {
+		utils.RespondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req changeUsernameRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	user, err := h.service.ChangeUsername(userId, req.Username)
+
+	if err != nil {
+		if errors.Is(err, service.ErrUsernameExists) || errors.Is(err, service.ErrUsernameHeld) {
+			utils.RespondWithError(w, http.StatusConflict, err.Error())
+			return
+		}
+
+		if errors.Is(err, service.ErrUsernameChangeTooSoon) {
+			utils.RespondWithError(w, http.StatusTooManyRequests, err.Error())
+			return
+		}
+
+		if errors.Is(err, service.ErrUserNotExists) || errors.Is(err, service.ErrSameUsername) ||
+			errors.Is(err, utils.ErrShortUsername) || errors.Is(err, utils.ErrInvalidUsername) {
+			utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to change username")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, registerResponse{
+		ID:            user.Id.String(),
+		Username:      user.Username,
+		Email:         user.Email,
+		EmailVerified: user.EmailVerifiedAt != nil,
+	})
+}
+
+// mail a confirmation link to the new address, the email stays the same until it's opened
+func (h *AccountHandler) HandleRequestEmailChange(w http.ResponseWriter, r *http.Request) {
+	userId, ok := middleware.GetUserID(r)
+
+	if !ok {
+		utils.RespondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req changeEmailRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	if req.Email == "" {
+		utils.RespondWithError(w, http.StatusBadRequest, "Email is required")
+		return
+	}
+
+	// a stolen access token alone can't take the account over
+	if err := h.authService.Reauthenticate(userId, middleware.GetSessionID(r), req.Password, req.Code); err != nil {
+		respondReauthError(w, err, "Failed to change email")
+		return
+	}
+
+	if err := h.service.RequestEmailChange(userId, req.Email); err != nil {
+		if errors.Is(err, service.ErrEmailExists) {
+			utils.RespondWithError(w, http.StatusConflict, err.Error())
+			return
+		}
+
+		if errors.Is(err, service.ErrEmailChangeTooSoon) {
+			utils.RespondWithError(w, http.StatusTooManyRequests, err.Error())
+			return
+		}
+
+		if errors.Is(err, service.ErrUserNotExists) || errors.Is(err, service.ErrSameEmail) ||
+			errors.Is(err, utils.ErrShortEmail) || errors.Is(err, utils.ErrInvalidEmail) {
+			utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to change email")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusAccepted, map[string]string{"message": "Check your new email address to confirm the change"})
+}
+
+// the token comes from the mailed link, it's enough on its own
+func (h *AccountHandler) HandleConfirmEmailChange(w http.ResponseWriter, r *http.Request) {
+	var req confirmEmailChangeRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	if req.Token == "" {
+		utils.RespondWithError(w, http.StatusBadRequest, "Token is required")
+		return
+	}
+
+	user, err := h.service.ConfirmEmailChange(req.Token)
+
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidEmailChangeToken) {
+			utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		if errors.Is(err, service.ErrEmailExists) {
+			utils.RespondWithError(w, http.StatusConflict, err.Error())
+			return
+		}
+
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to change email")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, registerResponse{
+		ID:            user.Id.String(),
+		Username:      user.Username,
+		Email:         user.Email,
+		EmailVerified: user.EmailVerifiedAt != nil,
+	})
+}
+
+// answer a failed password or code check of a sensitive action
+func respondReauthError(w http.ResponseWriter, err error, message string) {
+	if respondIfTooManyAttempts(w, err) {
+		return
+	}
+
+	if errors.Is(err, service.ErrReauthRequired) {
+		utils.RespondWithError(w, http.StatusForbidden, err.Error())
+		return
+	}
+
+	if errors.Is(err, service.ErrUserNotExists) || errors.Is(err, service.ErrWrongPassword) ||
+		errors.Is(err, service.ErrInvalidTwoFactorCode) {
+		utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.RespondWithError(w, http.StatusInternalServerError, message)
+}
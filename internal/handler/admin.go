@@ -0,0 +1,279 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/harry713j/vibe_writer/internal/middleware"
+	"github.com/harry713j/vibe_writer/internal/model"
+	"github.com/harry713j/vibe_writer/internal/service"
+	"github.com/harry713j/vibe_writer/internal/utils"
+)
+
+type AdminHandler struct {
+	service *service.AdminService
+}
+
+func NewAdminHandler(service *service.AdminService) *AdminHandler {
+	return &AdminHandler{
+		service: service,
+	}
+}
+
+type moderationRequest struct {
+	Reason string `json:"reason"`
+}
+
+type changeRoleRequest struct {
+	Role   model.Role `json:"role"`
+	Reason string     `json:"reason"`
+}
+
+// the reason is optional, an empty body is fine
+func decodeModerationRequest(r *http.Request) (string, error) {
+	var req moderationRequest
+
+	if r.ContentLength == 0 {
+		return "", nil
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return "", err
+	}
+
+	return req.Reason, nil
+}
+
+func (h *AdminHandler) HandleHideBlog(w http.ResponseWriter, r *http.Request) {
+	h.setBlogHidden(w, r, true)
+}
+
+func (h *AdminHandler) HandleUnhideBlog(w http.ResponseWriter, r *http.Request) {
+	h.setBlogHidden(w, r, false)
+}
+
+func (h *AdminHandler) setBlogHidden(w http.ResponseWriter, r *http.Request, hidden bool) {
+	actorId, ok := middleware.GetUserID(r)
+
+	if !ok {
+		utils.RespondWithError(w, http.StatusUnauthorized, "Unauthorized Request")
+		return
+	}
+
+	blogId, err := strconv.ParseInt(chi.URLParam(r, "blogId"), 10, 64)
+
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid blog id")
+		return
+	}
+
+	reason, err := decodeModerationRequest(r)
+
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	err = h.service.SetBlogHidden(actorId, blogId, hidden, reason)
+
+	if err != nil {
+		if errors.Is(err, service.ErrBlogIdNotExists) {
+			utils.RespondWithError(w, http.StatusNotFound, err.Error())
+			return
+		}
+
+		utils.RespondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	message := "Blog hidden successfully"
+
+	if !hidden {
+		message = "Blog unhidden successfully"
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, map[string]string{"message": message})
+}
+
+func (h *AdminHandler) HandleHideComment(w http.ResponseWriter, r *http.Request) {
+	h.setCommentHidden(w, r, true)
+}
+
+func (h *AdminHandler) HandleUnhideComment(w http.ResponseWriter, r *http.Request) {
+	h.setCommentHidden(w, r, false)
+}
+
+func (h *AdminHandler) setCommentHidden(w http.ResponseWriter, r *http.Request, hidden bool) {
+	actorId, ok := middleware.GetUserID(r)
+
+	if !ok {
+		utils.RespondWithError(w, http.StatusUnauthorized, "Unauthorized Request")
+		return
+	}
+
+	commentId, err := strconv.ParseInt(chi.URLParam(r, "commentId"), 10, 64)
+
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid comment id")
+		return
+	}
+
+	reason, err := decodeModerationRequest(r)
+
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	err = h.service.SetCommentHidden(actorId, commentId, hidden, reason)
+
+	if err != nil {
+		if errors.Is(err, service.ErrCommentNotExists) {
+			utils.RespondWithError(w, http.StatusNotFound, err.Error())
+			return
+		}
+
+		utils.RespondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	message := "Comment hidden successfully"
+
+	if !hidden {
+		message = "Comment unhidden successfully"
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, map[string]string{"message": message})
+}
+
+func (h *AdminHandler) HandleSuspendUser(w http.ResponseWriter, r *http.Request) {
+	h.setUserSuspended(w, r, true)
+}
+
+func (h *AdminHandler) HandleUnsuspendUser(w http.ResponseWriter, r *http.Request) {
+	h.setUserSuspended(w, r, false)
+}
+
+func (h *AdminHandler) setUserSuspended(w http.ResponseWriter, r *http.Request, suspended bool) {
+	actorId, ok := middleware.GetUserID(r)
+
+	if !ok {
+		utils.RespondWithError(w, http.StatusUnauthorized, "Unauthorized Request")
+		return
+	}
+
+	username := chi.URLParam(r, "username")
+
+	if username == "" {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid params")
+		return
+	}
+
+	reason, err := decodeModerationRequest(r)
+
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	err = h.service.SetUserSuspended(actorId, username, suspended, reason)
+
+	if err != nil {
+		if respondIfModerationError(w, err) {
+			return
+		}
+
+		utils.RespondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	message := "User suspended successfully"
+
+	if !suspended {
+		message = "User unsuspended successfully"
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, map[string]string{"message": message})
+}
+
+func (h *AdminHandler) HandleChangeRole(w http.ResponseWriter, r *http.Request) {
+	actorId, ok := middleware.GetUserID(r)
+
+	if !ok {
+		utils.RespondWithError(w, http.StatusUnauthorized, "Unauthorized Request")
+		return
+	}
+
+	username := chi.URLParam(r, "username")
+
+	if username == "" {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid params")
+		return
+	}
+
+	var req changeRoleRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	err := h.service.ChangeRole(actorId, username, req.Role, req.Reason)
+
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidRole) {
+			utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		if respondIfModerationError(w, err) {
+			return
+		}
+
+		utils.RespondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, map[string]string{"message": "Role changed successfully"})
+}
+
+func (h *AdminHandler) HandleGetAuditLogs(w http.ResponseWriter, r *http.Request) {
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid query params value")
+		return
+	}
+
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid query params value")
+		return
+	}
+
+	logs, err := h.service.GetAuditLogs(page, limit)
+
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, logs)
+}
+
+func respondIfModerationError(w http.ResponseWriter, err error) bool {
+	switch {
+	case errors.Is(err, service.ErrUsernameNotExists):
+		utils.RespondWithError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, service.ErrSelfModeration):
+		utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+	default:
+		return false
+	}
+
+	return true
+}
@@ -3,10 +3,17 @@ package handler
 import (
 	"encoding/json"
 	"errors"
+	"log"
+	"math"
+	"net"
 	"net/http"
+	"strconv"
 	"time"
 
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
 	"github.com/harry713j/vibe_writer/internal/middleware"
+	"github.com/harry713j/vibe_writer/internal/model"
 	"github.com/harry713j/vibe_writer/internal/service"
 	"github.com/harry713j/vibe_writer/internal/utils"
 )
@@ -28,9 +35,10 @@ type registerRequest struct {
 }
 
 type registerResponse struct {
-	ID       string `json:"id"`
-	Username string `json:"username"`
-	Email    string `json:"email"`
+	ID            string `json:"id"`
+	Username      string `json:"username"`
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
 }
 
 type loginRequest struct {
@@ -38,6 +46,43 @@ type loginRequest struct {
 	Password   string `json:"password"`
 }
 
+type twoFactorCodeRequest struct {
+	Code string `json:"code"`
+}
+
+type verifyLoginCodeRequest struct {
+	MfaToken string `json:"mfa_token"`
+	Code     string `json:"code"` // code from the app or a recovery code
+}
+
+type refreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type verifyEmailRequest struct {
+	Token string `json:"token"`
+}
+
+type forgotPasswordRequest struct {
+	Email string `json:"email"`
+}
+
+type resetPasswordRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+type changePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+type createPersonalTokenRequest struct {
+	Name     string             `json:"name"`
+	Scopes   []model.TokenScope `json:"scopes"`
+	ExpireAt *time.Time         `json:"expire_at"` // leave out for a token that never expires
+}
+
 func (h *AuthHandler) HandleSignup(w http.ResponseWriter, r *http.Request) {
 	var req registerRequest
 	err := json.NewDecoder(r.Body).Decode(&req)
@@ -55,7 +100,7 @@ func (h *AuthHandler) HandleSignup(w http.ResponseWriter, r *http.Request) {
 	user, err := h.service.RegisterUser(req.Username, req.Email, req.Password)
 
 	if err != nil {
-		if errors.Is(err, service.ErrUsernameExists) || errors.Is(err, service.ErrEmailExists) ||
+		if errors.Is(err, service.ErrUsernameExists) || errors.Is(err, service.ErrEmailExists) || errors.Is(err, service.ErrUsernameHeld) ||
 			errors.Is(err, utils.ErrShortPassword) || errors.Is(err, utils.ErrInvalidEmail) || errors.Is(err, utils.ErrInvalidUsername) ||
 			errors.Is(err, utils.ErrNoLowerCase) || errors.Is(err, utils.ErrNoNumber) || errors.Is(err, utils.ErrNoSpecialCharacter) ||
 			errors.Is(err, utils.ErrNoUpperCase) || errors.Is(err, utils.ErrShortEmail) || errors.Is(err, utils.ErrShortUsername) {
@@ -68,9 +113,10 @@ func (h *AuthHandler) HandleSignup(w http.ResponseWriter, r *http.Request) {
 	}
 
 	res := registerResponse{
-		ID:       user.Id.String(),
-		Email:    user.Email,
-		Username: user.Username,
+		ID:            user.Id.String(),
+		Email:         user.Email,
+		Username:      user.Username,
+		EmailVerified: user.EmailVerifiedAt != nil,
 	}
 
 	utils.RespondWithJSON(w, http.StatusCreated, res)
@@ -90,35 +136,63 @@ func (h *AuthHandler) HandleLogin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	accessToken, refreshToken, err := h.service.LoginUser(req.Identifier, req.Password)
+	result, err := h.service.LoginUser(req.Identifier, req.Password, r.UserAgent(), clientIP(r))
 
 	if err != nil {
+		if respondIfTooManyAttempts(w, err) {
+			return
+		}
+
 		if errors.Is(err, service.ErrUserNotExists) || errors.Is(err, service.ErrWrongPassword) {
 			utils.RespondWithError(w, http.StatusBadRequest, err.Error())
 			return
 		}
 
+		if errors.Is(err, service.ErrUserSuspended) {
+			utils.RespondWithError(w, http.StatusForbidden, err.Error())
+			return
+		}
+
 		utils.RespondWithError(w, http.StatusInternalServerError, "Something went wrong")
 		return
 	}
 
-	// set the cookies
-	rt := &http.Cookie{
-		Name:     "refresh_token",
-		Value:    refreshToken,
-		Path:     "/",
-		Expires:  time.Now().Add(7 * 24 * time.Hour),
-		MaxAge:   7 * 86400,
-		SameSite: http.SameSiteStrictMode,
-		Secure:   false, // true for https
-		HttpOnly: true,
+	// the client finishes the login at /auth/2fa/verify
+	if result.MfaToken != "" {
+		utils.RespondWithJSON(w, http.StatusOK, map[string]any{
+			"message":      "Two factor code required",
+			"mfa_required": true,
+			"mfa_token":    result.MfaToken,
+		})
+		return
 	}
 
-	http.SetCookie(w, rt)
+	respondWithLogin(w, result)
+}
+
+// answer 429 with Retry-After when the login is throttled
+func respondIfTooManyAttempts(w http.ResponseWriter, err error) bool {
+	var tooMany *service.TooManyAttemptsError
+
+	if !errors.As(err, &tooMany) {
+		return false
+	}
+
+	seconds := int(math.Ceil(tooMany.RetryAfter.Seconds()))
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	utils.RespondWithError(w, http.StatusTooManyRequests, err.Error())
+
+	return true
+}
+
+// set the refresh cookie and answer with the access token
+func respondWithLogin(w http.ResponseWriter, result *model.LoginResult) {
+	// set the cookies
+	setRefreshTokenCookie(w, result.RefreshToken)
 
 	utils.RespondWithJSON(w, http.StatusOK, map[string]string{
 		"message":      "Login successful",
-		"access_token": accessToken,
+		"access_token": result.AccessToken,
 	})
 }
 
@@ -132,7 +206,7 @@ func (h *AuthHandler) HandleLogout(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err := h.service.LogoutUser(userId)
+	err := h.service.LogoutUser(userId, middleware.GetSessionID(r))
 
 	if err != nil {
 		if errors.Is(err, service.ErrUserNotExists) {
@@ -149,30 +223,523 @@ func (h *AuthHandler) HandleLogout(w http.ResponseWriter, r *http.Request) {
 	utils.RespondWithJSON(w, http.StatusOK, map[string]string{"message": "Log out successful"})
 }
 
-// refresh access token
+// refresh access token, the refresh token is rotated as well. Browsers send it in the
+// cookie, other clients can send it in the body
 func (h *AuthHandler) HandleRefreshAccessToken(w http.ResponseWriter, r *http.Request) {
-	token, err := r.Cookie("refresh_token")
+	var refreshToken string
 
-	if err != nil {
+	if cookie, err := r.Cookie("refresh_token"); err == nil {
+		refreshToken = cookie.Value
+	} else {
+		var req refreshRequest
+
+		if err := json.NewDecoder(r.Body).Decode(&req); err == nil {
+			refreshToken = req.RefreshToken
+		}
+	}
+
+	if refreshToken == "" {
 		utils.RespondWithError(w, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
 
-	newAccessToken, err := h.service.RefreshAccessToken(token.Value)
+	newAccessToken, newRefreshToken, err := h.service.RefreshAccessToken(refreshToken, r.UserAgent(), clientIP(r))
 
 	if err != nil {
 
 		if errors.Is(err, service.ErrExpiredRefreshToken) || errors.Is(err, service.ErrInvalidRefreshToken) ||
-			errors.Is(err, service.ErrUserNotExists) {
+			errors.Is(err, service.ErrUserNotExists) || errors.Is(err, service.ErrRefreshTokenReused) {
+			http.SetCookie(w, &http.Cookie{Name: "refresh_token", Value: "", MaxAge: -1, Path: "/"})
 			utils.RespondWithError(w, http.StatusBadRequest, err.Error())
 			return
 		}
 
+		if errors.Is(err, service.ErrUserSuspended) {
+			http.SetCookie(w, &http.Cookie{Name: "refresh_token", Value: "", MaxAge: -1, Path: "/"})
+			utils.RespondWithError(w, http.StatusForbidden, err.Error())
+			return
+		}
+
 		utils.RespondWithError(w, http.StatusBadRequest, "Something went wrong")
 		return
 	}
 
+	setRefreshTokenCookie(w, newRefreshToken)
+
+	utils.RespondWithJSON(w, http.StatusOK, map[string]string{
+		"access_token":  newAccessToken,
+		"refresh_token": newRefreshToken,
+	})
+}
+
+// address of the client, RealIP has already replaced RemoteAddr with the forwarded one when
+// the request came through a trusted proxy
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
+
+func setRefreshTokenCookie(w http.ResponseWriter, refreshToken string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     "refresh_token",
+		Value:    refreshToken,
+		Path:     "/",
+		Expires:  time.Now().Add(7 * 24 * time.Hour),
+		MaxAge:   7 * 86400,
+		SameSite: http.SameSiteStrictMode,
+		Secure:   false, // true for https
+		HttpOnly: true,
+	})
+}
+
+func (h *AuthHandler) HandleVerifyEmail(w http.ResponseWriter, r *http.Request) {
+	var req verifyEmailRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	if req.Token == "" {
+		utils.RespondWithError(w, http.StatusBadRequest, "Token is required")
+		return
+	}
+
+	if err := h.service.VerifyEmail(req.Token); err != nil {
+		if errors.Is(err, service.ErrInvalidVerificationToken) {
+			utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		utils.RespondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, map[string]string{"message": "Email verified"})
+}
+
+// must have authorization
+func (h *AuthHandler) HandleResendVerification(w http.ResponseWriter, r *http.Request) {
+	userId, ok := middleware.GetUserID(r)
+
+	if !ok {
+		utils.RespondWithError(w, http.StatusUnauthorized, "Unauthorized Request")
+		return
+	}
+
+	if err := h.service.ResendVerification(userId); err != nil {
+		if errors.Is(err, service.ErrUserNotExists) || errors.Is(err, service.ErrEmailAlreadyVerified) {
+			utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		if errors.Is(err, service.ErrVerificationTooSoon) {
+			utils.RespondWithError(w, http.StatusTooManyRequests, err.Error())
+			return
+		}
+
+		utils.RespondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, map[string]string{"message": "Verification email sent"})
+}
+
+func (h *AuthHandler) HandleForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var req forgotPasswordRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	if req.Email == "" {
+		utils.RespondWithError(w, http.StatusBadRequest, "Email is required")
+		return
+	}
+
+	// same answer whatever happened, it must not reveal whether the email is registered
+	if err := h.service.ForgotPassword(req.Email); err != nil {
+		log.Println("Failed to start password reset: ", err)
+	}
+
 	utils.RespondWithJSON(w, http.StatusOK, map[string]string{
-		"access_token": newAccessToken,
+		"message": "If an account exists for this email, a reset link has been sent",
+	})
+}
+
+func (h *AuthHandler) HandleResetPassword(w http.ResponseWriter, r *http.Request) {
+	var req resetPasswordRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	if req.Token == "" || req.Password == "" {
+		utils.RespondWithError(w, http.StatusBadRequest, "Token & password are required")
+		return
+	}
+
+	if err := h.service.ResetPassword(req.Token, req.Password); err != nil {
+		if errors.Is(err, service.ErrInvalidResetToken) || isPasswordError(err) {
+			utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		utils.RespondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, map[string]string{"message": "Password has been reset"})
+}
+
+// must have authorization
+func (h *AuthHandler) HandleChangePassword(w http.ResponseWriter, r *http.Request) {
+	userId, ok := middleware.GetUserID(r)
+
+	if !ok {
+		utils.RespondWithError(w, http.StatusUnauthorized, "Unauthorized Request")
+		return
+	}
+
+	var req changePasswordRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	if req.CurrentPassword == "" || req.NewPassword == "" {
+		utils.RespondWithError(w, http.StatusBadRequest, "Current & new password are required")
+		return
+	}
+
+	if err := h.service.ChangePassword(userId, middleware.GetSessionID(r), req.CurrentPassword, req.NewPassword); err != nil {
+		if errors.Is(err, service.ErrUserNotExists) || errors.Is(err, service.ErrWrongPassword) || isPasswordError(err) {
+			utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		utils.RespondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, map[string]string{"message": "Password changed"})
+}
+
+// errors from utils.ValidatePassword
+func isPasswordError(err error) bool {
+	return errors.Is(err, utils.ErrShortPassword) || errors.Is(err, utils.ErrNoLowerCase) ||
+		errors.Is(err, utils.ErrNoUpperCase) || errors.Is(err, utils.ErrNoNumber) ||
+		errors.Is(err, utils.ErrNoSpecialCharacter)
+}
+
+// must have authorization
+func (h *AuthHandler) HandleGetSessions(w http.ResponseWriter, r *http.Request) {
+	userId, ok := middleware.GetUserID(r)
+
+	if !ok {
+		utils.RespondWithError(w, http.StatusUnauthorized, "Unauthorized Request")
+		return
+	}
+
+	sessions, err := h.service.GetSessions(userId, middleware.GetSessionID(r))
+
+	if err != nil {
+		if errors.Is(err, service.ErrUserNotExists) {
+			utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		utils.RespondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, sessions)
+}
+
+// must have authorization
+func (h *AuthHandler) HandleRevokeSession(w http.ResponseWriter, r *http.Request) {
+	userId, ok := middleware.GetUserID(r)
+
+	if !ok {
+		utils.RespondWithError(w, http.StatusUnauthorized, "Unauthorized Request")
+		return
+	}
+
+	sessionId, err := uuid.Parse(chi.URLParam(r, "id"))
+
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid session id")
+		return
+	}
+
+	if err := h.service.RevokeSession(userId, sessionId); err != nil {
+		if errors.Is(err, service.ErrSessionNotExists) {
+			utils.RespondWithError(w, http.StatusNotFound, err.Error())
+			return
+		}
+
+		utils.RespondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	// signing out the current device from the list is a logout
+	if sessionId == middleware.GetSessionID(r) {
+		http.SetCookie(w, &http.Cookie{Name: "refresh_token", Value: "", MaxAge: -1, Path: "/"})
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, map[string]string{"message": "Session revoked"})
+}
+
+// must have authorization, log out everywhere else
+func (h *AuthHandler) HandleRevokeOtherSessions(w http.ResponseWriter, r *http.Request) {
+	userId, ok := middleware.GetUserID(r)
+
+	if !ok {
+		utils.RespondWithError(w, http.StatusUnauthorized, "Unauthorized Request")
+		return
+	}
+
+	revoked, err := h.service.RevokeOtherSessions(userId, middleware.GetSessionID(r))
+
+	if err != nil {
+		if errors.Is(err, service.ErrUserNotExists) {
+			utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		utils.RespondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, map[string]int{"revoked": revoked})
+}
+
+func (h *AuthHandler) HandleVerifyLoginCode(w http.ResponseWriter, r *http.Request) {
+	var req verifyLoginCodeRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	if req.MfaToken == "" || req.Code == "" {
+		utils.RespondWithError(w, http.StatusBadRequest, "Mfa token & code are required")
+		return
+	}
+
+	result, err := h.service.VerifyLoginCode(req.MfaToken, req.Code, r.UserAgent(), clientIP(r))
+
+	if err != nil {
+		if respondIfTooManyAttempts(w, err) {
+			return
+		}
+
+		if errors.Is(err, service.ErrInvalidMfaToken) || errors.Is(err, service.ErrUserNotExists) {
+			utils.RespondWithError(w, http.StatusUnauthorized, err.Error())
+			return
+		}
+
+		if errors.Is(err, service.ErrInvalidTwoFactorCode) {
+			utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		if errors.Is(err, service.ErrUserSuspended) {
+			utils.RespondWithError(w, http.StatusForbidden, err.Error())
+			return
+		}
+
+		utils.RespondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	respondWithLogin(w, result)
+}
+
+// must have authorization
+func (h *AuthHandler) HandleEnrollTOTP(w http.ResponseWriter, r *http.Request) {
+	userId, ok := middleware.GetUserID(r)
+
+	if !ok {
+		utils.RespondWithError(w, http.StatusUnauthorized, "Unauthorized Request")
+		return
+	}
+
+	enrollment, err := h.service.EnrollTOTP(userId)
+
+	if err != nil {
+		if errors.Is(err, service.ErrUserNotExists) || errors.Is(err, service.ErrTwoFactorEnabled) {
+			utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		utils.RespondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, enrollment)
+}
+
+// must have authorization
+func (h *AuthHandler) HandleConfirmTOTP(w http.ResponseWriter, r *http.Request) {
+	userId, ok := middleware.GetUserID(r)
+
+	if !ok {
+		utils.RespondWithError(w, http.StatusUnauthorized, "Unauthorized Request")
+		return
+	}
+
+	var req twoFactorCodeRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Code == "" {
+		utils.RespondWithError(w, http.StatusBadRequest, "Code is required")
+		return
+	}
+
+	recoveryCodes, err := h.service.ConfirmTOTP(userId, req.Code)
+
+	if err != nil {
+		if errors.Is(err, service.ErrTwoFactorNotPending) || errors.Is(err, service.ErrTwoFactorEnabled) ||
+			errors.Is(err, service.ErrInvalidTwoFactorCode) {
+			utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		utils.RespondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, map[string]any{
+		"message":        "Two factor authentication enabled",
+		"recovery_codes": recoveryCodes,
 	})
 }
+
+// must have authorization
+func (h *AuthHandler) HandleDisableTOTP(w http.ResponseWriter, r *http.Request) {
+	userId, ok := middleware.GetUserID(r)
+
+	if !ok {
+		utils.RespondWithError(w, http.StatusUnauthorized, "Unauthorized Request")
+		return
+	}
+
+	var req twoFactorCodeRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Code == "" {
+		utils.RespondWithError(w, http.StatusBadRequest, "Code is required")
+		return
+	}
+
+	if err := h.service.DisableTOTP(userId, req.Code); err != nil {
+		if errors.Is(err, service.ErrTwoFactorNotEnabled) || errors.Is(err, service.ErrInvalidTwoFactorCode) {
+			utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		utils.RespondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, map[string]string{"message": "Two factor authentication disabled"})
+}
+
+// must have authorization, the token is only shown in this response
+func (h *AuthHandler) HandleCreatePersonalToken(w http.ResponseWriter, r *http.Request) {
+	userId, ok := middleware.GetUserID(r)
+
+	if !ok {
+		utils.RespondWithError(w, http.StatusUnauthorized, "Unauthorized Request")
+		return
+	}
+
+	var req createPersonalTokenRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	token, err := h.service.CreatePersonalToken(userId, req.Name, req.Scopes, req.ExpireAt)
+
+	if err != nil {
+		if errors.Is(err, service.ErrTokenNameRequired) || errors.Is(err, service.ErrInvalidScope) ||
+			errors.Is(err, service.ErrInvalidTokenExpiry) || errors.Is(err, service.ErrTooManyPersonalTokens) {
+			utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		utils.RespondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusCreated, token)
+}
+
+// must have authorization
+func (h *AuthHandler) HandleGetPersonalTokens(w http.ResponseWriter, r *http.Request) {
+	userId, ok := middleware.GetUserID(r)
+
+	if !ok {
+		utils.RespondWithError(w, http.StatusUnauthorized, "Unauthorized Request")
+		return
+	}
+
+	tokens, err := h.service.GetPersonalTokens(userId)
+
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, tokens)
+}
+
+// must have authorization
+func (h *AuthHandler) HandleRevokePersonalToken(w http.ResponseWriter, r *http.Request) {
+	userId, ok := middleware.GetUserID(r)
+
+	if !ok {
+		utils.RespondWithError(w, http.StatusUnauthorized, "Unauthorized Request")
+		return
+	}
+
+	tokenId, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid token id")
+		return
+	}
+
+	if err := h.service.RevokePersonalToken(userId, tokenId); err != nil {
+		if errors.Is(err, service.ErrPersonalTokenNotExists) {
+			utils.RespondWithError(w, http.StatusNotFound, err.Error())
+			return
+		}
+
+		utils.RespondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, map[string]string{"message": "Token revoked"})
+}
+
+// public keys for services verifying our access tokens, they can be cached for a while
+// since retired keys stay published until their tokens have expired
+func (h *AuthHandler) HandleJWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	utils.RespondWithJSON(w, http.StatusOK, h.service.JWKS())
+}
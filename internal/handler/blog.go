@@ -5,8 +5,9 @@ import (
 	"errors"
 	"net/http"
 	"strconv"
+	"time"
 
-	"github.com/go-chi/chi"
+	"github.com/go-chi/chi/v5"
 	"github.com/harry713j/vibe_writer/internal/middleware"
 	"github.com/harry713j/vibe_writer/internal/model"
 	"github.com/harry713j/vibe_writer/internal/service"
@@ -14,26 +15,37 @@ import (
 )
 
 type BlogHandler struct {
-	blogService *service.BlogService
+	blogService     *service.BlogService
+	trendingService *service.TrendingService
 }
 
-func NewBlogHandler(service *service.BlogService) *BlogHandler {
+func NewBlogHandler(service *service.BlogService, trendingService *service.TrendingService) *BlogHandler {
 	return &BlogHandler{
-		blogService: service,
+		blogService:     service,
+		trendingService: trendingService,
 	}
 }
 
 type createBlogRequest struct {
-	Title     string   `json:"title"`
-	Slug      string   `json:"slug"`
-	Content   string   `json:"content"`
-	PhotoUrls []string `json:"photo_urls"`
+	Title     string           `json:"title"`
+	Slug      string           `json:"slug"`
+	Content   string           `json:"content"`
+	PhotoUrls []string         `json:"photo_urls"`
+	Tags      []string         `json:"tags"`
+	Status    model.BlogStatus `json:"status"`     // could not be present
+	PublishAt *time.Time       `json:"publish_at"` // required for scheduled blogs
 }
 
 type updateBlogRequest struct {
 	Title     string   `json:"title"`
 	Content   string   `json:"content"`
 	PhotoUrls []string `json:"photo_urls"`
+	Tags      []string `json:"tags"` // could not be present, keeps the current tags
+}
+
+type changeBlogStatusRequest struct {
+	Status    model.BlogStatus `json:"status"`
+	PublishAt *time.Time       `json:"publish_at"`
 }
 
 type createCommentRequest struct {
@@ -41,6 +53,11 @@ type createCommentRequest struct {
 	ParentId int64  `json:"parent_id"` // could not be present
 }
 
+type commentSettingsRequest struct {
+	CommentsLocked        *bool `json:"comments_locked"`         // could not be present
+	CommentsFollowersOnly *bool `json:"comments_followers_only"` // could not be present
+}
+
 type toggleBlogLikeRequest struct {
 	LikeType model.LikeType `json:"like_type"`
 }
@@ -66,10 +83,13 @@ func (h *BlogHandler) HandleCreateBlog(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	blogData, err := h.blogService.CreateBlog(userId, req.Title, req.Slug, req.Content, req.PhotoUrls)
+	blogData, err := h.blogService.CreateBlog(userId, req.Title, req.Slug, req.Content, req.PhotoUrls, req.Tags,
+		req.Status, req.PublishAt)
 
 	if err != nil {
-		if errors.Is(err, service.ErrTitleExists) {
+		if errors.Is(err, service.ErrTitleExists) || errors.Is(err, service.ErrInvalidBlogStatus) ||
+			errors.Is(err, service.ErrInvalidPublishTime) || errors.Is(err, service.ErrInvalidTag) ||
+			errors.Is(err, service.ErrTooManyTags) {
 			utils.RespondWithError(w, http.StatusBadRequest, err.Error())
 			return
 		}
@@ -99,10 +119,11 @@ func (h *BlogHandler) HandleUpdateBlog(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	updatedBlogData, err := h.blogService.UpdateBlog(userId, slug, req.Title, req.Content, req.PhotoUrls)
+	updatedBlogData, err := h.blogService.UpdateBlog(userId, slug, req.Title, req.Content, req.PhotoUrls, req.Tags)
 
 	if err != nil {
-		if errors.Is(err, service.ErrBlogNotExists) {
+		if errors.Is(err, service.ErrBlogNotExists) || errors.Is(err, service.ErrInvalidTag) ||
+			errors.Is(err, service.ErrTooManyTags) {
 			utils.RespondWithError(w, http.StatusBadRequest, err.Error())
 			return
 		}
@@ -181,7 +202,7 @@ func (h *BlogHandler) HandleGetBlogs(w http.ResponseWriter, r *http.Request) {
 	utils.RespondWithJSON(w, http.StatusOK, blogs)
 }
 
-func (h *BlogHandler) HandleChangeBlogVisibility(w http.ResponseWriter, r *http.Request) {
+func (h *BlogHandler) HandleGetBlog(w http.ResponseWriter, r *http.Request) {
 	userId, ok := middleware.GetUserID(r)
 
 	if !ok {
@@ -192,14 +213,54 @@ func (h *BlogHandler) HandleChangeBlogVisibility(w http.ResponseWriter, r *http.
 	slug := chi.URLParam(r, "slug")
 
 	if slug == "" {
-		utils.RespondWithError(w, http.StatusBadRequest, "empty params")
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid params")
 		return
 	}
 
-	blog, err := h.blogService.ChangeBlogVisibility(userId, slug)
+	blog, err := h.blogService.GetOwnBlog(userId, slug)
 
 	if err != nil {
 		if errors.Is(err, service.ErrUserNotExists) || errors.Is(err, service.ErrBlogNotExists) {
+			utils.RespondWithError(w, http.StatusNotFound, err.Error())
+			return
+		}
+
+		utils.RespondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, blog)
+}
+
+func (h *BlogHandler) HandleChangeBlogStatus(w http.ResponseWriter, r *http.Request) {
+	userId, ok := middleware.GetUserID(r)
+
+	if !ok {
+		utils.RespondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	slug := chi.URLParam(r, "slug")
+
+	if slug == "" {
+		utils.RespondWithError(w, http.StatusBadRequest, "empty params")
+		return
+	}
+
+	var req changeBlogStatusRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+
+	if err != nil || req.Status == "" {
+		utils.RespondWithError(w, http.StatusBadRequest, "Status is required")
+		return
+	}
+
+	blog, err := h.blogService.ChangeBlogStatus(userId, slug, req.Status, req.PublishAt)
+
+	if err != nil {
+		if errors.Is(err, service.ErrUserNotExists) || errors.Is(err, service.ErrBlogNotExists) ||
+			errors.Is(err, service.ErrInvalidBlogStatus) || errors.Is(err, service.ErrInvalidStatusTransition) ||
+			errors.Is(err, service.ErrInvalidPublishTime) {
 			utils.RespondWithError(w, http.StatusBadRequest, err.Error())
 			return
 		}
@@ -235,12 +296,21 @@ func (h *BlogHandler) HandleCreateComment(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	comment, err := h.blogService.CreateComment(userid, slug, req.ParentId, req.Content)
+	// under /users/{username}/blogs the blog is one of that author, under /blogs the user's own
+	author := chi.URLParam(r, "username")
+
+	comment, err := h.blogService.CreateComment(userid, author, slug, req.ParentId, req.Content)
 
 	if err != nil {
 		if errors.Is(err, service.ErrUserNotExists) || errors.Is(err, service.ErrBlogNotExists) ||
-			errors.Is(err, service.ErrInvalidCommentContent) {
+			errors.Is(err, service.ErrInvalidCommentContent) || errors.Is(err, service.ErrCommentNotExists) {
 			utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		if errors.Is(err, service.ErrCommentsLocked) || errors.Is(err, service.ErrCommentsFollowersOnly) {
+			utils.RespondWithError(w, http.StatusForbidden, err.Error())
+			return
 		}
 
 		utils.RespondWithError(w, http.StatusInternalServerError, "Something went wrong")
@@ -250,6 +320,44 @@ func (h *BlogHandler) HandleCreateComment(w http.ResponseWriter, r *http.Request
 	utils.RespondWithJSON(w, http.StatusCreated, comment)
 }
 
+// lock the comments of the user's blog or open them to followers only
+func (h *BlogHandler) HandleUpdateCommentSettings(w http.ResponseWriter, r *http.Request) {
+	userId, ok := middleware.GetUserID(r)
+
+	if !ok {
+		utils.RespondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	slug := chi.URLParam(r, "slug")
+
+	if slug == "" {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid params")
+		return
+	}
+
+	var req commentSettingsRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	settings, err := h.blogService.UpdateCommentSettings(userId, slug, req.CommentsLocked, req.CommentsFollowersOnly)
+
+	if err != nil {
+		if errors.Is(err, service.ErrBlogNotExists) {
+			utils.RespondWithError(w, http.StatusNotFound, err.Error())
+			return
+		}
+
+		utils.RespondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, settings)
+}
+
 func (h *BlogHandler) HandleToggleBlogLike(w http.ResponseWriter, r *http.Request) {
 	userId, ok := middleware.GetUserID(r)
 
@@ -258,6 +366,7 @@ func (h *BlogHandler) HandleToggleBlogLike(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	author := chi.URLParam(r, "username")
 	slug := chi.URLParam(r, "slug")
 
 	if slug == "" {
@@ -273,7 +382,7 @@ func (h *BlogHandler) HandleToggleBlogLike(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	like, err := h.blogService.ToggleBlogLike(userId, slug, req.LikeType)
+	like, err := h.blogService.ToggleBlogLike(userId, author, slug, req.LikeType)
 
 	if err != nil {
 		if errors.Is(err, service.ErrUserNotExists) || errors.Is(err, service.ErrBlogNotExists) {
@@ -296,6 +405,7 @@ func (h *BlogHandler) HandleRemoveBlogLike(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	author := chi.URLParam(r, "username")
 	slug := chi.URLParam(r, "slug")
 
 	if slug == "" {
@@ -303,7 +413,7 @@ func (h *BlogHandler) HandleRemoveBlogLike(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	err := h.blogService.RemoveBlogLike(userId, slug)
+	err := h.blogService.RemoveBlogLike(userId, author, slug)
 
 	if err != nil {
 		if errors.Is(err, service.ErrUserNotExists) || errors.Is(err, service.ErrBlogNotExists) {
@@ -326,13 +436,14 @@ func (h *BlogHandler) HandleCreateBookmark(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	author := chi.URLParam(r, "username")
 	slug := chi.URLParam(r, "slug")
 	if slug == "" {
 		utils.RespondWithError(w, http.StatusBadRequest, "Invalid params")
 		return
 	}
 
-	err := h.blogService.CreateBookmark(userId, slug)
+	err := h.blogService.CreateBookmark(userId, author, slug)
 
 	if err != nil {
 		if errors.Is(err, service.ErrUserNotExists) || errors.Is(err, service.ErrBlogNotExists) {
@@ -355,13 +466,14 @@ func (h *BlogHandler) HandleRemoveBookmark(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	author := chi.URLParam(r, "username")
 	slug := chi.URLParam(r, "slug")
 	if slug == "" {
 		utils.RespondWithError(w, http.StatusBadRequest, "Invalid params")
 		return
 	}
 
-	err := h.blogService.RemoveBookmark(userId, slug)
+	err := h.blogService.RemoveBookmark(userId, author, slug)
 
 	if err != nil {
 		if errors.Is(err, service.ErrUserNotExists) || errors.Is(err, service.ErrBlogNotExists) {
@@ -375,3 +487,289 @@ func (h *BlogHandler) HandleRemoveBookmark(w http.ResponseWriter, r *http.Reques
 
 	utils.RespondWithJSON(w, http.StatusCreated, map[string]string{"message": "Bookmark removed successfully"})
 }
+
+/* revisions */
+func (h *BlogHandler) HandleGetRevisions(w http.ResponseWriter, r *http.Request) {
+	userId, ok := middleware.GetUserID(r)
+
+	if !ok {
+		utils.RespondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	slug := chi.URLParam(r, "slug")
+
+	if slug == "" {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid params")
+		return
+	}
+
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid query params value")
+		return
+	}
+
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid query params value")
+		return
+	}
+
+	revisions, err := h.blogService.GetRevisions(userId, slug, page, limit)
+
+	if err != nil {
+		if errors.Is(err, service.ErrBlogNotExists) {
+			utils.RespondWithError(w, http.StatusNotFound, err.Error())
+			return
+		}
+
+		utils.RespondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, revisions)
+}
+
+func (h *BlogHandler) HandleGetRevision(w http.ResponseWriter, r *http.Request) {
+	userId, ok := middleware.GetUserID(r)
+
+	if !ok {
+		utils.RespondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	slug := chi.URLParam(r, "slug")
+	revisionNumber, err := strconv.Atoi(chi.URLParam(r, "revision"))
+
+	if slug == "" || err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid params")
+		return
+	}
+
+	revision, err := h.blogService.GetRevision(userId, slug, revisionNumber)
+
+	if err != nil {
+		if errors.Is(err, service.ErrBlogNotExists) || errors.Is(err, service.ErrRevisionNotExists) {
+			utils.RespondWithError(w, http.StatusNotFound, err.Error())
+			return
+		}
+
+		utils.RespondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, revision)
+}
+
+func (h *BlogHandler) HandleDiffRevisions(w http.ResponseWriter, r *http.Request) {
+	userId, ok := middleware.GetUserID(r)
+
+	if !ok {
+		utils.RespondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	slug := chi.URLParam(r, "slug")
+
+	if slug == "" {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid params")
+		return
+	}
+
+	from, err := strconv.Atoi(r.URL.Query().Get("from"))
+
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid query params value")
+		return
+	}
+
+	to, err := strconv.Atoi(r.URL.Query().Get("to"))
+
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid query params value")
+		return
+	}
+
+	diff, err := h.blogService.DiffRevisions(userId, slug, from, to)
+
+	if err != nil {
+		if errors.Is(err, service.ErrBlogNotExists) || errors.Is(err, service.ErrRevisionNotExists) {
+			utils.RespondWithError(w, http.StatusNotFound, err.Error())
+			return
+		}
+
+		if errors.Is(err, utils.ErrDiffTooLarge) {
+			utils.RespondWithError(w, http.StatusUnprocessableEntity, err.Error())
+			return
+		}
+
+		utils.RespondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, diff)
+}
+
+func (h *BlogHandler) HandleRestoreRevision(w http.ResponseWriter, r *http.Request) {
+	userId, ok := middleware.GetUserID(r)
+
+	if !ok {
+		utils.RespondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	slug := chi.URLParam(r, "slug")
+	revisionNumber, err := strconv.Atoi(chi.URLParam(r, "revision"))
+
+	if slug == "" || err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid params")
+		return
+	}
+
+	blog, err := h.blogService.RestoreRevision(userId, slug, revisionNumber)
+
+	if err != nil {
+		if errors.Is(err, service.ErrBlogNotExists) || errors.Is(err, service.ErrRevisionNotExists) {
+			utils.RespondWithError(w, http.StatusNotFound, err.Error())
+			return
+		}
+
+		utils.RespondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, blog)
+}
+
+/* search */
+func (h *BlogHandler) HandleSearchBlogs(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	page, err := strconv.Atoi(query.Get("page"))
+
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid query params value")
+		return
+	}
+
+	limit, err := strconv.Atoi(query.Get("limit"))
+
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid query params value")
+		return
+	}
+
+	from, err := parseDateParam(query.Get("from"))
+
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid from date")
+		return
+	}
+
+	to, err := parseDateParam(query.Get("to"))
+
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid to date")
+		return
+	}
+
+	// a plain date includes the whole day
+	if to != nil && len(query.Get("to")) == len(time.DateOnly) {
+		endOfDay := to.Add(24*time.Hour - time.Nanosecond)
+		to = &endOfDay
+	}
+
+	blogs, err := h.blogService.SearchBlogs(query.Get("q"), query.Get("author"), from, to, page, limit)
+
+	if err != nil {
+		if errors.Is(err, service.ErrEmptySearchQuery) || errors.Is(err, service.ErrInvalidDateRange) ||
+			errors.Is(err, service.ErrInvalidAuthor) {
+			utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		utils.RespondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, blogs)
+}
+
+// accepts both 2006-01-02 and RFC3339, empty value means no filter
+func parseDateParam(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	if t, err := time.Parse(time.DateOnly, value); err == nil {
+		return &t, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &t, nil
+}
+
+/* feed */
+func (h *BlogHandler) HandleGetFeed(w http.ResponseWriter, r *http.Request) {
+	userId, ok := middleware.GetUserID(r)
+
+	if !ok {
+		utils.RespondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid query params value")
+		return
+	}
+
+	feed, err := h.blogService.GetFeed(userId, r.URL.Query().Get("cursor"), limit)
+
+	if err != nil {
+		if errors.Is(err, service.ErrUserNotExists) || errors.Is(err, utils.ErrInvalidCursor) {
+			utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		utils.RespondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, feed)
+}
+
+/* trending */
+func (h *BlogHandler) HandleGetTrendingBlogs(w http.ResponseWriter, r *http.Request) {
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid query params value")
+		return
+	}
+
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid query params value")
+		return
+	}
+
+	blogs, err := h.trendingService.GetTrendingBlogs(page, limit)
+
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, blogs)
+}
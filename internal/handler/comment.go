@@ -7,6 +7,7 @@ import (
 	"strconv"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
 	"github.com/harry713j/vibe_writer/internal/middleware"
 	"github.com/harry713j/vibe_writer/internal/model"
 	"github.com/harry713j/vibe_writer/internal/service"
@@ -27,6 +28,10 @@ type toggleCommentLikeRequest struct {
 	LikeType model.LikeType `json:"like_type"`
 }
 
+type editCommentRequest struct {
+	Content string `json:"content"`
+}
+
 func (h *CommentHandler) HandleDeleteComment(w http.ResponseWriter, r *http.Request) {
 	userid, ok := middleware.GetUserID(r)
 
@@ -46,6 +51,11 @@ func (h *CommentHandler) HandleDeleteComment(w http.ResponseWriter, r *http.Requ
 	err = h.service.DeleteComment(userid, int64(commentId))
 
 	if err != nil {
+		if errors.Is(err, service.ErrUserNotExists) || errors.Is(err, service.ErrCommentNotExists) {
+			utils.RespondWithError(w, http.StatusNotFound, err.Error())
+			return
+		}
+
 		utils.RespondWithError(w, http.StatusInternalServerError, "Something went wrong")
 		return
 	}
@@ -53,6 +63,135 @@ func (h *CommentHandler) HandleDeleteComment(w http.ResponseWriter, r *http.Requ
 	utils.RespondWithJSON(w, http.StatusOK, map[string]string{"message": "comment deleted successfully"})
 }
 
+// only the author can edit, and only for a while after posting
+func (h *CommentHandler) HandleEditComment(w http.ResponseWriter, r *http.Request) {
+	userId, ok := middleware.GetUserID(r)
+
+	if !ok {
+		utils.RespondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	commentId, err := strconv.ParseInt(chi.URLParam(r, "commentId"), 10, 64)
+
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid comment id")
+		return
+	}
+
+	var req editCommentRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	comment, err := h.service.EditComment(userId, commentId, req.Content)
+
+	if err != nil {
+		if errors.Is(err, service.ErrCommentNotExists) {
+			utils.RespondWithError(w, http.StatusNotFound, err.Error())
+			return
+		}
+
+		if errors.Is(err, service.ErrCommentEditClosed) {
+			utils.RespondWithError(w, http.StatusForbidden, err.Error())
+			return
+		}
+
+		if errors.Is(err, service.ErrUserNotExists) || errors.Is(err, service.ErrInvalidCommentContent) {
+			utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		utils.RespondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, comment)
+}
+
+// must run behind the moderator check
+func (h *CommentHandler) HandleGetCommentRevisions(w http.ResponseWriter, r *http.Request) {
+	commentId, err := strconv.ParseInt(chi.URLParam(r, "commentId"), 10, 64)
+
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid comment id")
+		return
+	}
+
+	revisions, err := h.service.GetCommentRevisions(commentId)
+
+	if err != nil {
+		if errors.Is(err, service.ErrCommentNotExists) {
+			utils.RespondWithError(w, http.StatusNotFound, err.Error())
+			return
+		}
+
+		utils.RespondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, map[string][]model.CommentRevision{"revisions": revisions})
+}
+
+// hide, unhide, pin and unpin share the same shape, only the blog author can use them
+func (h *CommentHandler) authorAction(action func(uuid.UUID, int64) error, message string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId, ok := middleware.GetUserID(r)
+
+		if !ok {
+			utils.RespondWithError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+
+		commentId, err := strconv.ParseInt(chi.URLParam(r, "commentId"), 10, 64)
+
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, "Invalid comment id")
+			return
+		}
+
+		if err := action(userId, commentId); err != nil {
+			if errors.Is(err, service.ErrCommentNotExists) {
+				utils.RespondWithError(w, http.StatusNotFound, err.Error())
+				return
+			}
+
+			if errors.Is(err, service.ErrNotBlogAuthor) || errors.Is(err, service.ErrCommentHiddenByMod) {
+				utils.RespondWithError(w, http.StatusForbidden, err.Error())
+				return
+			}
+
+			if errors.Is(err, service.ErrCannotPinComment) {
+				utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+
+			utils.RespondWithError(w, http.StatusInternalServerError, "Something went wrong")
+			return
+		}
+
+		utils.RespondWithJSON(w, http.StatusOK, map[string]string{"message": message})
+	}
+}
+
+func (h *CommentHandler) HandleHideComment(w http.ResponseWriter, r *http.Request) {
+	h.authorAction(h.service.HideComment, "comment hidden successfully")(w, r)
+}
+
+func (h *CommentHandler) HandleUnhideComment(w http.ResponseWriter, r *http.Request) {
+	h.authorAction(h.service.UnhideComment, "comment unhidden successfully")(w, r)
+}
+
+func (h *CommentHandler) HandlePinComment(w http.ResponseWriter, r *http.Request) {
+	h.authorAction(h.service.PinComment, "comment pinned successfully")(w, r)
+}
+
+func (h *CommentHandler) HandleUnpinComment(w http.ResponseWriter, r *http.Request) {
+	h.authorAction(h.service.UnpinComment, "comment unpinned successfully")(w, r)
+}
+
 func (h *CommentHandler) HandleToggleCommentLike(w http.ResponseWriter, r *http.Request) {
 	userId, ok := middleware.GetUserID(r)
 
@@ -0,0 +1,125 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/harry713j/vibe_writer/internal/config"
+	"github.com/harry713j/vibe_writer/internal/service"
+	"github.com/harry713j/vibe_writer/internal/utils"
+)
+
+const socialLoginCookie = "oidc_login"
+
+type SocialLoginHandler struct {
+	service *service.SocialLoginService
+	site    *config.SiteConfig
+}
+
+func NewSocialLoginHandler(service *service.SocialLoginService, site *config.SiteConfig) *SocialLoginHandler {
+	return &SocialLoginHandler{
+		service: service,
+		site:    site,
+	}
+}
+
+// names of the providers the frontend can show a button for
+func (h *SocialLoginHandler) HandleGetProviders(w http.ResponseWriter, r *http.Request) {
+	utils.RespondWithJSON(w, http.StatusOK, map[string][]string{"providers": h.service.GetProviders()})
+}
+
+// the browser is sent to the provider, the login state waits in a cookie for the callback
+func (h *SocialLoginHandler) HandleBeginLogin(w http.ResponseWriter, r *http.Request) {
+	authUrl, stateToken, err := h.service.BeginLogin(r.Context(), chi.URLParam(r, "provider"))
+
+	if err != nil {
+		if errors.Is(err, service.ErrUnknownProvider) {
+			utils.RespondWithError(w, http.StatusNotFound, err.Error())
+			return
+		}
+
+		if errors.Is(err, service.ErrSocialProviderFailed) {
+			utils.RespondWithError(w, http.StatusBadGateway, err.Error())
+			return
+		}
+
+		utils.RespondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     socialLoginCookie,
+		Value:    stateToken,
+		Path:     "/",
+		MaxAge:   10 * 60,
+		SameSite: http.SameSiteLaxMode, // it has to come along on the redirect back from the provider
+		Secure:   false,                // true for https
+		HttpOnly: true,
+	})
+
+	http.Redirect(w, r, authUrl, http.StatusFound)
+}
+
+// the provider sends the browser back here. The refresh token is set as a cookie and the
+// browser goes on to the frontend, which picks up an access token from /auth/refresh.
+// Accounts with two factor authentication get the mfa token in the fragment instead
+func (h *SocialLoginHandler) HandleCallback(w http.ResponseWriter, r *http.Request) {
+	http.SetCookie(w, &http.Cookie{Name: socialLoginCookie, Value: "", MaxAge: -1, Path: "/"})
+
+	query := r.URL.Query()
+
+	// the user said no at the provider
+	if providerError := query.Get("error"); providerError != "" {
+		h.redirectToSite(w, r, url.Values{"error": {providerError}}, "")
+		return
+	}
+
+	cookie, err := r.Cookie(socialLoginCookie)
+
+	if err != nil {
+		h.redirectToSite(w, r, url.Values{"error": {service.ErrInvalidSocialLogin.Error()}}, "")
+		return
+	}
+
+	result, err := h.service.CompleteLogin(r.Context(), chi.URLParam(r, "provider"), cookie.Value, query.Get("state"),
+		query.Get("code"), r.UserAgent(), clientIP(r))
+
+	if err != nil {
+		message := "Something went wrong"
+
+		if errors.Is(err, service.ErrUnknownProvider) || errors.Is(err, service.ErrInvalidSocialLogin) ||
+			errors.Is(err, service.ErrSocialEmailRequired) || errors.Is(err, service.ErrSocialEmailConflict) ||
+			errors.Is(err, service.ErrSocialProviderFailed) || errors.Is(err, service.ErrUserSuspended) ||
+			errors.Is(err, utils.ErrInvalidEmail) || errors.Is(err, utils.ErrShortEmail) {
+			message = err.Error()
+		}
+
+		h.redirectToSite(w, r, url.Values{"error": {message}}, "")
+		return
+	}
+
+	// the fragment never reaches a server or its logs
+	if result.MfaToken != "" {
+		h.redirectToSite(w, r, nil, url.Values{"mfa_token": {result.MfaToken}}.Encode())
+		return
+	}
+
+	setRefreshTokenCookie(w, result.RefreshToken)
+	h.redirectToSite(w, r, nil, "")
+}
+
+func (h *SocialLoginHandler) redirectToSite(w http.ResponseWriter, r *http.Request, query url.Values, fragment string) {
+	target := h.site.URL + "/oauth/callback"
+
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+
+	if fragment != "" {
+		target += "#" + fragment
+	}
+
+	http.Redirect(w, r, target, http.StatusFound)
+}
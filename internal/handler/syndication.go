@@ -0,0 +1,121 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/harry713j/vibe_writer/internal/model"
+	"github.com/harry713j/vibe_writer/internal/service"
+	"github.com/harry713j/vibe_writer/internal/utils"
+)
+
+type SyndicationHandler struct {
+	service *service.SyndicationService
+}
+
+func NewSyndicationHandler(service *service.SyndicationService) *SyndicationHandler {
+	return &SyndicationHandler{
+		service: service,
+	}
+}
+
+type feedFormat string
+
+const (
+	rssFormat  feedFormat = "rss"
+	atomFormat feedFormat = "atom"
+)
+
+func (h *SyndicationHandler) HandleAuthorRSS(w http.ResponseWriter, r *http.Request) {
+	h.handleAuthorFeed(w, r, rssFormat)
+}
+
+func (h *SyndicationHandler) HandleAuthorAtom(w http.ResponseWriter, r *http.Request) {
+	h.handleAuthorFeed(w, r, atomFormat)
+}
+
+func (h *SyndicationHandler) HandleTagRSS(w http.ResponseWriter, r *http.Request) {
+	h.handleTagFeed(w, r, rssFormat)
+}
+
+func (h *SyndicationHandler) HandleTagAtom(w http.ResponseWriter, r *http.Request) {
+	h.handleTagFeed(w, r, atomFormat)
+}
+
+func (h *SyndicationHandler) handleAuthorFeed(w http.ResponseWriter, r *http.Request, format feedFormat) {
+	username := chi.URLParam(r, "username")
+
+	if username == "" {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid params")
+		return
+	}
+
+	feed, err := h.service.AuthorFeed(username)
+
+	if err != nil {
+		if errors.Is(err, service.ErrUserNotExists) {
+			utils.RespondWithError(w, http.StatusNotFound, err.Error())
+			return
+		}
+
+		utils.RespondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	h.respondWithFeed(w, r, feed, format)
+}
+
+func (h *SyndicationHandler) handleTagFeed(w http.ResponseWriter, r *http.Request, format feedFormat) {
+	tag := chi.URLParam(r, "tag")
+
+	if tag == "" {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid params")
+		return
+	}
+
+	feed, err := h.service.TagFeed(tag)
+
+	if err != nil {
+		if errors.Is(err, service.ErrTagNotExists) {
+			utils.RespondWithError(w, http.StatusNotFound, err.Error())
+			return
+		}
+
+		utils.RespondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	h.respondWithFeed(w, r, feed, format)
+}
+
+func (h *SyndicationHandler) respondWithFeed(w http.ResponseWriter, r *http.Request, feed *model.Feed, format feedFormat) {
+	var body []byte
+	var err error
+	contentType := utils.RSSContentType
+
+	if format == atomFormat {
+		body, err = utils.RenderAtom(feed, requestURL(r))
+		contentType = utils.AtomContentType
+	} else {
+		body, err = utils.RenderRSS(feed, requestURL(r))
+	}
+
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	utils.RespondWithFeed(w, r, contentType, body, feed.Updated)
+}
+
+// absolute url the request was made to, honouring a TLS terminating proxy
+func requestURL(r *http.Request) string {
+	scheme := "http"
+
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+
+	return scheme + "://" + r.Host + r.URL.RequestURI()
+}
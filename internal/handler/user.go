@@ -8,7 +8,6 @@ import (
 
 	"github.com/go-chi/chi/v5"
 	"github.com/harry713j/vibe_writer/internal/middleware"
-	"github.com/harry713j/vibe_writer/internal/model"
 	"github.com/harry713j/vibe_writer/internal/service"
 	"github.com/harry713j/vibe_writer/internal/utils"
 )
@@ -191,7 +190,10 @@ func (u *UserProfileHandler) HandleGetBlog(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	blogRes, err := u.blogService.GetBlog(username, slug)
+	// anonymous readers are uuid.Nil, signed in ones also see their own hidden comments
+	viewerId, _ := middleware.GetUserID(r)
+
+	blogRes, err := u.blogService.GetBlog(username, slug, viewerId)
 
 	if err != nil {
 		if errors.Is(err, service.ErrUserNotExists) || errors.Is(err, service.ErrBlogNotExists) {
@@ -229,6 +231,7 @@ func (u *UserProfileHandler) HandleRemoveAvatar(w http.ResponseWriter, r *http.R
 	utils.RespondWithJSON(w, http.StatusNoContent, "")
 }
 
+// comment threads of a blog, limit is optional and cursor is empty for the first page
 func (h *UserProfileHandler) HandleGetAllComments(w http.ResponseWriter, r *http.Request) {
 	username := chi.URLParam(r, "username")
 	slug := chi.URLParam(r, "slug")
@@ -238,10 +241,66 @@ func (h *UserProfileHandler) HandleGetAllComments(w http.ResponseWriter, r *http
 		return
 	}
 
-	comments, err := h.profileService.GetAllCommentsOfBlog(username, slug)
+	limit, err := limitParam(r)
+
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid query params value")
+		return
+	}
+
+	viewerId, _ := middleware.GetUserID(r)
+
+	comments, err := h.profileService.GetAllCommentsOfBlog(viewerId, username, slug, r.URL.Query().Get("cursor"), limit)
+
+	if err != nil {
+		if errors.Is(err, service.ErrUserNotExists) || errors.Is(err, service.ErrBlogNotExists) {
+			utils.RespondWithError(w, http.StatusNotFound, err.Error())
+			return
+		}
+
+		if errors.Is(err, utils.ErrInvalidCursor) {
+			utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		utils.RespondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, comments)
+}
+
+// the replies behind a comment's replies_cursor
+func (h *UserProfileHandler) HandleGetCommentReplies(w http.ResponseWriter, r *http.Request) {
+	username := chi.URLParam(r, "username")
+	slug := chi.URLParam(r, "slug")
+	commentId, err := strconv.ParseInt(chi.URLParam(r, "commentId"), 10, 64)
+
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid comment id")
+		return
+	}
+
+	limit, err := limitParam(r)
+
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid query params value")
+		return
+	}
+
+	viewerId, _ := middleware.GetUserID(r)
+
+	replies, err := h.profileService.GetCommentReplies(viewerId, username, slug, commentId, r.URL.Query().Get("cursor"),
+		limit)
 
 	if err != nil {
-		if errors.Is(err, service.ErrBlogNotExists) {
+		if errors.Is(err, service.ErrUserNotExists) || errors.Is(err, service.ErrBlogNotExists) ||
+			errors.Is(err, service.ErrCommentNotExists) {
+			utils.RespondWithError(w, http.StatusNotFound, err.Error())
+			return
+		}
+
+		if errors.Is(err, utils.ErrInvalidCursor) {
 			utils.RespondWithError(w, http.StatusBadRequest, err.Error())
 			return
 		}
@@ -250,7 +309,18 @@ func (h *UserProfileHandler) HandleGetAllComments(w http.ResponseWriter, r *http
 		return
 	}
 
-	utils.RespondWithJSON(w, http.StatusOK, map[string][]model.CommentWithStat{"comments": comments})
+	utils.RespondWithJSON(w, http.StatusOK, replies)
+}
+
+// 0 when the limit is left out, the service picks the default then
+func limitParam(r *http.Request) (int, error) {
+	value := r.URL.Query().Get("limit")
+
+	if value == "" {
+		return 0, nil
+	}
+
+	return strconv.Atoi(value)
 }
 
 func (h *UserProfileHandler) HandleGetBookmarks(w http.ResponseWriter, r *http.Request) {
@@ -0,0 +1,349 @@
+// Package oidctest is an OpenID Connect provider for trying social login locally and in tests.
+// It signs in whoever fills in its form or whoever a test asks for, so never expose it
+package oidctest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/harry713j/vibe_writer/internal/keyring"
+	"github.com/harry713j/vibe_writer/internal/utils"
+)
+
+var (
+	ErrLoginRejected = errors.New("the mock provider rejected the login")
+)
+
+// who signs in at the provider
+type User struct {
+	Email             string
+	EmailVerified     bool
+	Name              string
+	PreferredUsername string
+}
+
+type authorization struct {
+	clientId    string
+	redirectUri string
+	nonce       string
+	challenge   string
+	user        User
+	expireAt    time.Time
+}
+
+type Provider struct {
+	issuer       string
+	clientId     string
+	clientSecret string
+	keys         *keyring.Keyring
+
+	mu    sync.Mutex
+	codes map[string]authorization
+}
+
+var loginPage = template.Must(template.New("login").Parse(`<!doctype html>
+<title>Mock OIDC login</title>
+<h1>Mock OIDC login</h1>
+<form method="post">
+	{{range $key, $values := .}}{{range $values}}<input type="hidden" name="{{$key}}" value="{{.}}">{{end}}{{end}}
+	<p><label>Email <input name="email" type="email" required></label></p>
+	<p><label>Name <input name="name"></label></p>
+	<p><label>Username <input name="preferred_username"></label></p>
+	<p><label><input name="email_verified" type="checkbox" value="true" checked> Email verified</label></p>
+	<button type="submit">Sign in</button>
+</form>
+`))
+
+// a provider with a fresh RS256 key, issuer must be how the server reaches it
+func NewProvider(issuer, clientId, clientSecret string) (*Provider, error) {
+	keys, err := newKeyring()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &Provider{
+		issuer:       issuer,
+		clientId:     clientId,
+		clientSecret: clientSecret,
+		keys:         keys,
+		codes:        map[string]authorization{},
+	}, nil
+}
+
+// the server picks the key up through the jwks
+func newKeyring() (*keyring.Keyring, error) {
+	private, err := rsa.GenerateKey(rand.Reader, 2048)
+
+	if err != nil {
+		return nil, err
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := keyring.ParsePEM(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+
+	if err != nil {
+		return nil, err
+	}
+
+	return keyring.New(key)
+}
+
+// the same email is always the same subject
+func Subject(email string) string {
+	sum := sha256.Sum256([]byte(email))
+	return base64.RawURLEncoding.EncodeToString(sum[:16])
+}
+
+func (p *Provider) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", p.handleDiscovery)
+	mux.HandleFunc("GET /jwks", p.handleJWKS)
+	mux.HandleFunc("GET /authorize", p.handleAuthorizeForm)
+	mux.HandleFunc("POST /authorize", p.handleAuthorize)
+	mux.HandleFunc("POST /token", p.handleToken)
+
+	return mux
+}
+
+func (p *Provider) handleDiscovery(w http.ResponseWriter, r *http.Request) {
+	utils.RespondWithJSON(w, http.StatusOK, map[string]any{
+		"issuer":                                p.issuer,
+		"authorization_endpoint":                p.issuer + "/authorize",
+		"token_endpoint":                        p.issuer + "/token",
+		"jwks_uri":                              p.issuer + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+		"scopes_supported":                      []string{"openid", "email", "profile"},
+	})
+}
+
+func (p *Provider) handleJWKS(w http.ResponseWriter, r *http.Request) {
+	utils.RespondWithJSON(w, http.StatusOK, p.keys.JWKS())
+}
+
+func (p *Provider) handleAuthorizeForm(w http.ResponseWriter, r *http.Request) {
+	if err := p.checkAuthorizeRequest(r.URL.Query()); err != "" {
+		http.Error(w, err, http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	loginPage.Execute(w, r.URL.Query())
+}
+
+func (p *Provider) checkAuthorizeRequest(query url.Values) string {
+	switch {
+	case query.Get("client_id") != p.clientId:
+		return "unknown client_id"
+	case query.Get("response_type") != "code":
+		return "response_type must be code"
+	case query.Get("redirect_uri") == "":
+		return "redirect_uri is required"
+	case query.Get("code_challenge") == "" || query.Get("code_challenge_method") != "S256":
+		return "PKCE with S256 is required"
+	}
+
+	return ""
+}
+
+func (p *Provider) handleAuthorize(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "invalid form", http.StatusBadRequest)
+		return
+	}
+
+	if err := p.checkAuthorizeRequest(r.PostForm); err != "" {
+		http.Error(w, err, http.StatusBadRequest)
+		return
+	}
+
+	code, err := utils.RandomHex(32)
+
+	if err != nil {
+		http.Error(w, "failed to create code", http.StatusInternalServerError)
+		return
+	}
+
+	p.mu.Lock()
+	p.codes[code] = authorization{
+		clientId:    r.PostForm.Get("client_id"),
+		redirectUri: r.PostForm.Get("redirect_uri"),
+		nonce:       r.PostForm.Get("nonce"),
+		challenge:   r.PostForm.Get("code_challenge"),
+		user: User{
+			Email:             r.PostForm.Get("email"),
+			EmailVerified:     r.PostForm.Get("email_verified") == "true",
+			Name:              r.PostForm.Get("name"),
+			PreferredUsername: r.PostForm.Get("preferred_username"),
+		},
+		expireAt: time.Now().Add(time.Minute),
+	}
+	p.mu.Unlock()
+
+	redirect := r.PostForm.Get("redirect_uri") + "?" + url.Values{
+		"code":  {code},
+		"state": {r.PostForm.Get("state")},
+	}.Encode()
+
+	http.Redirect(w, r, redirect, http.StatusFound)
+}
+
+func tokenError(w http.ResponseWriter, code string) {
+	utils.RespondWithJSON(w, http.StatusBadRequest, map[string]string{"error": code})
+}
+
+func (p *Provider) handleToken(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		tokenError(w, "invalid_request")
+		return
+	}
+
+	// clients authenticate with basic auth or in the form
+	clientId, clientSecret, ok := r.BasicAuth()
+
+	if !ok {
+		clientId, clientSecret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+
+	if clientId != p.clientId || subtle.ConstantTimeCompare([]byte(clientSecret), []byte(p.clientSecret)) != 1 {
+		tokenError(w, "invalid_client")
+		return
+	}
+
+	if r.PostForm.Get("grant_type") != "authorization_code" {
+		tokenError(w, "unsupported_grant_type")
+		return
+	}
+
+	// codes work once
+	p.mu.Lock()
+	auth, ok := p.codes[r.PostForm.Get("code")]
+	delete(p.codes, r.PostForm.Get("code"))
+	p.mu.Unlock()
+
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	challenge := base64.RawURLEncoding.EncodeToString(sum[:])
+
+	if !ok || time.Now().After(auth.expireAt) || auth.clientId != clientId ||
+		auth.redirectUri != r.PostForm.Get("redirect_uri") || auth.challenge != challenge {
+		tokenError(w, "invalid_grant")
+		return
+	}
+
+	now := time.Now()
+
+	idToken, err := p.keys.Sign(jwt.MapClaims{
+		"iss":                p.issuer,
+		"aud":                p.clientId,
+		"sub":                Subject(auth.user.Email),
+		"nonce":              auth.nonce,
+		"email":              auth.user.Email,
+		"email_verified":     auth.user.EmailVerified,
+		"name":               auth.user.Name,
+		"preferred_username": auth.user.PreferredUsername,
+		"iat":                now.Unix(),
+		"exp":                now.Add(5 * time.Minute).Unix(),
+	})
+
+	if err != nil {
+		tokenError(w, "server_error")
+		return
+	}
+
+	accessToken, err := utils.RandomHex(32)
+
+	if err != nil {
+		tokenError(w, "server_error")
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	utils.RespondWithJSON(w, http.StatusOK, map[string]any{
+		"access_token": accessToken,
+		"token_type":   "Bearer",
+		"expires_in":   300,
+		"id_token":     idToken,
+	})
+}
+
+// a provider on a local httptest server, Close shuts it down
+type Server struct {
+	*httptest.Server
+	Provider *Provider
+}
+
+func NewServer(clientId, clientSecret string) (*Server, error) {
+	server := httptest.NewUnstartedServer(nil)
+	issuer := "http://" + server.Listener.Addr().String()
+
+	provider, err := NewProvider(issuer, clientId, clientSecret)
+
+	if err != nil {
+		server.Close()
+		return nil, err
+	}
+
+	server.Config.Handler = provider.Handler()
+	server.Start()
+
+	return &Server{Server: server, Provider: provider}, nil
+}
+
+// sign the user in at the authorization url the client built, the way the form does, and
+// return where the provider sends the browser back to
+func (s *Server) Login(authUrl string, user User) (*url.URL, error) {
+	parsed, err := url.Parse(authUrl)
+
+	if err != nil {
+		return nil, err
+	}
+
+	form := parsed.Query()
+	form.Set("email", user.Email)
+	form.Set("name", user.Name)
+	form.Set("preferred_username", user.PreferredUsername)
+
+	if user.EmailVerified {
+		form.Set("email_verified", "true")
+	}
+
+	client := s.Client()
+	client.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	resp, err := client.PostForm(s.URL+"/authorize", form)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusFound {
+		return nil, ErrLoginRejected
+	}
+
+	return resp.Location()
+}
@@ -0,0 +1,160 @@
+package identity
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/harry713j/vibe_writer/internal/config"
+	"golang.org/x/oauth2"
+)
+
+var (
+	ErrNoIdToken     = errors.New("provider returned no id token")
+	ErrNonceMismatch = errors.New("id token nonce does not match the login")
+)
+
+// what the provider tells us about the user
+type Identity struct {
+	Provider          string
+	Subject           string
+	Email             string
+	EmailVerified     bool
+	Name              string
+	PreferredUsername string
+}
+
+// an OpenID Connect provider, discovery happens on first use so a provider that is down
+// doesn't keep the server from starting
+type Provider struct {
+	Name   string
+	config config.OIDCProviderConfig
+
+	callbackURL string
+	mu          sync.Mutex
+	oauth       *oauth2.Config
+	verifier    *oidc.IDTokenVerifier
+}
+
+func newProvider(providerConfig config.OIDCProviderConfig, callbackURL string) *Provider {
+	return &Provider{
+		Name:        providerConfig.Name,
+		config:      providerConfig,
+		callbackURL: callbackURL + "/auth/oidc/" + providerConfig.Name + "/callback",
+	}
+}
+
+func (p *Provider) discover(ctx context.Context) (*oauth2.Config, *oidc.IDTokenVerifier, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.oauth != nil {
+		return p.oauth, p.verifier, nil
+	}
+
+	provider, err := oidc.NewProvider(ctx, p.config.Issuer)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	p.oauth = &oauth2.Config{
+		ClientID:     p.config.ClientID,
+		ClientSecret: p.config.ClientSecret,
+		Endpoint:     provider.Endpoint(),
+		RedirectURL:  p.callbackURL,
+		Scopes:       []string{oidc.ScopeOpenID, "email", "profile"},
+	}
+	p.verifier = provider.Verifier(&oidc.Config{ClientID: p.config.ClientID})
+
+	return p.oauth, p.verifier, nil
+}
+
+// where to send the user, the code challenge is derived from the PKCE verifier
+func (p *Provider) AuthCodeURL(ctx context.Context, state, nonce, verifier string) (string, error) {
+	oauth, _, err := p.discover(ctx)
+
+	if err != nil {
+		return "", err
+	}
+
+	return oauth.AuthCodeURL(state, oidc.Nonce(nonce), oauth2.S256ChallengeOption(verifier)), nil
+}
+
+// trade the code for tokens and check the id token belongs to this login
+func (p *Provider) Exchange(ctx context.Context, code, verifier, nonce string) (*Identity, error) {
+	oauth, idTokenVerifier, err := p.discover(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := oauth.Exchange(ctx, code, oauth2.VerifierOption(verifier))
+
+	if err != nil {
+		return nil, err
+	}
+
+	rawIdToken, ok := token.Extra("id_token").(string)
+
+	if !ok || rawIdToken == "" {
+		return nil, ErrNoIdToken
+	}
+
+	idToken, err := idTokenVerifier.Verify(ctx, rawIdToken)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if idToken.Nonce != nonce {
+		return nil, ErrNonceMismatch
+	}
+
+	var claims struct {
+		Email             string `json:"email"`
+		EmailVerified     any    `json:"email_verified"` // some providers send it as a string
+		Name              string `json:"name"`
+		PreferredUsername string `json:"preferred_username"`
+	}
+
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, err
+	}
+
+	return &Identity{
+		Provider:          p.Name,
+		Subject:           idToken.Subject,
+		Email:             claims.Email,
+		EmailVerified:     claims.EmailVerified == true || claims.EmailVerified == "true",
+		Name:              claims.Name,
+		PreferredUsername: claims.PreferredUsername,
+	}, nil
+}
+
+// the configured providers by name
+type Registry struct {
+	providers map[string]*Provider
+	names     []string
+}
+
+func NewRegistry(oidcConfig *config.OIDCConfig) *Registry {
+	registry := &Registry{providers: map[string]*Provider{}}
+
+	for _, providerConfig := range oidcConfig.Providers {
+		registry.providers[providerConfig.Name] = newProvider(providerConfig, oidcConfig.CallbackURL)
+		registry.names = append(registry.names, providerConfig.Name)
+	}
+
+	return registry
+}
+
+func (r *Registry) Get(name string) (*Provider, bool) {
+	provider, ok := r.providers[name]
+	return provider, ok
+}
+
+func (r *Registry) Names() []string {
+	return r.names
+}
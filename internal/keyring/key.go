@@ -0,0 +1,169 @@
+package keyring
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"math/big"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	ErrInvalidPEM     = errors.New("no PEM block found in key")
+	ErrUnsupportedKey = errors.New("key must be RSA or Ed25519")
+	ErrWeakRSAKey     = errors.New("RSA keys must be at least 2048 bits")
+	ErrNoSigningKey   = errors.New("keyring has no signing key")
+	ErrUnknownKey     = errors.New("token is signed with an unknown key")
+	ErrUnexpectedAlg  = errors.New("token algorithm does not match its key")
+	errNotPublic      = errors.New("hmac keys have no public form")
+)
+
+// a key of the ring, verification only keys have no sign key
+type Key struct {
+	Id        string
+	Method    jwt.SigningMethod
+	signKey   any
+	verifyKey any
+}
+
+// a symmetric key, it is never published in the jwks
+func NewHMACKey(id string, secret []byte) *Key {
+	return &Key{Id: id, Method: jwt.SigningMethodHS256, signKey: secret, verifyKey: secret}
+}
+
+// parse a PEM private key (PKCS1 or PKCS8) or public key (PKIX), the key id is its
+// RFC 7638 thumbprint so the same key always gets the same id
+func ParsePEM(data []byte) (*Key, error) {
+	block, _ := pem.Decode(data)
+
+	if block == nil {
+		return nil, ErrInvalidPEM
+	}
+
+	var parsed any
+	var err error
+
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, ErrUnsupportedKey
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return newKey(parsed)
+}
+
+func newKey(parsed any) (*Key, error) {
+	key := &Key{}
+
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		key.Method, key.signKey, key.verifyKey = jwt.SigningMethodRS256, k, &k.PublicKey
+	case *rsa.PublicKey:
+		key.Method, key.verifyKey = jwt.SigningMethodRS256, k
+	case ed25519.PrivateKey:
+		key.Method, key.signKey, key.verifyKey = jwt.SigningMethodEdDSA, k, k.Public()
+	case ed25519.PublicKey:
+		key.Method, key.verifyKey = jwt.SigningMethodEdDSA, k
+	default:
+		return nil, ErrUnsupportedKey
+	}
+
+	if rsaKey, ok := key.verifyKey.(*rsa.PublicKey); ok && rsaKey.N.BitLen() < 2048 {
+		return nil, ErrWeakRSAKey
+	}
+
+	jwk, err := key.JWK()
+
+	if err != nil {
+		return nil, err
+	}
+
+	key.Id, err = thumbprint(jwk)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+func (k *Key) CanSign() bool {
+	return k.signKey != nil
+}
+
+// public form of the key as published in the jwks
+type JWK struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+}
+
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+func (k *Key) JWK() (*JWK, error) {
+	encode := base64.RawURLEncoding.EncodeToString
+
+	switch public := k.verifyKey.(type) {
+	case *rsa.PublicKey:
+		return &JWK{Kty: "RSA", Use: "sig", Alg: k.Method.Alg(), Kid: k.Id,
+			N: encode(public.N.Bytes()), E: encode(big.NewInt(int64(public.E)).Bytes())}, nil
+	case ed25519.PublicKey:
+		return &JWK{Kty: "OKP", Use: "sig", Alg: k.Method.Alg(), Kid: k.Id, Crv: "Ed25519", X: encode(public)}, nil
+	}
+
+	return nil, errNotPublic
+}
+
+// RFC 7638, sha256 over the required members in lexical order
+func thumbprint(jwk *JWK) (string, error) {
+	var members any
+
+	switch jwk.Kty {
+	case "RSA":
+		members = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{jwk.E, jwk.Kty, jwk.N}
+	case "OKP":
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+		}{jwk.Crv, jwk.Kty, jwk.X}
+	default:
+		return "", ErrUnsupportedKey
+	}
+
+	data, err := json.Marshal(members)
+
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(data)
+
+	return base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
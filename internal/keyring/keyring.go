@@ -0,0 +1,144 @@
+package keyring
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/harry713j/vibe_writer/internal/config"
+)
+
+// id of the HS256 secret, tokens signed before keys had ids carry no kid and are
+// checked against the secret as well
+const SecretKeyId = "secret"
+
+// signs with one key and verifies with every key it holds, picked by the kid header
+type Keyring struct {
+	signing *Key
+	keys    map[string]*Key
+	ordered []*Key // signing key first, keeps the jwks stable
+}
+
+func New(signing *Key, verify ...*Key) (*Keyring, error) {
+	if signing == nil || !signing.CanSign() {
+		return nil, ErrNoSigningKey
+	}
+
+	ring := &Keyring{signing: signing, keys: map[string]*Key{}}
+
+	for _, key := range append([]*Key{signing}, verify...) {
+		if _, ok := ring.keys[key.Id]; ok {
+			continue
+		}
+
+		ring.keys[key.Id] = key
+		ring.ordered = append(ring.ordered, key)
+	}
+
+	return ring, nil
+}
+
+// the signing key file wins over the secret, the secret stays accepted for verification
+// so tokens it signed keep working while switching over
+func Load(config *config.KeyConfig) (*Keyring, error) {
+	var signing *Key
+	var verify []*Key
+
+	if config.Secret != "" {
+		signing = NewHMACKey(SecretKeyId, []byte(config.Secret))
+	}
+
+	if config.SigningKeyFile != "" {
+		key, err := readKey(config.SigningKeyFile)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if signing != nil {
+			verify = append(verify, signing)
+		}
+
+		signing = key
+	}
+
+	for _, file := range config.VerifyKeyFiles {
+		key, err := readKey(file)
+
+		if err != nil {
+			return nil, err
+		}
+
+		verify = append(verify, key)
+	}
+
+	return New(signing, verify...)
+}
+
+func readKey(file string) (*Key, error) {
+	data, err := os.ReadFile(file)
+
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := ParsePEM(data)
+
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+
+	return key, nil
+}
+
+func (r *Keyring) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(r.signing.Method, claims)
+	token.Header["kid"] = r.signing.Id
+
+	return token.SignedString(r.signing.signKey)
+}
+
+// jwt.Keyfunc choosing the key by kid, the algorithm has to be the one of the key
+func (r *Keyring) Keyfunc(token *jwt.Token) (any, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	if kid == "" {
+		kid = SecretKeyId
+	}
+
+	key, ok := r.keys[kid]
+
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, ErrUnexpectedAlg
+	}
+
+	return key.verifyKey, nil
+}
+
+// algorithms of the keys on the ring, for jwt.WithValidMethods
+func (r *Keyring) Methods() []string {
+	var methods []string
+
+	for _, key := range r.ordered {
+		methods = append(methods, key.Method.Alg())
+	}
+
+	return methods
+}
+
+// public keys for other services to verify our tokens, the HS256 secret is left out
+func (r *Keyring) JWKS() JWKSet {
+	set := JWKSet{Keys: []JWK{}}
+
+	for _, key := range r.ordered {
+		if jwk, err := key.JWK(); err == nil {
+			set.Keys = append(set.Keys, *jwk)
+		}
+	}
+
+	return set
+}
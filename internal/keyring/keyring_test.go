@@ -0,0 +1,282 @@
+package keyring
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func pemBlock(t *testing.T, blockType string, der []byte) []byte {
+	t.Helper()
+	return pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+}
+
+func ed25519PEM(t *testing.T) (private, public []byte) {
+	t.Helper()
+
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	privDer, err := x509.MarshalPKCS8PrivateKey(priv)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pubDer, err := x509.MarshalPKIXPublicKey(pub)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return pemBlock(t, "PRIVATE KEY", privDer), pemBlock(t, "PUBLIC KEY", pubDer)
+}
+
+func rsaPEM(t *testing.T, bits int) []byte {
+	t.Helper()
+
+	priv, err := rsa.GenerateKey(rand.Reader, bits)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return pemBlock(t, "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(priv))
+}
+
+func mustParse(t *testing.T, data []byte) *Key {
+	t.Helper()
+
+	key, err := ParsePEM(data)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return key
+}
+
+func TestParsePEM(t *testing.T) {
+	edPrivate, edPublic := ed25519PEM(t)
+
+	tests := []struct {
+		name    string
+		data    []byte
+		alg     string
+		canSign bool
+		wantErr error
+	}{
+		{name: "ed25519 private", data: edPrivate, alg: "EdDSA", canSign: true},
+		{name: "ed25519 public", data: edPublic, alg: "EdDSA"},
+		{name: "rsa private", data: rsaPEM(t, 2048), alg: "RS256", canSign: true},
+		{name: "weak rsa", data: rsaPEM(t, 1024), wantErr: ErrWeakRSAKey},
+		{name: "not pem", data: []byte("secret"), wantErr: ErrInvalidPEM},
+		{name: "unsupported block", data: pemBlock(t, "CERTIFICATE", []byte{1}), wantErr: ErrUnsupportedKey},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := ParsePEM(tt.data)
+
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("ParsePEM() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if key.Method.Alg() != tt.alg {
+				t.Errorf("alg = %s, want %s", key.Method.Alg(), tt.alg)
+			}
+
+			if key.CanSign() != tt.canSign {
+				t.Errorf("CanSign() = %v, want %v", key.CanSign(), tt.canSign)
+			}
+
+			if key.Id == "" {
+				t.Error("key has no id")
+			}
+		})
+	}
+}
+
+// the public half of a key has to get the kid of the private one, or a retired key
+// moved to the verify keys would no longer match the tokens it signed
+func TestKeyIdIsThumbprint(t *testing.T) {
+	private, public := ed25519PEM(t)
+
+	if privateId, publicId := mustParse(t, private).Id, mustParse(t, public).Id; privateId != publicId {
+		t.Errorf("private kid %s != public kid %s", privateId, publicId)
+	}
+}
+
+func TestNew(t *testing.T) {
+	private, public := ed25519PEM(t)
+	signing := mustParse(t, private)
+
+	if _, err := New(nil); !errors.Is(err, ErrNoSigningKey) {
+		t.Errorf("New(nil) error = %v, want %v", err, ErrNoSigningKey)
+	}
+
+	if _, err := New(mustParse(t, public)); !errors.Is(err, ErrNoSigningKey) {
+		t.Errorf("New(public key) error = %v, want %v", err, ErrNoSigningKey)
+	}
+
+	ring, err := New(signing, mustParse(t, public), NewHMACKey(SecretKeyId, []byte("secret")))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if methods := ring.Methods(); len(methods) != 2 || methods[0] != "EdDSA" || methods[1] != "HS256" {
+		t.Errorf("Methods() = %v, want [EdDSA HS256]", methods)
+	}
+}
+
+func TestSignAndVerify(t *testing.T) {
+	current := mustParse(t, rsaPEM(t, 2048))
+	retired := mustParse(t, rsaPEM(t, 2048))
+	edPrivate, edPublic := ed25519PEM(t)
+	ed := mustParse(t, edPrivate)
+	secret := NewHMACKey(SecretKeyId, []byte("secret"))
+
+	ring, err := New(current, retired, mustParse(t, edPublic), secret)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims := jwt.MapClaims{"sub": "user", "exp": time.Now().Add(time.Minute).Unix()}
+
+	sign := func(method jwt.SigningMethod, kid string, key any) string {
+		token := jwt.NewWithClaims(method, claims)
+
+		if kid != "" {
+			token.Header["kid"] = kid
+		}
+
+		signed, err := token.SignedString(key)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return signed
+	}
+
+	signedByRing, err := ring.Sign(claims)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	retiredRing, err := New(retired)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signedByRetired, err := retiredRing.Sign(claims)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stranger := mustParse(t, rsaPEM(t, 2048))
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr error
+	}{
+		{name: "signing key", token: signedByRing},
+		{name: "retired key", token: signedByRetired},
+		{name: "ed25519 verify key", token: sign(jwt.SigningMethodEdDSA, ed.Id, ed.signKey)},
+		{name: "secret with kid", token: sign(jwt.SigningMethodHS256, SecretKeyId, []byte("secret"))},
+		{name: "secret without kid", token: sign(jwt.SigningMethodHS256, "", []byte("secret"))},
+		{name: "unknown kid", token: sign(jwt.SigningMethodRS256, stranger.Id, stranger.signKey), wantErr: ErrUnknownKey},
+		{name: "right kid wrong key", token: sign(jwt.SigningMethodRS256, current.Id, stranger.signKey),
+			wantErr: jwt.ErrTokenSignatureInvalid},
+		{name: "wrong secret", token: sign(jwt.SigningMethodHS256, "", []byte("guess")), wantErr: jwt.ErrTokenSignatureInvalid},
+		// the public key used as an HMAC secret, the classic algorithm confusion
+		{name: "algorithm of another key", token: sign(jwt.SigningMethodHS256, current.Id,
+			x509.MarshalPKCS1PublicKey(current.verifyKey.(*rsa.PublicKey))), wantErr: ErrUnexpectedAlg},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := jwt.Parse(tt.token, ring.Keyfunc, jwt.WithValidMethods(ring.Methods()))
+
+			if tt.wantErr == nil && err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Parse() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSignUsesSigningKid(t *testing.T) {
+	signing := mustParse(t, rsaPEM(t, 2048))
+	ring, err := New(signing, NewHMACKey(SecretKeyId, []byte("secret")))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signed, err := ring.Sign(jwt.MapClaims{"sub": "user"})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token, _, err := jwt.NewParser().ParseUnverified(signed, jwt.MapClaims{})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if kid := token.Header["kid"]; kid != signing.Id {
+		t.Errorf("kid = %v, want %s", kid, signing.Id)
+	}
+
+	if alg := token.Method.Alg(); alg != "RS256" {
+		t.Errorf("alg = %s, want RS256", alg)
+	}
+}
+
+func TestJWKSLeavesOutSecret(t *testing.T) {
+	private, _ := ed25519PEM(t)
+	signing := mustParse(t, private)
+	ring, err := New(signing, NewHMACKey(SecretKeyId, []byte("secret")))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	set := ring.JWKS()
+
+	if len(set.Keys) != 1 {
+		t.Fatalf("JWKS() has %d keys, want 1", len(set.Keys))
+	}
+
+	if jwk := set.Keys[0]; jwk.Kid != signing.Id || jwk.Kty != "OKP" || jwk.Crv != "Ed25519" {
+		t.Errorf("JWKS() key = %+v", jwk)
+	}
+}
@@ -0,0 +1,65 @@
+package limiter
+
+import "time"
+
+// how failures of one key are punished. After FreeAttempts failures every further one
+// doubles the wait starting at BaseDelay up to MaxDelay, LockoutAfter failures lock the
+// key for LockoutFor
+type Policy struct {
+	FreeAttempts int
+	BaseDelay    time.Duration
+	MaxDelay     time.Duration
+	LockoutAfter int
+	LockoutFor   time.Duration
+}
+
+type Limiter struct {
+	store Store
+}
+
+func New(store Store) *Limiter {
+	return &Limiter{store: store}
+}
+
+// count an attempt of the key up front, so parallel attempts can't all pass a check before
+// any of them fails. Returns the wait when the key may not try now, nothing is counted then
+func (l *Limiter) Reserve(key string, policy Policy, now time.Time) (time.Duration, error) {
+	// counters outlive the longest wait they can cause
+	ttl := max(policy.LockoutFor, policy.MaxDelay)
+
+	return l.store.Reserve(key, policy, now, ttl)
+}
+
+// take back a reserved attempt that turned out not to be a failure
+func (l *Limiter) Release(key string) error {
+	return l.store.Release(key)
+}
+
+// forget the failures of the key, after a successful attempt
+func (l *Limiter) Reset(key string) error {
+	return l.store.Delete(key)
+}
+
+// how long an entry has to wait before its next attempt, 0 when it may try now
+func (p Policy) Wait(entry Entry, now time.Time) time.Duration {
+	var delay time.Duration
+
+	switch {
+	case p.LockoutAfter > 0 && entry.Failures >= p.LockoutAfter:
+		delay = p.LockoutFor
+	case entry.Failures > p.FreeAttempts:
+		delay = p.BaseDelay
+		for i := p.FreeAttempts + 1; i < entry.Failures && delay < p.MaxDelay; i++ {
+			delay *= 2
+		}
+		delay = min(delay, p.MaxDelay)
+	default:
+		return 0
+	}
+
+	if wait := entry.LastFailure.Add(delay).Sub(now); wait > 0 {
+		return wait
+	}
+
+	return 0
+}
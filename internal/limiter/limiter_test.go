@@ -0,0 +1,203 @@
+package limiter
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+var testPolicy = Policy{
+	FreeAttempts: 3,
+	BaseDelay:    time.Second,
+	MaxDelay:     10 * time.Second,
+	LockoutAfter: 10,
+	LockoutFor:   time.Minute,
+}
+
+func TestPolicyWait(t *testing.T) {
+	now := time.Date(2025, 12, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		failures int
+		since    time.Duration // time since the last failure
+		want     time.Duration
+	}{
+		{name: "no failures", failures: 0, want: 0},
+		{name: "free attempts", failures: 3, want: 0},
+		{name: "first delayed attempt", failures: 4, want: time.Second},
+		{name: "delay doubles", failures: 5, want: 2 * time.Second},
+		{name: "delay doubles again", failures: 6, want: 4 * time.Second},
+		{name: "delay stops at max", failures: 9, want: 10 * time.Second},
+		{name: "lockout", failures: 10, want: time.Minute},
+		{name: "part of the delay passed", failures: 5, since: 500 * time.Millisecond, want: 1500 * time.Millisecond},
+		{name: "delay passed", failures: 5, since: 3 * time.Second, want: 0},
+		{name: "lockout passed", failures: 12, since: 2 * time.Minute, want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry := Entry{Failures: tt.failures, LastFailure: now.Add(-tt.since)}
+
+			if got := testPolicy.Wait(entry, now); got != tt.want {
+				t.Errorf("Wait() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPolicyWaitWithoutLockout(t *testing.T) {
+	policy := testPolicy
+	policy.LockoutAfter = 0
+	now := time.Now()
+
+	if got := policy.Wait(Entry{Failures: 50, LastFailure: now}, now); got != policy.MaxDelay {
+		t.Errorf("Wait() = %v, want %v", got, policy.MaxDelay)
+	}
+}
+
+func TestMemoryStoreReserve(t *testing.T) {
+	now := time.Date(2025, 12, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		attempts []time.Duration // offsets from now of earlier reservations
+		at       time.Duration
+		want     time.Duration
+	}{
+		{name: "first attempt", at: 0, want: 0},
+		{name: "within free attempts", attempts: []time.Duration{0, 0}, at: 0, want: 0},
+		{name: "after free attempts", attempts: []time.Duration{0, 0, 0, 0}, at: 0, want: time.Second},
+		{name: "after the delay", attempts: []time.Duration{0, 0, 0, 0}, at: time.Second, want: 0},
+		{name: "entry expired", attempts: []time.Duration{0, 0, 0, 0}, at: 2 * time.Minute, want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewMemoryStore()
+
+			for _, offset := range tt.attempts {
+				if _, err := store.Reserve("key", testPolicy, now.Add(offset), time.Minute); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			got, err := store.Reserve("key", testPolicy, now.Add(tt.at), time.Minute)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got != tt.want {
+				t.Errorf("Reserve() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMemoryStoreReserveDoesNotCountBlockedAttempts(t *testing.T) {
+	store := NewMemoryStore()
+	now := time.Now()
+
+	for range 4 {
+		store.Reserve("key", testPolicy, now, time.Minute)
+	}
+
+	for range 5 {
+		if wait, _ := store.Reserve("key", testPolicy, now, time.Minute); wait == 0 {
+			t.Fatal("expected the key to wait")
+		}
+	}
+
+	if failures := store.entries["key"].Failures; failures != 4 {
+		t.Errorf("failures = %d, want 4", failures)
+	}
+}
+
+func TestMemoryStoreReserveIsAtomic(t *testing.T) {
+	store := NewMemoryStore()
+	now := time.Now()
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	allowed := 0
+
+	for range 50 {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			if wait, _ := store.Reserve("key", testPolicy, now, time.Minute); wait == 0 {
+				mu.Lock()
+				allowed++
+				mu.Unlock()
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	// the free attempts and the first delayed one, the rest has to wait for it
+	if want := testPolicy.FreeAttempts + 1; allowed != want {
+		t.Errorf("allowed = %d, want %d", allowed, want)
+	}
+}
+
+func TestMemoryStoreRelease(t *testing.T) {
+	tests := []struct {
+		name     string
+		reserved int
+		want     int
+	}{
+		{name: "unknown key", reserved: 0, want: 0},
+		{name: "last attempt", reserved: 1, want: 0},
+		{name: "one of several", reserved: 3, want: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewMemoryStore()
+			now := time.Now()
+
+			for range tt.reserved {
+				store.Reserve("key", testPolicy, now, time.Minute)
+			}
+
+			if err := store.Release("key"); err != nil {
+				t.Fatal(err)
+			}
+
+			entry, ok := store.entries["key"]
+
+			if tt.want == 0 && ok {
+				t.Fatalf("entry kept with %d failures", entry.Failures)
+			}
+
+			if entry.Failures != tt.want {
+				t.Errorf("failures = %d, want %d", entry.Failures, tt.want)
+			}
+		})
+	}
+}
+
+func TestLimiterReset(t *testing.T) {
+	l := New(NewMemoryStore())
+	now := time.Now()
+
+	for range 10 {
+		l.Reserve("key", testPolicy, now)
+	}
+
+	if wait, _ := l.Reserve("key", testPolicy, now); wait == 0 {
+		t.Fatal("expected the key to wait")
+	}
+
+	if err := l.Reset("key"); err != nil {
+		t.Fatal(err)
+	}
+
+	if wait, _ := l.Reserve("key", testPolicy, now); wait != 0 {
+		t.Errorf("Reserve() after Reset = %v, want 0", wait)
+	}
+}
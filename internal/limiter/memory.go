@@ -0,0 +1,95 @@
+package limiter
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type memoryEntry struct {
+	Entry
+	expireAt time.Time
+}
+
+// in-process store, counters are lost on restart and not shared between instances
+type MemoryStore struct {
+	mu      sync.Mutex
+	entries map[string]memoryEntry
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{entries: make(map[string]memoryEntry)}
+}
+
+func (s *MemoryStore) Reserve(key string, policy Policy, now time.Time, ttl time.Duration) (time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.entries[key]
+
+	if !ok || now.After(entry.expireAt) {
+		entry = memoryEntry{}
+	}
+
+	if wait := policy.Wait(entry.Entry, now); wait > 0 {
+		return wait, nil
+	}
+
+	entry.Failures++
+	entry.LastFailure = now
+	entry.expireAt = now.Add(ttl)
+	s.entries[key] = entry
+
+	return 0, nil
+}
+
+func (s *MemoryStore) Release(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.entries[key]
+
+	if !ok {
+		return nil
+	}
+
+	if entry.Failures <= 1 {
+		delete(s.entries, key)
+		return nil
+	}
+
+	entry.Failures--
+	s.entries[key] = entry
+
+	return nil
+}
+
+func (s *MemoryStore) Delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.entries, key)
+
+	return nil
+}
+
+// drop expired entries every interval so the map doesn't grow forever, runs until ctx is done
+func (s *MemoryStore) RunJanitor(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			s.mu.Lock()
+			for key, entry := range s.entries {
+				if now.After(entry.expireAt) {
+					delete(s.entries, key)
+				}
+			}
+			s.mu.Unlock()
+		}
+	}
+}
@@ -0,0 +1,20 @@
+package limiter
+
+import "time"
+
+// failures recorded for a key
+type Entry struct {
+	Failures    int
+	LastFailure time.Time
+}
+
+// where attempts are kept, the memory store serves a single instance, a shared
+// backend (redis, postgres) lets several instances see the same counters
+type Store interface {
+	// in one step, the wait of the key under policy, or when there is none count an attempt
+	// at now, the entry is forgotten ttl after its last attempt
+	Reserve(key string, policy Policy, now time.Time, ttl time.Duration) (time.Duration, error)
+	// take back one attempt counted by Reserve
+	Release(key string) error
+	Delete(key string) error
+}
@@ -0,0 +1,46 @@
+package mailer
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"time"
+
+	"github.com/harry713j/vibe_writer/internal/config"
+)
+
+// plain text email
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+type Mailer interface {
+	Send(msg Message) error
+}
+
+// mailer for the configured driver, anything but smtp writes mails to disk or the log
+func New(config *config.MailConfig) Mailer {
+	if config.Driver == "smtp" {
+		return NewSMTPMailer(config.Host, config.Port, config.Username, config.Password, config.From)
+	}
+
+	return NewFileMailer(config.Dir, config.From)
+}
+
+// RFC 5322 message with headers
+func (m Message) bytes(from string) []byte {
+	var buf bytes.Buffer
+
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", m.To)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", m.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	buf.WriteString("\r\n")
+	buf.WriteString(m.Body)
+
+	return buf.Bytes()
+}
@@ -0,0 +1,39 @@
+package mailer
+
+import (
+	"net"
+	"net/mail"
+	"net/smtp"
+)
+
+type SMTPMailer struct {
+	addr string
+	auth smtp.Auth
+	from string
+}
+
+func NewSMTPMailer(host, port, username, password, from string) *SMTPMailer {
+	var auth smtp.Auth
+
+	// local relays usually don't want credentials
+	if username != "" {
+		auth = smtp.PlainAuth("", username, password, host)
+	}
+
+	return &SMTPMailer{
+		addr: net.JoinHostPort(host, port),
+		auth: auth,
+		from: from,
+	}
+}
+
+func (m *SMTPMailer) Send(msg Message) error {
+	// the envelope wants the bare address, the From header keeps the display name
+	sender := m.from
+
+	if address, err := mail.ParseAddress(m.from); err == nil {
+		sender = address.Address
+	}
+
+	return smtp.SendMail(m.addr, m.auth, sender, []string{msg.To}, msg.bytes(m.from))
+}
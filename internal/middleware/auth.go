@@ -5,66 +5,244 @@ import (
 	"errors"
 	"log"
 	"net/http"
+	"slices"
 	"strings"
 
 	"github.com/google/uuid"
+	"github.com/harry713j/vibe_writer/internal/model"
 	"github.com/harry713j/vibe_writer/internal/service"
 	"github.com/harry713j/vibe_writer/internal/utils"
 )
 
 type contextKey string
 
-const userIdKey contextKey = "userID"
+const (
+	userIdKey    contextKey = "userID"
+	sessionIdKey contextKey = "sessionID"
+	roleKey      contextKey = "role"
+)
 
+// accepts access tokens only, personal access tokens are turned away
 func AuthMiddleware(authService *service.AuthService) func(http.Handler) http.Handler {
+	return authenticate(authService, "")
+}
+
+// accepts access tokens and personal access tokens that carry the scope
+func ScopedAuth(authService *service.AuthService, scope model.TokenScope) func(http.Handler) http.Handler {
+	return authenticate(authService, scope)
+}
+
+// lets anonymous requests through. A token that can't be used, expired or without the
+// blogs:read scope, is ignored and the request is served as an anonymous one
+func OptionalAuth(authService *service.AuthService) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			// extract the access token
-			authHeader := r.Header.Get("Authorization")
-
-			if authHeader == "" {
-				utils.RespondWithError(w, http.StatusUnauthorized, "No authorization header found")
+			if r.Header.Get("Authorization") == "" {
+				next.ServeHTTP(w, r)
 				return
 			}
 
-			authValues := strings.Split(authHeader, " ")
+			ctx, authErr := authorize(authService, model.SCOPE_BLOGS_READ, r)
 
-			if len(authValues) != 2 || authValues[0] != "Bearer" {
-				utils.RespondWithError(w, http.StatusUnauthorized, "Invalid authorization header")
+			if authErr != nil {
+				next.ServeHTTP(w, r)
 				return
 			}
-			token := authValues[1]
-			// validate the token
-			claims, err := authService.ValidateJwtToken(token)
 
-			if err != nil {
-				log.Println("JWT validation error ", err)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
 
-				if errors.Is(err, service.ErrInvalidToken) || errors.Is(err, service.ErrExpiredToken) {
-					utils.RespondWithError(w, http.StatusUnauthorized, err.Error())
-					return
+func authenticate(authService *service.AuthService, scope model.TokenScope) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, authErr := authorize(authService, scope, r)
+
+			if authErr != nil {
+				if authErr.challenge != "" {
+					w.Header().Set("WWW-Authenticate", authErr.challenge)
 				}
 
+				utils.RespondWithError(w, authErr.status, authErr.message)
+				return
+			}
+			// call next handler with the new request
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// why a request couldn't be authenticated, as it is answered
+type authError struct {
+	status    int
+	message   string
+	challenge string // WWW-Authenticate header, if any
+}
+
+// the request context with the user of the authorization header added
+func authorize(authService *service.AuthService, scope model.TokenScope, r *http.Request) (context.Context, *authError) {
+	// extract the access token
+	authHeader := r.Header.Get("Authorization")
+
+	if authHeader == "" {
+		return nil, &authError{status: http.StatusUnauthorized, message: "No authorization header found"}
+	}
+
+	authValues := strings.Split(authHeader, " ")
+
+	if len(authValues) != 2 || authValues[0] != "Bearer" {
+		return nil, &authError{status: http.StatusUnauthorized, message: "Invalid authorization header"}
+	}
+	token := authValues[1]
+
+	if service.IsPersonalToken(token) {
+		return authorizePersonalToken(authService, scope, token, r)
+	}
+	// validate the token
+	claims, err := authService.ValidateJwtToken(token)
+
+	if err != nil {
+		log.Println("JWT validation error ", err)
+
+		if errors.Is(err, service.ErrInvalidToken) || errors.Is(err, service.ErrExpiredToken) {
+			return nil, &authError{status: http.StatusUnauthorized, message: err.Error()}
+		}
+
+		return nil, &authError{status: http.StatusUnauthorized, message: "Unauthorized"}
+	}
+
+	userIdStr, ok := claims["sub"].(string)
+
+	if !ok {
+		return nil, &authError{status: http.StatusUnauthorized, message: "Invalid token claims"}
+	}
+
+	userId, err := uuid.Parse(userIdStr)
+
+	if err != nil {
+		return nil, &authError{status: http.StatusUnauthorized, message: "Invalid user ID in token"}
+	}
+	// add userId to request context
+	ctx := context.WithValue(r.Context(), userIdKey, userId)
+
+	// older tokens carry no session
+	if sid, ok := claims["sid"].(string); ok {
+		if sessionId, err := uuid.Parse(sid); err == nil {
+			ctx = context.WithValue(ctx, sessionIdKey, sessionId)
+		}
+	}
+
+	// a suspension takes effect right away, not when the access token expires.
+	// The role comes along from the same lookup, fresher than the claim
+	role, err := authService.GetActiveRole(userId)
+
+	if err != nil {
+		if errors.Is(err, service.ErrUserSuspended) {
+			return nil, &authError{status: http.StatusForbidden, message: err.Error()}
+		}
+
+		return nil, &authError{status: http.StatusUnauthorized, message: "Unauthorized"}
+	}
+
+	return context.WithValue(ctx, roleKey, role), nil
+}
+
+// personal tokens have no session and no role claim, only the user and what the scopes allow
+func authorizePersonalToken(authService *service.AuthService, scope model.TokenScope, token string,
+	r *http.Request) (context.Context, *authError) {
+	if scope == "" {
+		return nil, &authError{status: http.StatusForbidden, message: "Personal access tokens can not be used for this request"}
+	}
+
+	personalToken, err := authService.ValidatePersonalToken(token)
+
+	if err != nil {
+		if errors.Is(err, service.ErrUserSuspended) || errors.Is(err, service.ErrAccountDeletionScheduled) {
+			return nil, &authError{status: http.StatusForbidden, message: err.Error()}
+		}
+
+		if errors.Is(err, service.ErrInvalidToken) || errors.Is(err, service.ErrExpiredToken) {
+			return nil, &authError{status: http.StatusUnauthorized, message: err.Error()}
+		}
+
+		return nil, &authError{status: http.StatusUnauthorized, message: "Unauthorized"}
+	}
+
+	if !slices.Contains(personalToken.Scopes, scope) {
+		return nil, &authError{
+			status:    http.StatusForbidden,
+			message:   service.ErrInsufficientScope.Error(),
+			challenge: `Bearer error="insufficient_scope", scope="` + string(scope) + `"`,
+		}
+	}
+
+	return context.WithValue(r.Context(), userIdKey, personalToken.UserId), nil
+}
+
+// blocks users with an unverified email when the auth config asks for it, must run after AuthMiddleware
+func RequireVerifiedEmail(authService *service.AuthService) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !authService.RequiresVerifiedEmail() {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			userId, ok := GetUserID(r)
+
+			if !ok {
+				utils.RespondWithError(w, http.StatusUnauthorized, "Unauthorized Request")
+				return
+			}
+
+			verified, err := authService.IsEmailVerified(userId)
+
+			if err != nil {
 				utils.RespondWithError(w, http.StatusUnauthorized, "Unauthorized")
 				return
 			}
 
-			userIdStr, ok := claims["sub"].(string)
+			if !verified {
+				utils.RespondWithError(w, http.StatusForbidden, service.ErrEmailNotVerified.Error())
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// allows only the given roles, must run after AuthMiddleware. The role is read from the database
+// so a demoted user loses access right away instead of when the access token expires
+func RequireRole(authService *service.AuthService, roles ...model.Role) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userId, ok := GetUserID(r)
 
 			if !ok {
-				utils.RespondWithError(w, http.StatusUnauthorized, "Invalid token claims")
+				utils.RespondWithError(w, http.StatusUnauthorized, "Unauthorized Request")
 				return
 			}
 
-			userId, err := uuid.Parse(userIdStr)
+			role, err := authService.GetActiveRole(userId)
 
 			if err != nil {
-				utils.RespondWithError(w, http.StatusUnauthorized, "Invalid user ID in token")
+				if errors.Is(err, service.ErrUserSuspended) {
+					utils.RespondWithError(w, http.StatusForbidden, err.Error())
+					return
+				}
+
+				utils.RespondWithError(w, http.StatusUnauthorized, "Unauthorized")
 				return
 			}
-			// add userId to request context
-			ctx := context.WithValue(r.Context(), userIdKey, userId)
-			// call next handler with the new request
+
+			if !slices.Contains(roles, role) {
+				utils.RespondWithError(w, http.StatusForbidden, "You don't have permission to do this")
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), roleKey, role)
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
@@ -75,3 +253,20 @@ func GetUserID(r *http.Request) (uuid.UUID, bool) {
 	userID, ok := r.Context().Value(userIdKey).(uuid.UUID)
 	return userID, ok
 }
+
+// GetSessionID retrieves the session of the access token, uuid.Nil when it has none
+func GetSessionID(r *http.Request) uuid.UUID {
+	sessionId, _ := r.Context().Value(sessionIdKey).(uuid.UUID)
+	return sessionId
+}
+
+// GetUserRole retrieves the role of the user, tokens without one belong to regular users
+func GetUserRole(r *http.Request) model.Role {
+	role, ok := r.Context().Value(roleKey).(model.Role)
+
+	if !ok {
+		return model.ROLE_USER
+	}
+
+	return role
+}
@@ -0,0 +1,88 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// client address of requests that come through one of the trusted proxies. Only then the
+// forwarded headers replace RemoteAddr, anyone else could put whatever they like in them
+func RealIP(trustedProxies []netip.Prefix) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			peer, ok := parseAddr(r.RemoteAddr)
+
+			if !ok || !isTrusted(peer, trustedProxies) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if ip, ok := forwardedFor(r, trustedProxies); ok {
+				r.RemoteAddr = ip.String()
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// the proxies append the address they got the request from, so the last one that isn't a
+// trusted proxy is the client, whatever the client wrote in front of it is ignored
+func forwardedFor(r *http.Request, trustedProxies []netip.Prefix) (netip.Addr, bool) {
+	var hops []string
+
+	for _, header := range r.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(header, ",")...)
+	}
+
+	var client netip.Addr
+
+	for i := len(hops) - 1; i >= 0; i-- {
+		ip, ok := parseAddr(hops[i])
+
+		if !ok {
+			break
+		}
+
+		client = ip
+
+		if !isTrusted(ip, trustedProxies) {
+			return client, true
+		}
+	}
+
+	if client.IsValid() {
+		return client, true
+	}
+
+	return parseAddr(r.Header.Get("X-Real-IP"))
+}
+
+// an address with or without a port
+func parseAddr(value string) (netip.Addr, bool) {
+	value = strings.TrimSpace(value)
+
+	if host, _, err := net.SplitHostPort(value); err == nil {
+		value = host
+	}
+
+	ip, err := netip.ParseAddr(value)
+
+	if err != nil {
+		return netip.Addr{}, false
+	}
+
+	return ip.Unmap(), true
+}
+
+func isTrusted(ip netip.Addr, trustedProxies []netip.Prefix) bool {
+	for _, prefix := range trustedProxies {
+		if prefix.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
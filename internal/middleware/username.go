@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/harry713j/vibe_writer/internal/service"
+)
+
+// send requests for a username that was given up to the same path under the new username.
+// The old username is freed once its hold is over, so the redirect is only cached for a day
+func RedirectRenamedUser(accountService *service.AccountService) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			username := chi.URLParam(r, "username")
+			current, err := accountService.GetRenamedUsername(username)
+
+			if err != nil {
+				if !errors.Is(err, service.ErrUserNotExists) {
+					log.Println("Failed to look up renamed username: ", err)
+				}
+
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// only the segment after /users/, a blog slug can be the same word as the username
+			segments := strings.Split(r.URL.Path, "/")
+
+			for i := 0; i+1 < len(segments); i++ {
+				if segments[i] == "users" && segments[i+1] == username {
+					segments[i+1] = current
+					break
+				}
+			}
+
+			target := strings.Join(segments, "/")
+
+			if r.URL.RawQuery != "" {
+				target += "?" + r.URL.RawQuery
+			}
+
+			w.Header().Set("Cache-Control", "max-age=86400")
+			http.Redirect(w, r, target, http.StatusMovedPermanently)
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// enum type
+type ExportStatus string
+
+const (
+	EXPORT_PENDING    ExportStatus = "pending"
+	EXPORT_PROCESSING ExportStatus = "processing"
+	EXPORT_READY      ExportStatus = "ready"
+	EXPORT_DOWNLOADED ExportStatus = "downloaded"
+	EXPORT_FAILED     ExportStatus = "failed"
+)
+
+type DataExport struct {
+	Id        uuid.UUID    `json:"id"`
+	UserId    uuid.UUID    `json:"user_id"`
+	Status    ExportStatus `json:"status"`
+	FilePath  string       `json:"-"`
+	CreatedAt time.Time    `json:"created_at"`
+	ReadyAt   *time.Time   `json:"ready_at"`
+	ExpireAt  *time.Time   `json:"expire_at"` // a ready archive is deleted after this
+}
+
+/* records written to the export archive */
+
+type ExportBlog struct {
+	Id        int64      `json:"id"`
+	Title     string     `json:"title"`
+	Slug      string     `json:"slug"`
+	Content   string     `json:"-"` // goes to the markdown file
+	Status    BlogStatus `json:"status"`
+	Tags      []string   `json:"tags"`
+	PhotoUrls []string   `json:"photo_urls"`
+	PublishAt *time.Time `json:"publish_at"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt *time.Time `json:"updated_at"`
+}
+
+type ExportComment struct {
+	Id        int64     `json:"id"`
+	BlogSlug  string    `json:"blog_slug"`
+	BlogTitle string    `json:"blog_title"`
+	ParentId  *int64    `json:"parent_id"`
+	Content   string    `json:"content"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ExportLike struct {
+	BlogSlug  string    `json:"blog_slug,omitempty"`
+	CommentId *int64    `json:"comment_id,omitempty"`
+	LikeType  LikeType  `json:"like_type"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ExportBookmark struct {
+	BlogSlug       string    `json:"blog_slug"`
+	BlogTitle      string    `json:"blog_title"`
+	AuthorUsername string    `json:"author_username"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+type ExportFollow struct {
+	Username  string    `json:"username"`
+	CreatedAt time.Time `json:"created_at"`
+}
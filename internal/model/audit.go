@@ -0,0 +1,32 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// enum type
+type AuditAction string
+
+const (
+	AUDIT_BLOG_HIDE      AuditAction = "blog.hide"
+	AUDIT_BLOG_UNHIDE    AuditAction = "blog.unhide"
+	AUDIT_COMMENT_HIDE   AuditAction = "comment.hide"
+	AUDIT_COMMENT_UNHIDE AuditAction = "comment.unhide"
+	AUDIT_USER_SUSPEND   AuditAction = "user.suspend"
+	AUDIT_USER_UNSUSPEND AuditAction = "user.unsuspend"
+	AUDIT_USER_ROLE      AuditAction = "user.role"
+)
+
+type AuditLog struct {
+	Id            int64       `json:"id"`
+	ActorId       *uuid.UUID  `json:"actor_id"` // nil once the actor's account is deleted
+	ActorUsername string      `json:"actor_username"`
+	Action        AuditAction `json:"action"`
+	TargetType    string      `json:"target_type"`
+	TargetId      string      `json:"target_id"`
+	Reason        string      `json:"reason"`
+	Details       string      `json:"details"`
+	CreatedAt     time.Time   `json:"created_at"`
+}
@@ -6,19 +6,138 @@ import (
 	"github.com/google/uuid"
 )
 
+// enum type
+type Role string
+
+const (
+	ROLE_USER      Role = "user"
+	ROLE_MODERATOR Role = "moderator"
+	ROLE_ADMIN     Role = "admin"
+)
+
 type User struct {
-	Id        uuid.UUID `json:"id"`
-	Username  string    `json:"username"`
-	Email     string    `json:"email"`
-	Password  string    `json:"password"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	Id              uuid.UUID  `json:"id"`
+	Username        string     `json:"username"`
+	Email           string     `json:"email"`
+	Password        string     `json:"password"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`
+	Role            Role       `json:"role"`
+	SuspendedAt     *time.Time `json:"suspended_at"`
+	DeleteAt        *time.Time `json:"delete_at"` // set while the account waits to be deleted
 }
 
+// only the hash of a refresh token is stored, every refresh replaces the token with
+// a new one of the same family
 type RefreshToken struct {
-	Id        int64     `json:"id"`
-	UserId    uuid.UUID `json:"user_id"`
-	Token     uuid.UUID `json:"token"`
-	ExpireAt  time.Time `json:"expire_at"`
-	CreatedAt time.Time `json:"created_at"`
+	Id        int64      `json:"id"`
+	UserId    uuid.UUID  `json:"user_id"`
+	FamilyId  uuid.UUID  `json:"family_id"`
+	TokenHash string     `json:"-"`
+	ExpireAt  time.Time  `json:"expire_at"`
+	RotatedAt *time.Time `json:"rotated_at"`
+	RevokedAt *time.Time `json:"revoked_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+type EmailVerification struct {
+	Id        int64      `json:"id"`
+	UserId    uuid.UUID  `json:"user_id"`
+	TokenId   uuid.UUID  `json:"token_id"`
+	Email     string     `json:"email"`
+	ExpireAt  time.Time  `json:"expire_at"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+type PasswordReset struct {
+	Id        int64      `json:"id"`
+	UserId    uuid.UUID  `json:"user_id"`
+	ExpireAt  time.Time  `json:"expire_at"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// a new email waiting for its owner to confirm it, the address only changes then
+type EmailChange struct {
+	Id        int64      `json:"id"`
+	UserId    uuid.UUID  `json:"user_id"`
+	NewEmail  string     `json:"new_email"`
+	ExpireAt  time.Time  `json:"expire_at"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// a signed in device, its refresh tokens all belong to one family with the session id
+type Session struct {
+	Id         uuid.UUID  `json:"id"`
+	UserId     uuid.UUID  `json:"user_id"`
+	UserAgent  string     `json:"user_agent"`
+	IpAddress  string     `json:"ip_address"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt time.Time  `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"-"`
+	Current    bool       `json:"current"`
+}
+
+type UserTOTP struct {
+	UserId       uuid.UUID  `json:"user_id"`
+	Secret       string     `json:"-"`
+	ConfirmedAt  *time.Time `json:"confirmed_at"`
+	LastUsedStep *int64     `json:"-"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+type TOTPEnrollment struct {
+	Secret     string `json:"secret"`
+	OtpauthUri string `json:"otpauth_uri"`
+}
+
+// result of the password step, MfaToken is set instead of the tokens when the
+// account has two factor authentication
+type LoginResult struct {
+	AccessToken  string
+	RefreshToken string
+	MfaToken     string
+}
+
+// enum type
+type TokenScope string
+
+const (
+	SCOPE_BLOGS_READ     TokenScope = "blogs:read"
+	SCOPE_BLOGS_WRITE    TokenScope = "blogs:write"
+	SCOPE_COMMENTS_WRITE TokenScope = "comments:write"
+	SCOPE_UPLOADS_WRITE  TokenScope = "uploads:write"
+)
+
+// long lived token for scripts and integrations, it only reaches the routes its scopes allow
+type PersonalAccessToken struct {
+	Id          int64        `json:"id"`
+	UserId      uuid.UUID    `json:"user_id"`
+	Name        string       `json:"name"`
+	TokenHash   string       `json:"-"`
+	TokenPrefix string       `json:"token_prefix"` // lets the user tell their tokens apart
+	Scopes      []TokenScope `json:"scopes"`
+	ExpireAt    *time.Time   `json:"expire_at"` // nil never expires
+	LastUsedAt  *time.Time   `json:"last_used_at"`
+	CreatedAt   time.Time    `json:"created_at"`
+}
+
+// the plain token is only returned once, when it is created
+type CreatedPersonalAccessToken struct {
+	PersonalAccessToken
+	Token string `json:"token"`
+}
+
+// an account at an OpenID Connect provider linked to the user
+type UserIdentity struct {
+	Id          int64     `json:"id"`
+	UserId      uuid.UUID `json:"user_id"`
+	Provider    string    `json:"provider"`
+	Subject     string    `json:"-"`
+	Email       string    `json:"email"`
+	CreatedAt   time.Time `json:"created_at"`
+	LastLoginAt time.Time `json:"last_login_at"`
 }
@@ -6,15 +6,27 @@ import (
 	"github.com/google/uuid"
 )
 
+// enum type
+type BlogStatus string
+
+const (
+	DRAFT     BlogStatus = "draft"
+	SCHEDULED BlogStatus = "scheduled"
+	PUBLISHED BlogStatus = "published"
+	UNLISTED  BlogStatus = "unlisted"
+	ARCHIVED  BlogStatus = "archived"
+)
+
 type Blog struct {
-	Id         int64      `json:"id"`
-	UserId     uuid.UUID  `json:"user_id"`
-	Title      string     `json:"title"`
-	Slug       string     `json:"slug"`
-	Content    string     `json:"content"`
-	Visibility bool       `json:"visibility"`
-	CreatedAt  time.Time  `json:"created_at"`
-	UpdatedAt  *time.Time `json:"updated_at"`
+	Id        int64      `json:"id"`
+	UserId    uuid.UUID  `json:"user_id"`
+	Title     string     `json:"title"`
+	Slug      string     `json:"slug"`
+	Content   string     `json:"content"`
+	Status    BlogStatus `json:"status"`
+	PublishAt *time.Time `json:"publish_at"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt *time.Time `json:"updated_at"`
 }
 
 type BlogPhoto struct {
@@ -26,25 +38,45 @@ type BlogPhoto struct {
 
 type BlogSummary struct {
 	Blog
-	Thumbnail    string `json:"blog_thumbnail"`
-	LikesCount   int    `json:"likes_count"`
-	DislikeCount int    `json:"dislikes_count"`
-	CommentCount int    `json:"comments_count"`
+	Thumbnail      string  `json:"blog_thumbnail"`
+	LikesCount     int     `json:"likes_count"`
+	DislikeCount   int     `json:"dislikes_count"`
+	CommentCount   int     `json:"comments_count"`
+	Tags           []Tag   `json:"tags"`
+	AuthorUsername string  `json:"author_username,omitempty"`
+	AuthorName     string  `json:"author_name,omitempty"`
+	AuthorAvatar   string  `json:"author_avatar,omitempty"`
+	TrendingScore  float64 `json:"trending_score,omitempty"`  // trending list only
+	Rank           float64 `json:"rank,omitempty"`            // search results only
+	TitleHighlight string  `json:"title_highlight,omitempty"` // search results only
+	Highlight      string  `json:"highlight,omitempty"`       // search results only
+}
+
+// what the author allows in the discussion of a blog
+type CommentSettings struct {
+	CommentsLocked        bool   `json:"comments_locked"`         // nobody can comment or reply
+	CommentsFollowersOnly bool   `json:"comments_followers_only"` // only followers of the author can comment
+	PinnedCommentId       *int64 `json:"pinned_comment_id"`
 }
 
 type BlogWithStat struct {
 	Blog
-	PhotoUrls    []string `json:"photo_urls"`
-	LikeCount    int      `json:"likes_count"`
-	DislikeCount int      `json:"dislikes_count"`
+	CommentSettings
+	ContentHtml  string     `json:"content_html"`        // sanitized html rendered from the markdown content
+	HiddenAt     *time.Time `json:"hidden_at,omitempty"` // set when a moderator hid the blog
+	PhotoUrls    []string   `json:"photo_urls"`
+	LikeCount    int        `json:"likes_count"`
+	DislikeCount int        `json:"dislikes_count"`
 }
 
 type BlogResponse struct {
 	BlogWithStat
-	Comments     []CommentWithStat `json:"comments"`
-	AuthorName   string            `json:"author_name"`
-	AuthorBio    string            `json:"author_bio"`
-	AuthorAvatar string            `json:"author_avatar"`
+	Tags         []Tag                        `json:"tags"`
+	Mentions     []MentionSpan                `json:"mentions"`           // in the content
+	Comments     *CursorResponse[CommentNode] `json:"comments,omitempty"` // first page, only when reading the blog
+	AuthorName   string                       `json:"author_name"`
+	AuthorBio    string                       `json:"author_bio"`
+	AuthorAvatar string                       `json:"author_avatar"`
 }
 
 type PageMeta struct {
@@ -58,3 +90,8 @@ type PaginatedResponse[T any] struct {
 	Data []T      `json:"data"`
 	Meta PageMeta `json:"meta"`
 }
+
+type CursorResponse[T any] struct {
+	Data       []T    `json:"data"`
+	NextCursor string `json:"next_cursor"` // empty on the last page
+}
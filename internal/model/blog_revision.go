@@ -0,0 +1,52 @@
+package model
+
+import (
+	"time"
+)
+
+type BlogRevision struct {
+	Id               int64     `json:"id"`
+	BlogId           int64     `json:"blog_id"`
+	Revision         int       `json:"revision"`
+	Title            string    `json:"title"`
+	Content          string    `json:"content"`
+	PhotoUrls        []string  `json:"photo_urls"`
+	AddedPhotoUrls   []string  `json:"added_photo_urls"`
+	RemovedPhotoUrls []string  `json:"removed_photo_urls"`
+	RestoredFrom     *int      `json:"restored_from"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+type BlogRevisionSummary struct {
+	Id           int64     `json:"id"`
+	Revision     int       `json:"revision"`
+	Title        string    `json:"title"`
+	RestoredFrom *int      `json:"restored_from"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// enum type
+type DiffOp string
+
+const (
+	UNCHANGED DiffOp = "unchanged"
+	ADDED     DiffOp = "added"
+	REMOVED   DiffOp = "removed"
+)
+
+type DiffLine struct {
+	Op      DiffOp `json:"op"`
+	Content string `json:"content"`
+	OldLine int    `json:"old_line,omitempty"`
+	NewLine int    `json:"new_line,omitempty"`
+}
+
+type BlogRevisionDiff struct {
+	From             int        `json:"from"`
+	To               int        `json:"to"`
+	FromTitle        string     `json:"from_title"`
+	ToTitle          string     `json:"to_title"`
+	Lines            []DiffLine `json:"lines"`
+	AddedPhotoUrls   []string   `json:"added_photo_urls"`
+	RemovedPhotoUrls []string   `json:"removed_photo_urls"`
+}
@@ -17,12 +17,50 @@ type Comment struct {
 }
 
 type CommentWithStat struct {
-	Id           int64      `json:"id"`
-	UserId       uuid.UUID  `json:"user_id"`
-	ParentId     *int64     `json:"parent_id"`
-	Content      string     `json:"content"`
-	CreatedAt    *time.Time `json:"created_at"`
-	UpdatedAt    *time.Time `json:"updated_at"`
-	LikeCount    int        `json:"likes_count"`
-	DislikeCount int        `json:"dislikes_count"`
+	Id           int64         `json:"id"`
+	UserId       uuid.UUID     `json:"user_id,omitzero"` // left out of a hidden comment's placeholder
+	ParentId     *int64        `json:"parent_id"`
+	Content      string        `json:"content"`
+	CreatedAt    *time.Time    `json:"created_at"`
+	UpdatedAt    *time.Time    `json:"updated_at"`
+	LikeCount    int           `json:"likes_count"`
+	DislikeCount int           `json:"dislikes_count"`
+	Edited       bool          `json:"edited"`
+	EditCount    int           `json:"edit_count"`
+	EditedAt     *time.Time    `json:"edited_at"`
+	Mentions     []MentionSpan `json:"mentions"`
+}
+
+// a version of a comment that an edit replaced
+type CommentRevision struct {
+	Id         int64     `json:"id"`
+	CommentId  int64     `json:"comment_id"`
+	Revision   int       `json:"revision"`
+	Content    string    `json:"content"`
+	CreatedAt  time.Time `json:"created_at"` // when this version was written
+	ReplacedAt time.Time `json:"replaced_at"`
+}
+
+// a comment with its first replies nested under it
+type CommentNode struct {
+	CommentWithStat
+	AuthorUsername string        `json:"author_username"`
+	AuthorName     string        `json:"author_name"`
+	AuthorAvatar   string        `json:"author_avatar"`
+	ReplyCount     int           `json:"reply_count"` // direct replies, shown or not
+	Replies        []CommentNode `json:"replies"`
+	RepliesCursor  string        `json:"replies_cursor,omitempty"` // loads the replies not shown, empty when all are
+	Pinned         bool          `json:"pinned,omitempty"`
+	Hidden         bool          `json:"hidden,omitempty"` // only its writer still sees the content
+}
+
+// a comment with who can moderate it, its writer and the author of its blog
+type CommentOwnership struct {
+	CommentId      int64      `json:"comment_id"`
+	BlogId         int64      `json:"blog_id"`
+	ParentId       *int64     `json:"parent_id"`
+	UserId         uuid.UUID  `json:"user_id"`
+	BlogAuthorId   uuid.UUID  `json:"blog_author_id"`
+	HiddenAt       *time.Time `json:"hidden_at"`
+	HiddenByAuthor bool       `json:"hidden_by_author"`
 }
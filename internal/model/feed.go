@@ -0,0 +1,29 @@
+package model
+
+import (
+	"time"
+)
+
+// format independent content of an RSS or Atom feed
+type Feed struct {
+	Id          string
+	Title       string
+	Link        string
+	Description string
+	AuthorName  string
+	AuthorLink  string
+	Image       string
+	Updated     time.Time
+	Items       []FeedItem
+}
+
+type FeedItem struct {
+	Id         string
+	Title      string
+	Link       string
+	Summary    string
+	AuthorName string
+	Categories []string
+	Published  time.Time
+	Updated    time.Time
+}
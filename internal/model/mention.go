@@ -0,0 +1,35 @@
+package model
+
+import (
+	"github.com/google/uuid"
+)
+
+// a user mentioned in a blog or a comment
+type Mention struct {
+	UserId   uuid.UUID
+	Username string // current username
+	Written  string // username as it was written, it may be an old one
+}
+
+// where a mention sits in the content, for clients to link it. Offsets count unicode code points
+type MentionSpan struct {
+	Start    int       `json:"start"`
+	End      int       `json:"end"`
+	UserId   uuid.UUID `json:"user_id"`
+	Username string    `json:"username"`
+}
+
+// a mention whose user wasn't told about it yet
+type PendingMention struct {
+	Id             int64
+	UserId         uuid.UUID
+	Email          string
+	Username       string
+	AuthorId       uuid.UUID
+	AuthorUsername string
+	CommentId      *int64 // nil for a mention in the blog itself
+	BlogId         int64
+	BlogTitle      string
+	BlogSlug       string
+	BlogUsername   string // the author of the blog
+}
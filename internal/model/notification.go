@@ -0,0 +1,56 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// enum type
+type NotificationType string
+
+const (
+	NOTIFY_BLOG_LIKE NotificationType = "blog_like"
+	NOTIFY_COMMENT   NotificationType = "comment" // on the user's blog
+	NOTIFY_REPLY     NotificationType = "reply"   // to the user's comment
+	NOTIFY_BOOKMARK  NotificationType = "bookmark"
+	NOTIFY_FOLLOW    NotificationType = "follow"
+	NOTIFY_MENTION   NotificationType = "mention"
+)
+
+var NotificationTypes = []NotificationType{
+	NOTIFY_BLOG_LIKE, NOTIFY_COMMENT, NOTIFY_REPLY, NOTIFY_BOOKMARK, NOTIFY_FOLLOW, NOTIFY_MENTION,
+}
+
+type NotificationActor struct {
+	Id       uuid.UUID `json:"id"`
+	Username string    `json:"username"`
+	Avatar   string    `json:"avatar"`
+}
+
+type NotificationBlog struct {
+	Id             int64  `json:"id"`
+	Title          string `json:"title"`
+	Slug           string `json:"slug"`
+	AuthorUsername string `json:"author_username"`
+}
+
+// one or more users doing the same thing, "12 people liked your post"
+type Notification struct {
+	Id         int64               `json:"id"`
+	Type       NotificationType    `json:"type"`
+	Message    string              `json:"message"`
+	ActorCount int                 `json:"actor_count"`
+	Actors     []NotificationActor `json:"actors"` // the latest few
+	Blog       *NotificationBlog   `json:"blog,omitempty"`
+	CommentId  *int64              `json:"comment_id,omitempty"` // the replied to or mentioning comment
+	Read       bool                `json:"read"`
+	ReadAt     *time.Time          `json:"read_at"`
+	CreatedAt  time.Time           `json:"created_at"`
+	UpdatedAt  time.Time           `json:"updated_at"` // latest activity
+}
+
+type NotificationPage struct {
+	CursorResponse[Notification]
+	UnreadCount int `json:"unread_count"`
+}
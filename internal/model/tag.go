@@ -0,0 +1,12 @@
+package model
+
+type Tag struct {
+	Id   int64  `json:"id"`
+	Name string `json:"name"`
+	Slug string `json:"slug"`
+}
+
+type TagWithCount struct {
+	Tag
+	PostCount int `json:"post_count"`
+}
@@ -0,0 +1,353 @@
+package repo
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/harry713j/vibe_writer/internal/model"
+)
+
+// data exports and what goes into them, plus the clean up of deleted accounts
+type AccountRepository struct {
+	DB *sql.DB
+}
+
+func NewAccountRepository(db *sql.DB) *AccountRepository {
+	return &AccountRepository{DB: db}
+}
+
+const exportColumns = "id, user_id, status, file_path, created_at, ready_at, expire_at"
+
+func scanExport(row rowScanner) (*model.DataExport, error) {
+	var export model.DataExport
+
+	err := row.Scan(&export.Id, &export.UserId, &export.Status, &export.FilePath, &export.CreatedAt,
+		&export.ReadyAt, &export.ExpireAt)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &export, nil
+}
+
+func (r *AccountRepository) CreateExport(userId uuid.UUID) (*model.DataExport, error) {
+	query := "INSERT INTO data_exports(id, user_id) VALUES($1, $2) RETURNING " + exportColumns
+
+	return scanExport(r.DB.QueryRow(query, uuid.New(), userId))
+}
+
+func (r *AccountRepository) GetExport(userId, exportId uuid.UUID) (*model.DataExport, error) {
+	query := "SELECT " + exportColumns + " FROM data_exports WHERE user_id=$1 AND id=$2"
+
+	return scanExport(r.DB.QueryRow(query, userId, exportId))
+}
+
+// an export of the user that is still being built or waiting to be downloaded
+func (r *AccountRepository) GetOpenExport(userId uuid.UUID) (*model.DataExport, error) {
+	query := "SELECT " + exportColumns + ` FROM data_exports
+		WHERE user_id=$1 AND status IN ('pending', 'processing', 'ready')
+		ORDER BY created_at DESC LIMIT 1`
+
+	return scanExport(r.DB.QueryRow(query, userId))
+}
+
+// take the oldest pending export, SKIP LOCKED keeps two servers from building the same one
+func (r *AccountRepository) ClaimPendingExport() (*model.DataExport, error) {
+	query := `
+		UPDATE data_exports SET status = 'processing'
+		WHERE id = (
+			SELECT id FROM data_exports
+			WHERE status = 'pending'
+			ORDER BY created_at
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + exportColumns
+
+	return scanExport(r.DB.QueryRow(query))
+}
+
+func (r *AccountRepository) FinishExport(exportId uuid.UUID, filePath string, readyAt, expireAt time.Time) error {
+	_, err := r.DB.Exec("UPDATE data_exports SET status='ready', file_path=$2, ready_at=$3, expire_at=$4 WHERE id=$1",
+		exportId, filePath, readyAt, expireAt)
+
+	return err
+}
+
+func (r *AccountRepository) FailExport(exportId uuid.UUID) error {
+	_, err := r.DB.Exec("UPDATE data_exports SET status='failed' WHERE id=$1", exportId)
+
+	return err
+}
+
+// mark a ready export as downloaded, only one request can win it
+func (r *AccountRepository) TakeExport(userId, exportId uuid.UUID, now time.Time) (*model.DataExport, error) {
+	query := `UPDATE data_exports SET status='downloaded'
+		WHERE user_id=$1 AND id=$2 AND status='ready' AND expire_at > $3
+		RETURNING ` + exportColumns
+
+	return scanExport(r.DB.QueryRow(query, userId, exportId, now))
+}
+
+// ready exports nobody downloaded in time, and exports stuck in processing after a crash
+func (r *AccountRepository) GetStaleExports(now time.Time, stuckBefore time.Time) ([]model.DataExport, error) {
+	query := "SELECT " + exportColumns + ` FROM data_exports
+		WHERE (status = 'ready' AND expire_at <= $1) OR (status = 'processing' AND created_at < $2)`
+
+	rows, err := r.DB.Query(query, now, stuckBefore)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var exports []model.DataExport
+
+	for rows.Next() {
+		export, err := scanExport(rows)
+
+		if err != nil {
+			return nil, err
+		}
+
+		exports = append(exports, *export)
+	}
+
+	return exports, rows.Err()
+}
+
+func (r *AccountRepository) GetExportBlogs(userId uuid.UUID) ([]model.ExportBlog, error) {
+	query := `
+		SELECT
+			b.id,
+			b.title,
+			b.slug,
+			b.content,
+			b.status,
+			COALESCE((
+				SELECT array_agg(t.name ORDER BY t.name)
+				FROM blog_tags bt
+				JOIN tags t ON t.id = bt.tag_id
+				WHERE bt.blog_id = b.id
+			), '{}'),
+			COALESCE((
+				SELECT array_agg(bp.photo_url ORDER BY bp.id)
+				FROM blog_photos bp
+				WHERE bp.blog_id = b.id
+			), '{}'),
+			b.publish_at,
+			b.created_at,
+			b.updated_at
+		FROM blogs b
+		WHERE b.user_id = $1
+		ORDER BY b.created_at
+	`
+
+	rows, err := r.DB.Query(query, userId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var blogs []model.ExportBlog
+
+	for rows.Next() {
+		var blog model.ExportBlog
+
+		err := rows.Scan(&blog.Id, &blog.Title, &blog.Slug, &blog.Content, &blog.Status, textArray(&blog.Tags),
+			textArray(&blog.PhotoUrls), &blog.PublishAt, &blog.CreatedAt, &blog.UpdatedAt)
+
+		if err != nil {
+			return nil, err
+		}
+
+		blogs = append(blogs, blog)
+	}
+
+	return blogs, rows.Err()
+}
+
+func (r *AccountRepository) GetExportComments(userId uuid.UUID) ([]model.ExportComment, error) {
+	query := `
+		SELECT c.id, b.slug, b.title, c.parent_id, c.content, c.created_at
+		FROM comments c
+		JOIN blogs b ON b.id = c.blog_id
+		WHERE c.user_id = $1
+		ORDER BY c.created_at
+	`
+
+	rows, err := r.DB.Query(query, userId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var comments []model.ExportComment
+
+	for rows.Next() {
+		var comment model.ExportComment
+
+		err := rows.Scan(&comment.Id, &comment.BlogSlug, &comment.BlogTitle, &comment.ParentId, &comment.Content,
+			&comment.CreatedAt)
+
+		if err != nil {
+			return nil, err
+		}
+
+		comments = append(comments, comment)
+	}
+
+	return comments, rows.Err()
+}
+
+func (r *AccountRepository) GetExportLikes(userId uuid.UUID) ([]model.ExportLike, error) {
+	query := `
+		SELECT COALESCE(b.slug, ''), l.comment_id, l.like_type, COALESCE(l.updated_at, l.created_at)
+		FROM likes l
+		LEFT JOIN blogs b ON b.id = l.blog_id
+		WHERE l.user_id = $1
+		ORDER BY l.created_at
+	`
+
+	rows, err := r.DB.Query(query, userId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var likes []model.ExportLike
+
+	for rows.Next() {
+		var like model.ExportLike
+
+		if err := rows.Scan(&like.BlogSlug, &like.CommentId, &like.LikeType, &like.CreatedAt); err != nil {
+			return nil, err
+		}
+
+		likes = append(likes, like)
+	}
+
+	return likes, rows.Err()
+}
+
+// bookmarks of the user, blogs of others that became drafts or were hidden since are left out
+func (r *AccountRepository) GetExportBookmarks(userId uuid.UUID) ([]model.ExportBookmark, error) {
+	query := `
+		SELECT b.slug, b.title, u.username, bm.created_at
+		FROM bookmarks bm
+		JOIN blogs b ON b.id = bm.blog_id
+		JOIN users u ON u.id = b.user_id
+		WHERE bm.user_id = $1 AND (b.user_id = $1 OR ` + reachableBlogCondition + `)
+		ORDER BY bm.created_at
+	`
+
+	rows, err := r.DB.Query(query, userId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var bookmarks []model.ExportBookmark
+
+	for rows.Next() {
+		var bookmark model.ExportBookmark
+
+		err := rows.Scan(&bookmark.BlogSlug, &bookmark.BlogTitle, &bookmark.AuthorUsername, &bookmark.CreatedAt)
+
+		if err != nil {
+			return nil, err
+		}
+
+		bookmarks = append(bookmarks, bookmark)
+	}
+
+	return bookmarks, rows.Err()
+}
+
+// who the user follows, or who follows the user when followers is true
+func (r *AccountRepository) GetExportFollows(userId uuid.UUID, followers bool) ([]model.ExportFollow, error) {
+	query := `
+		SELECT u.username, f.created_at
+		FROM follows f
+		JOIN users u ON u.id = f.following_id
+		WHERE f.follower_id = $1
+		ORDER BY f.created_at
+	`
+
+	if followers {
+		query = `
+			SELECT u.username, f.created_at
+			FROM follows f
+			JOIN users u ON u.id = f.follower_id
+			WHERE f.following_id = $1
+			ORDER BY f.created_at
+		`
+	}
+
+	rows, err := r.DB.Query(query, userId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var follows []model.ExportFollow
+
+	for rows.Next() {
+		var follow model.ExportFollow
+
+		if err := rows.Scan(&follow.Username, &follow.CreatedAt); err != nil {
+			return nil, err
+		}
+
+		follows = append(follows, follow)
+	}
+
+	return follows, rows.Err()
+}
+
+// every cloud image the user uploaded: the avatar and the photos of any blog revision
+func (r *AccountRepository) GetImageUrls(userId uuid.UUID) ([]string, error) {
+	query := `
+		SELECT up.avatar_url FROM user_profiles up WHERE up.user_id = $1 AND COALESCE(up.avatar_url, '') <> ''
+		UNION
+		SELECT bp.photo_url FROM blog_photos bp JOIN blogs b ON b.id = bp.blog_id WHERE b.user_id = $1
+		UNION
+		SELECT url FROM blog_revisions br JOIN blogs b ON b.id = br.blog_id, unnest(br.photo_urls) AS url
+		WHERE b.user_id = $1
+	`
+
+	rows, err := r.DB.Query(query, userId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var urls []string
+
+	for rows.Next() {
+		var url string
+
+		if err := rows.Scan(&url); err != nil {
+			return nil, err
+		}
+
+		urls = append(urls, url)
+	}
+
+	return urls, rows.Err()
+}
@@ -2,10 +2,13 @@ package repo
 
 import (
 	"database/sql"
+	"html"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/harry713j/vibe_writer/internal/model"
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 type BlogRepository struct {
@@ -18,6 +21,17 @@ func NewBlogRepository(db *sql.DB) *BlogRepository {
 	}
 }
 
+// blogs that show up in public listings
+const publicBlogCondition = "(b.status = 'published' AND b.hidden_at IS NULL)"
+
+// blogs that can be opened by anyone who has the link
+const reachableBlogCondition = "(b.status IN ('published', 'unlisted') AND b.hidden_at IS NULL)"
+
+// pgx stdlib hands arrays over as text, let pgtype decode them
+func textArray(dst *[]string) sql.Scanner {
+	return pgtype.NewMap().SQLScanner(dst)
+}
+
 type authorData struct {
 	FullName string `json:"author_name"`
 	Bio      string `json:"author_bio"`
@@ -25,21 +39,24 @@ type authorData struct {
 }
 
 // create blog
-func (b *BlogRepository) CreateBlog(userId uuid.UUID, title, slug, content string) (int64, error) {
+func (b *BlogRepository) CreateBlog(userId uuid.UUID, title, slug, content, contentHtml string, status model.BlogStatus,
+	publishAt *time.Time) (int64, error) {
 	blog := &model.Blog{
 		UserId:    userId,
 		Title:     title,
 		Slug:      slug,
 		Content:   content,
+		Status:    status,
+		PublishAt: publishAt,
 		CreatedAt: time.Now(),
 	}
 
 	var blogId int64
 
-	err := b.DB.QueryRow(`INSERT INTO blogs(user_id, title, slug, content, created_at) 
-		VALUES($1, $2, $3, $4, $5)
+	err := b.DB.QueryRow(`INSERT INTO blogs(user_id, title, slug, content, content_html, status, publish_at, created_at) 
+		VALUES($1, $2, $3, $4, $5, $6, $7, $8)
 		RETURNING id`,
-		blog.UserId, blog.Title, blog.Slug, blog.Content, blog.CreatedAt).Scan(&blogId)
+		blog.UserId, blog.Title, blog.Slug, blog.Content, contentHtml, blog.Status, blog.PublishAt, blog.CreatedAt).Scan(&blogId)
 
 	if err != nil {
 		return 0, err
@@ -62,20 +79,123 @@ func (b *BlogRepository) CreateBlogImage(blogId int64, photoUrl string) (int64,
 	return blogPhotoId, nil
 }
 
-// update by slug -> slug is immutable
-func (b *BlogRepository) UpdateBlog(userId uuid.UUID, slug string, title string, content string) (int64, error) {
+// update by slug -> slug is immutable. The content, photos and the new revision are written in one
+// transaction, the UPDATE locks the blog row so concurrent edits get their revision numbers in turn
+func (b *BlogRepository) UpdateBlog(userId uuid.UUID, slug, title, content, contentHtml string, photoUrls []string,
+	restoredFrom *int) (int64, error) {
+	tx, err := b.DB.Begin()
+
+	if err != nil {
+		return 0, err
+	}
+
+	defer tx.Rollback()
+
 	var blogId int64
 
-	err := b.DB.QueryRow(`UPDATE blogs SET title=$1, content=$2 WHERE slug=$3 AND user_id=$4 RETURNING id`,
-		title, content, slug, userId).Scan(&blogId)
+	err = tx.QueryRow(`UPDATE blogs SET title=$1, content=$2, content_html=$3, updated_at=CURRENT_TIMESTAMP
+		WHERE slug=$4 AND user_id=$5 RETURNING id`,
+		title, content, contentHtml, slug, userId).Scan(&blogId)
 
 	if err != nil {
 		return 0, err
 	}
 
+	var existingUrls []string
+
+	err = tx.QueryRow("SELECT COALESCE(array_agg(photo_url), '{}') FROM blog_photos WHERE blog_id=$1", blogId).
+		Scan(textArray(&existingUrls))
+
+	if err != nil {
+		return 0, err
+	}
+
+	removedUrls := missingUrls(existingUrls, photoUrls) // old photo urls to be remove
+	addedUrls := missingUrls(photoUrls, existingUrls)   // new photo urls to be add
+
+	if _, err := tx.Exec("DELETE FROM blog_photos WHERE blog_id=$1 AND photo_url = ANY($2)", blogId, removedUrls); err != nil {
+		return 0, err
+	}
+
+	for _, url := range addedUrls {
+		if _, err := tx.Exec("INSERT INTO blog_photos(blog_id, photo_url) VALUES($1, $2)", blogId, url); err != nil {
+			return 0, err
+		}
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO blog_revisions(blog_id, revision, title, content, photo_urls, added_photo_urls, removed_photo_urls, restored_from)
+		VALUES(
+			$1,
+			(SELECT COALESCE(MAX(revision), 0) + 1 FROM blog_revisions WHERE blog_id = $1),
+			$2, $3, $4, $5, $6, $7
+		)
+	`, blogId, title, content, nonNilUrls(photoUrls), nonNilUrls(addedUrls), nonNilUrls(removedUrls), restoredFrom)
+
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
 	return blogId, nil
 }
 
+// urls in a that are not in b
+func missingUrls(a, b []string) []string {
+	seen := make(map[string]bool, len(b))
+
+	for _, url := range b {
+		seen[url] = true
+	}
+
+	var missing []string
+
+	for _, url := range a {
+		if !seen[url] {
+			missing = append(missing, url)
+		}
+	}
+
+	return nonNilUrls(missing)
+}
+
+// blogs written before markdown rendering, content_html is still NULL for them
+func (b *BlogRepository) GetBlogsWithoutHtml(limit int) ([]model.Blog, error) {
+	rows, err := b.DB.Query("SELECT id, content FROM blogs WHERE content_html IS NULL ORDER BY id LIMIT $1", limit)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var blogs []model.Blog
+
+	for rows.Next() {
+		var blog model.Blog
+
+		if err := rows.Scan(&blog.Id, &blog.Content); err != nil {
+			return nil, err
+		}
+
+		blogs = append(blogs, blog)
+	}
+
+	return blogs, nil
+}
+
+// store the rendered html without touching updated_at
+func (b *BlogRepository) UpdateBlogHtml(blogId int64, contentHtml string) error {
+	if _, err := b.DB.Exec("UPDATE blogs SET content_html=$1 WHERE id=$2", contentHtml, blogId); err != nil {
+		return err
+	}
+
+	return nil
+}
+
 // update blog photos
 func (b *BlogRepository) UpdateBlogPhoto(blogId, blogPhotoId int64, photoUrl string) error {
 	if _, err := b.DB.Exec("UPDATE blog_photos SET photo_url=$1 WHERE blog_id=$2 AND id=$3",
@@ -86,14 +206,32 @@ func (b *BlogRepository) UpdateBlogPhoto(blogId, blogPhotoId int64, photoUrl str
 	return nil
 }
 
-func (b *BlogRepository) UpdateBlogVisibility(userId uuid.UUID, slug string) error {
-	if _, err := b.DB.Exec("UPDATE blogs SET visibility = NOT visibility WHERE user_id = $1 AND slug = $2", userId, slug); err != nil {
+func (b *BlogRepository) UpdateBlogStatus(userId uuid.UUID, slug string, status model.BlogStatus, publishAt *time.Time) error {
+	query := `UPDATE blogs SET status = $1, publish_at = $2, updated_at = CURRENT_TIMESTAMP WHERE user_id = $3 AND slug = $4`
+
+	if _, err := b.DB.Exec(query, status, publishAt, userId, slug); err != nil {
 		return err
 	}
 
 	return nil
 }
 
+// publish every scheduled blog whose publish time has passed
+func (b *BlogRepository) PublishScheduledBlogs(now time.Time) (int64, error) {
+	query := `
+		UPDATE blogs SET status = 'published', updated_at = CURRENT_TIMESTAMP
+		WHERE status = 'scheduled' AND publish_at <= $1
+	`
+
+	result, err := b.DB.Exec(query, now)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
 // delete by slug
 func (b *BlogRepository) DeleteBlog(userId uuid.UUID, slug string) error {
 	if _, err := b.DB.Exec("DELETE FROM blogs WHERE slug=$1 AND user_id=$2", slug, userId); err != nil {
@@ -102,16 +240,6 @@ func (b *BlogRepository) DeleteBlog(userId uuid.UUID, slug string) error {
 	return nil
 }
 
-// delete blog photos of a blog
-func (b *BlogRepository) DeleteBlogPhotosByURLs(blogId int64, photoURLs []string) error {
-	_, err := b.DB.Exec(
-		"DELETE FROM blog_photos WHERE blog_id=$1 AND photo_url = ANY($2)",
-		blogId,
-		photoURLs,
-	)
-	return err
-}
-
 // delete blog photo
 func (b *BlogRepository) DeleteBlogPhoto(blogId int64, photoUrl string) error {
 	if _, err := b.DB.Exec("DELETE FROM blog_photos WHERE photo_url=$1 AND blog_id=$2", photoUrl, blogId); err != nil {
@@ -137,10 +265,11 @@ func (b *BlogRepository) GetAllPublicBlog(userId uuid.UUID, page, limit int) (*m
 		SELECT 
 			b.id,
 			b.title,
+			b.user_id,
 			b.slug,
 			b.content,
-			b.visibility,
-			b.user_id,
+			b.status,
+			b.publish_at,
 			b.created_at,
 			b.updated_at,
 			COALESCE((
@@ -155,10 +284,10 @@ func (b *BlogRepository) GetAllPublicBlog(userId uuid.UUID, page, limit int) (*m
 			COUNT(DISTINCT c.id) AS comments_count
 		FROM blogs b
 		LEFT JOIN likes l ON l.blog_id = b.id
-		LEFT JOIN comments c ON c.blog_id = b.id
-		WHERE b.user_id = $1 AND b.visibility = true
-		GROUP BY b.id, b.title, b.slug, b.content, b.visibility, b.user_id, b.created_at, b.updated_at
-		ORDER BY b.created_at DESC
+		LEFT JOIN comments c ON c.blog_id = b.id AND c.hidden_at IS NULL
+		WHERE b.user_id = $1 AND ` + publicBlogCondition + `
+		GROUP BY b.id
+		ORDER BY b.publish_at DESC, b.id DESC
 		LIMIT $2 OFFSET $3
 		`
 
@@ -179,7 +308,8 @@ func (b *BlogRepository) GetAllPublicBlog(userId uuid.UUID, page, limit int) (*m
 			&blog.UserId,
 			&blog.Slug,
 			&blog.Content,
-			&blog.Visibility,
+			&blog.Status,
+			&blog.PublishAt,
 			&blog.CreatedAt,
 			&blog.UpdatedAt,
 			&blog.Thumbnail,
@@ -195,9 +325,365 @@ func (b *BlogRepository) GetAllPublicBlog(userId uuid.UUID, page, limit int) (*m
 		blogs = append(blogs, blog)
 	}
 
+	if err := attachTags(b.DB, blogs); err != nil {
+		return nil, err
+	}
+
 	// total blogs
 	var total int
-	err = b.DB.QueryRow("SELECT COUNT(*) FROM blogs WHERE blogs.user_id = $1", userId).Scan(&total)
+	err = b.DB.QueryRow("SELECT COUNT(*) FROM blogs b WHERE b.user_id = $1 AND "+publicBlogCondition, userId).Scan(&total)
+
+	if err != nil {
+		return nil, err
+	}
+
+	totalPages := (total + limit - 1) / limit
+
+	return &model.PaginatedResponse[model.BlogSummary]{
+		Data: blogs,
+		Meta: model.PageMeta{
+			Total: total,
+			Page:  page,
+			Limit: limit,
+			Pages: totalPages,
+		},
+	}, nil
+}
+
+// public blogs carrying a tag, newest first
+func (b *BlogRepository) GetPublicBlogsByTag(tagId int64, page, limit int) (*model.PaginatedResponse[model.BlogSummary], error) {
+	if page < 1 {
+		page = 1
+	}
+
+	if limit <= 0 {
+		limit = 10
+	}
+
+	offset := (page - 1) * limit
+
+	var blogs []model.BlogSummary
+	query := `
+		SELECT 
+			b.id,
+			b.title,
+			b.user_id,
+			b.slug,
+			b.content,
+			b.status,
+			b.publish_at,
+			b.created_at,
+			b.updated_at,
+			COALESCE((
+				SELECT bp.photo_url 
+				FROM blog_photos bp 
+				WHERE bp.blog_id = b.id 
+				ORDER BY bp.id ASC 
+				LIMIT 1
+			), '') AS blog_thumbnail,
+			COUNT(l.*) FILTER (WHERE l.like_type = 'like')    AS likes_count,
+			COUNT(l.*) FILTER (WHERE l.like_type = 'dislike') AS dislikes_count,
+			COUNT(DISTINCT c.id) AS comments_count,
+			u.username,
+			COALESCE(up.full_name, '') AS author_name,
+			COALESCE(up.avatar_url, '') AS author_avatar
+		FROM blogs b
+		JOIN blog_tags bt ON bt.blog_id = b.id
+		JOIN users u ON u.id = b.user_id
+		LEFT JOIN user_profiles up ON up.user_id = b.user_id
+		LEFT JOIN likes l ON l.blog_id = b.id
+		LEFT JOIN comments c ON c.blog_id = b.id AND c.hidden_at IS NULL
+		WHERE bt.tag_id = $1 AND ` + publicBlogCondition + `
+		GROUP BY b.id, u.id, up.user_id
+		ORDER BY b.publish_at DESC, b.id DESC
+		LIMIT $2 OFFSET $3
+		`
+
+	rows, err := b.DB.Query(query, tagId, limit, offset)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		blog := model.BlogSummary{}
+
+		err := rows.Scan(
+			&blog.Id,
+			&blog.Title,
+			&blog.UserId,
+			&blog.Slug,
+			&blog.Content,
+			&blog.Status,
+			&blog.PublishAt,
+			&blog.CreatedAt,
+			&blog.UpdatedAt,
+			&blog.Thumbnail,
+			&blog.LikesCount,
+			&blog.DislikeCount,
+			&blog.CommentCount,
+			&blog.AuthorUsername,
+			&blog.AuthorName,
+			&blog.AuthorAvatar,
+		)
+
+		if err != nil {
+			return nil, err
+		}
+
+		blogs = append(blogs, blog)
+	}
+
+	if err := attachTags(b.DB, blogs); err != nil {
+		return nil, err
+	}
+
+	var total int
+	countQuery := `
+		SELECT COUNT(*) FROM blogs b
+		JOIN blog_tags bt ON bt.blog_id = b.id
+		WHERE bt.tag_id = $1 AND ` + publicBlogCondition
+
+	err = b.DB.QueryRow(countQuery, tagId).Scan(&total)
+
+	if err != nil {
+		return nil, err
+	}
+
+	totalPages := (total + limit - 1) / limit
+
+	return &model.PaginatedResponse[model.BlogSummary]{
+		Data: blogs,
+		Meta: model.PageMeta{
+			Total: total,
+			Page:  page,
+			Limit: limit,
+			Pages: totalPages,
+		},
+	}, nil
+}
+
+// public blogs of everyone the user follows, newest first, starting after the cursor
+func (b *BlogRepository) GetFeed(userId uuid.UUID, cursorTime *time.Time, cursorId int64, limit int) ([]model.BlogSummary, error) {
+	if limit <= 0 {
+		limit = 10
+	}
+
+	query := `
+		SELECT 
+			b.id,
+			b.title,
+			b.user_id,
+			b.slug,
+			b.content,
+			b.status,
+			b.publish_at,
+			b.created_at,
+			b.updated_at,
+			COALESCE((
+				SELECT bp.photo_url 
+				FROM blog_photos bp 
+				WHERE bp.blog_id = b.id 
+				ORDER BY bp.id ASC 
+				LIMIT 1
+			), '') AS blog_thumbnail,
+			(SELECT COUNT(*) FROM likes l WHERE l.blog_id = b.id AND l.like_type = 'like') AS likes_count,
+			(SELECT COUNT(*) FROM likes l WHERE l.blog_id = b.id AND l.like_type = 'dislike') AS dislikes_count,
+			(SELECT COUNT(*) FROM comments c WHERE c.blog_id = b.id AND c.hidden_at IS NULL) AS comments_count,
+			u.username,
+			COALESCE(up.full_name, '') AS author_name,
+			COALESCE(up.avatar_url, '') AS author_avatar
+		FROM follows f
+		JOIN blogs b ON b.user_id = f.following_id
+		JOIN users u ON u.id = b.user_id
+		LEFT JOIN user_profiles up ON up.user_id = b.user_id
+		WHERE f.follower_id = $1
+			AND ` + publicBlogCondition + `
+			AND ($2::timestamp IS NULL OR (b.publish_at, b.id) < ($2, $3))
+		ORDER BY b.publish_at DESC, b.id DESC
+		LIMIT $4
+	`
+
+	rows, err := b.DB.Query(query, userId, cursorTime, cursorId, limit)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var blogs []model.BlogSummary
+
+	for rows.Next() {
+		blog := model.BlogSummary{}
+
+		err := rows.Scan(
+			&blog.Id,
+			&blog.Title,
+			&blog.UserId,
+			&blog.Slug,
+			&blog.Content,
+			&blog.Status,
+			&blog.PublishAt,
+			&blog.CreatedAt,
+			&blog.UpdatedAt,
+			&blog.Thumbnail,
+			&blog.LikesCount,
+			&blog.DislikeCount,
+			&blog.CommentCount,
+			&blog.AuthorUsername,
+			&blog.AuthorName,
+			&blog.AuthorAvatar,
+		)
+
+		if err != nil {
+			return nil, err
+		}
+
+		blogs = append(blogs, blog)
+	}
+
+	if err := attachTags(b.DB, blogs); err != nil {
+		return nil, err
+	}
+
+	return blogs, nil
+}
+
+// ts_headline marks the matches with these instead of <mark>, the text around them is
+// raw user content and has to be escaped before the marks go in. The sentinels are
+// stripped from the content first so a stray one can't unbalance the marks
+const (
+	highlightStart = "\x02"
+	highlightStop  = "\x03"
+)
+
+func highlightHTML(headline string) string {
+	headline = html.EscapeString(headline)
+	headline = strings.ReplaceAll(headline, highlightStart, "<mark>")
+	return strings.ReplaceAll(headline, highlightStop, "</mark>")
+}
+
+// full text search over public blogs, author and publish date filters are optional
+func (b *BlogRepository) SearchPublicBlogs(searchQuery string, authorId *uuid.UUID, from, to *time.Time,
+	page, limit int) (*model.PaginatedResponse[model.BlogSummary], error) {
+	if page < 1 {
+		page = 1
+	}
+
+	if limit <= 0 {
+		limit = 10
+	}
+
+	offset := (page - 1) * limit
+
+	filter := `
+		b.search_vector @@ q.query
+		AND ` + publicBlogCondition + `
+		AND ($2::uuid IS NULL OR b.user_id = $2)
+		AND ($3::timestamp IS NULL OR b.publish_at >= $3)
+		AND ($4::timestamp IS NULL OR b.publish_at <= $4)
+	`
+
+	// the headlines are only worked out for the rows of this page
+	query := `
+		SELECT
+			p.id, p.title, p.user_id, p.slug, p.content, p.status, p.publish_at, p.created_at, p.updated_at,
+			p.blog_thumbnail, p.likes_count, p.dislikes_count, p.comments_count, p.rank,
+			ts_headline('english', translate(p.title, $8::text, ''), p.query, 'HighlightAll=true, ' || $7::text) AS title_highlight,
+			ts_headline('english', translate(p.content, $8::text, ''), p.query, 'MaxWords=35, MinWords=15, MaxFragments=2, ' || $7::text) AS highlight
+		FROM (
+			SELECT 
+				b.id,
+				b.title,
+				b.user_id,
+				b.slug,
+				b.content,
+				b.status,
+				b.publish_at,
+				b.created_at,
+				b.updated_at,
+				COALESCE((
+					SELECT bp.photo_url 
+					FROM blog_photos bp 
+					WHERE bp.blog_id = b.id 
+					ORDER BY bp.id ASC 
+					LIMIT 1
+				), '') AS blog_thumbnail,
+				COUNT(l.*) FILTER (WHERE l.like_type = 'like')    AS likes_count,
+				COUNT(l.*) FILTER (WHERE l.like_type = 'dislike') AS dislikes_count,
+				COUNT(DISTINCT c.id) AS comments_count,
+				ts_rank_cd(b.search_vector, q.query) AS rank,
+				q.query
+			FROM blogs b
+			CROSS JOIN websearch_to_tsquery('english', $1) AS q(query)
+			LEFT JOIN likes l ON l.blog_id = b.id
+			LEFT JOIN comments c ON c.blog_id = b.id AND c.hidden_at IS NULL
+			WHERE ` + filter + `
+			GROUP BY b.id, q.query
+			ORDER BY rank DESC, b.publish_at DESC
+			LIMIT $5 OFFSET $6
+		) p
+		ORDER BY p.rank DESC, p.publish_at DESC
+	`
+
+	rows, err := b.DB.Query(query, searchQuery, authorId, from, to, limit, offset,
+		"StartSel="+highlightStart+", StopSel="+highlightStop, highlightStart+highlightStop)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var blogs []model.BlogSummary
+
+	for rows.Next() {
+		blog := model.BlogSummary{}
+
+		err := rows.Scan(
+			&blog.Id,
+			&blog.Title,
+			&blog.UserId,
+			&blog.Slug,
+			&blog.Content,
+			&blog.Status,
+			&blog.PublishAt,
+			&blog.CreatedAt,
+			&blog.UpdatedAt,
+			&blog.Thumbnail,
+			&blog.LikesCount,
+			&blog.DislikeCount,
+			&blog.CommentCount,
+			&blog.Rank,
+			&blog.TitleHighlight,
+			&blog.Highlight,
+		)
+
+		if err != nil {
+			return nil, err
+		}
+
+		blog.TitleHighlight = highlightHTML(blog.TitleHighlight)
+		blog.Highlight = highlightHTML(blog.Highlight)
+
+		blogs = append(blogs, blog)
+	}
+
+	if err := attachTags(b.DB, blogs); err != nil {
+		return nil, err
+	}
+
+	var total int
+	countQuery := `
+		SELECT COUNT(*)
+		FROM blogs b
+		CROSS JOIN websearch_to_tsquery('english', $1) AS q(query)
+		WHERE ` + filter
+
+	err = b.DB.QueryRow(countQuery, searchQuery, authorId, from, to).Scan(&total)
 
 	if err != nil {
 		return nil, err
@@ -233,10 +719,11 @@ func (b *BlogRepository) GetAllBlog(userId uuid.UUID, page, limit int) (*model.P
 		SELECT 
 			b.id,
 			b.title,
+			b.user_id,
 			b.slug,
 			b.content,
-			b.visibility,
-			b.user_id,
+			b.status,
+			b.publish_at,
 			b.created_at,
 			b.updated_at,
 			COALESCE((
@@ -251,9 +738,9 @@ func (b *BlogRepository) GetAllBlog(userId uuid.UUID, page, limit int) (*model.P
 			COUNT(DISTINCT c.id) AS comments_count
 		FROM blogs b
 		LEFT JOIN likes l ON l.blog_id = b.id
-		LEFT JOIN comments c ON c.blog_id = b.id
+		LEFT JOIN comments c ON c.blog_id = b.id AND c.hidden_at IS NULL
 		WHERE b.user_id = $1
-		GROUP BY b.id, b.title, b.slug, b.content, b.user_id, b.created_at, b.updated_at
+		GROUP BY b.id
 		ORDER BY b.created_at DESC
 		LIMIT $2 OFFSET $3
 		`
@@ -275,7 +762,8 @@ func (b *BlogRepository) GetAllBlog(userId uuid.UUID, page, limit int) (*model.P
 			&blog.UserId,
 			&blog.Slug,
 			&blog.Content,
-			&blog.Visibility,
+			&blog.Status,
+			&blog.PublishAt,
 			&blog.CreatedAt,
 			&blog.UpdatedAt,
 			&blog.Thumbnail,
@@ -291,6 +779,10 @@ func (b *BlogRepository) GetAllBlog(userId uuid.UUID, page, limit int) (*model.P
 		blogs = append(blogs, blog)
 	}
 
+	if err := attachTags(b.DB, blogs); err != nil {
+		return nil, err
+	}
+
 	// total blogs
 	var total int
 	err = b.DB.QueryRow("SELECT COUNT(*) FROM blogs WHERE blogs.user_id = $1", userId).Scan(&total)
@@ -327,7 +819,7 @@ func (b *BlogRepository) GetBlogBySlug(userId uuid.UUID, slug string) (*model.Bl
 		return nil, err
 	}
 
-	comments, err := b.getBlogComments(blogDataStat.Id)
+	tags, err := getTagsByBlogIds(b.DB, []int64{blogDataStat.Id})
 
 	if err != nil {
 		return nil, err
@@ -335,7 +827,7 @@ func (b *BlogRepository) GetBlogBySlug(userId uuid.UUID, slug string) (*model.Bl
 
 	blog := model.BlogResponse{
 		BlogWithStat: *blogDataStat,
-		Comments:     comments,
+		Tags:         nonNilTags(tags[blogDataStat.Id]),
 		AuthorName:   authorData.FullName,
 		AuthorBio:    authorData.Bio,
 		AuthorAvatar: authorData.Avatar,
@@ -344,24 +836,63 @@ func (b *BlogRepository) GetBlogBySlug(userId uuid.UUID, slug string) (*model.Bl
 	return &blog, nil
 }
 
-// get blog by title
-func (b *BlogRepository) GetBlogByTitle(userId uuid.UUID, title string) error {
-	query := `SELECT * FROM blogs  WHERE user_id=$1 AND title=$2`
-	_, err := b.DB.Exec(query, userId, title)
+// a blog of the author the viewer can comment on, any of them when the viewer is the author
+func (b *BlogRepository) GetReachableBlog(viewerId, authorId uuid.UUID, slug string) (*model.BlogWithStat, error) {
+	var blog model.BlogWithStat
+
+	query := `
+		SELECT b.id, b.user_id, b.title, b.slug, b.status, b.comments_locked, b.comments_followers_only, b.pinned_comment_id
+		FROM blogs b
+		WHERE b.user_id = $2 AND b.slug = $3 AND (b.user_id = $1 OR ` + reachableBlogCondition + `)
+	`
+
+	err := b.DB.QueryRow(query, viewerId, authorId, slug).Scan(&blog.Id, &blog.UserId, &blog.Title, &blog.Slug, &blog.Status,
+		&blog.CommentsLocked, &blog.CommentsFollowersOnly, &blog.PinnedCommentId)
 
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	return nil
+	return &blog, nil
+}
+
+// lock the discussion or open it to followers only, nil leaves a setting as it is
+func (b *BlogRepository) UpdateCommentSettings(blogId int64, locked, followersOnly *bool) error {
+	_, err := b.DB.Exec(`UPDATE blogs SET comments_locked = COALESCE($2, comments_locked),
+		comments_followers_only = COALESCE($3, comments_followers_only) WHERE id = $1`, blogId, locked, followersOnly)
+
+	return err
+}
+
+// pin a comment to the top of the discussion, it replaces the one pinned before
+func (b *BlogRepository) SetPinnedComment(blogId, commentId int64) error {
+	_, err := b.DB.Exec("UPDATE blogs SET pinned_comment_id = $2 WHERE id = $1", blogId, commentId)
+
+	return err
+}
+
+// unpin the comment, nothing happens when another one is pinned
+func (b *BlogRepository) UnpinComment(blogId, commentId int64) error {
+	_, err := b.DB.Exec("UPDATE blogs SET pinned_comment_id = NULL WHERE id = $1 AND pinned_comment_id = $2", blogId, commentId)
+
+	return err
+}
+
+// get blog by title
+func (b *BlogRepository) GetBlogByTitle(userId uuid.UUID, title string) error {
+	var blogId int64
+	query := `SELECT id FROM blogs WHERE user_id=$1 AND title=$2`
+
+	return b.DB.QueryRow(query, userId, title).Scan(&blogId)
 }
 
 // get blog by id
 func (b *BlogRepository) GetBlogById(userId uuid.UUID, blogId int64) (*model.BlogResponse, error) {
 	var blog model.Blog
 
-	err := b.DB.QueryRow("SELECT * FROM blogs WHERE id = $1", blogId).Scan(
-		&blog.Id, &blog.UserId, &blog.Title, &blog.Slug, &blog.Content, &blog.Visibility,
+	err := b.DB.QueryRow(`SELECT id, user_id, title, slug, content, status, publish_at, created_at, updated_at
+		FROM blogs WHERE id = $1`, blogId).Scan(
+		&blog.Id, &blog.UserId, &blog.Title, &blog.Slug, &blog.Content, &blog.Status, &blog.PublishAt,
 		&blog.CreatedAt, &blog.UpdatedAt,
 	)
 
@@ -381,7 +912,7 @@ func (b *BlogRepository) GetBlogById(userId uuid.UUID, blogId int64) (*model.Blo
 		return nil, err
 	}
 
-	comments, err := b.getBlogComments(blogData.Id)
+	tags, err := getTagsByBlogIds(b.DB, []int64{blogData.Id})
 
 	if err != nil {
 		return nil, err
@@ -389,7 +920,7 @@ func (b *BlogRepository) GetBlogById(userId uuid.UUID, blogId int64) (*model.Blo
 
 	blogRes := model.BlogResponse{
 		BlogWithStat: *blogData,
-		Comments:     comments,
+		Tags:         nonNilTags(tags[blogData.Id]),
 		AuthorName:   authorData.FullName,
 		AuthorBio:    authorData.Bio,
 		AuthorAvatar: authorData.Avatar,
@@ -398,28 +929,6 @@ func (b *BlogRepository) GetBlogById(userId uuid.UUID, blogId int64) (*model.Blo
 	return &blogRes, nil
 }
 
-func (b *BlogRepository) GetPhotoUrls(blogId int64) ([]string, error) {
-	var photoUrls []string
-	rows, err := b.DB.Query("SELECT photo_url FROM blog_photos WHERE blog_id=$1", blogId)
-
-	if err != nil {
-		return nil, err
-	}
-
-	defer rows.Close()
-
-	for rows.Next() {
-		var url string
-		if err := rows.Scan(&url); err != nil {
-			return nil, err
-		}
-
-		photoUrls = append(photoUrls, url)
-	}
-
-	return photoUrls, nil
-}
-
 func (b *BlogRepository) getBlogWithStatBySlug(userId uuid.UUID, slug string) (*model.BlogWithStat, error) {
 	var blogData model.BlogWithStat
 
@@ -430,23 +939,33 @@ func (b *BlogRepository) getBlogWithStatBySlug(userId uuid.UUID, slug string) (*
 			b.user_id,
 			b.slug,
 			b.content,
-			b.visibility,
+			COALESCE(b.content_html, '') AS content_html,
+			b.hidden_at,
+			b.status,
+			b.publish_at,
 			b.created_at,
 			b.updated_at, 
-			COALESCE(array_agg(bp.photo_url) FILTER (WHERE bp.photo_url IS NOT NULL), '{}') AS photo_urls,
+			b.comments_locked,
+			b.comments_followers_only,
+			b.pinned_comment_id,
+			COALESCE((
+				SELECT array_agg(bp.photo_url ORDER BY bp.id)
+				FROM blog_photos bp
+				WHERE bp.blog_id = b.id
+			), '{}') AS photo_urls,
 			COUNT(l.*) FILTER (WHERE l.like_type = 'like') AS likes_count,
 			COUNT(l.*) FILTER (WHERE l.like_type = 'dislike') AS dislikes_count
 
 		FROM blogs b
-		LEFT JOIN blog_photos bp ON b.id = bp.blog_id
 		LEFT JOIN likes l ON b.id = l.blog_id
 		WHERE b.user_id = $1 AND b.slug = $2
 		GROUP BY b.id
 	 `
 
 	err := b.DB.QueryRow(blogQuery, userId, slug).Scan(
-		&blogData.Id, &blogData.Title, &blogData.UserId, &blogData.Slug, &blogData.Content, &blogData.Visibility,
-		&blogData.CreatedAt, &blogData.UpdatedAt, &blogData.PhotoUrls, &blogData.LikeCount, &blogData.DislikeCount,
+		&blogData.Id, &blogData.Title, &blogData.UserId, &blogData.Slug, &blogData.Content, &blogData.ContentHtml, &blogData.HiddenAt, &blogData.Status,
+		&blogData.PublishAt, &blogData.CreatedAt, &blogData.UpdatedAt, &blogData.CommentsLocked, &blogData.CommentsFollowersOnly,
+		&blogData.PinnedCommentId, textArray(&blogData.PhotoUrls), &blogData.LikeCount, &blogData.DislikeCount,
 	)
 
 	if err != nil {
@@ -473,50 +992,3 @@ func (b *BlogRepository) getAuthorData(userId uuid.UUID) (*authorData, error) {
 
 	return &authorData, nil
 }
-
-func (b *BlogRepository) getBlogComments(blogId int64) ([]model.CommentWithStat, error) {
-	var comments []model.CommentWithStat
-
-	commentQuery := `
-		SELECT 
-			c.id,
-			c.content,
-			c.user_id,
-			c.parent_id,
-			c.created_at,
-			c.updated_at,
-			COUNT(l.*) FILTER (WHERE l.like_type = 'like') AS likes_count,
-			COUNT(l.*) FILTER (WHERE l.like_type = 'dislike') AS dislikes_count
-
-		FROM comments c
-		LEFT JOIN likes l ON c.id = l.comment_id
-		WHERE c.blog_id = $1
-		GROUP BY c.id
-		ORDER BY c.created_at ASC
-	`
-
-	commentRows, err := b.DB.Query(commentQuery, blogId)
-
-	if err != nil {
-		return nil, err
-	}
-
-	defer commentRows.Close()
-
-	for commentRows.Next() {
-		var comment model.CommentWithStat
-
-		err := commentRows.Scan(
-			&comment.Id, &comment.Content, &comment.UserId, &comment.ParentId,
-			&comment.CreatedAt, &comment.UpdatedAt, &comment.LikeCount, &comment.DislikeCount,
-		)
-
-		if err != nil {
-			return nil, err
-		}
-
-		comments = append(comments, comment)
-	}
-
-	return comments, err
-}
@@ -0,0 +1,164 @@
+package repo
+
+import (
+	"database/sql"
+
+	"github.com/harry713j/vibe_writer/internal/model"
+)
+
+type BlogRevisionRepository struct {
+	DB *sql.DB
+}
+
+func NewBlogRevisionRepository(db *sql.DB) *BlogRevisionRepository {
+	return &BlogRevisionRepository{DB: db}
+}
+
+// create the next revision of a blog and return its number
+func (r *BlogRevisionRepository) CreateRevision(blogId int64, title, content string, photoUrls, addedUrls, removedUrls []string,
+	restoredFrom *int) (int, error) {
+
+	query := `
+		INSERT INTO blog_revisions(blog_id, revision, title, content, photo_urls, added_photo_urls, removed_photo_urls, restored_from)
+		VALUES(
+			$1,
+			(SELECT COALESCE(MAX(revision), 0) + 1 FROM blog_revisions WHERE blog_id = $1),
+			$2, $3, $4, $5, $6, $7
+		)
+		RETURNING revision
+	`
+
+	var revision int
+
+	err := r.DB.QueryRow(query, blogId, title, content, nonNilUrls(photoUrls), nonNilUrls(addedUrls),
+		nonNilUrls(removedUrls), restoredFrom).Scan(&revision)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return revision, nil
+}
+
+func (r *BlogRevisionRepository) GetRevisions(blogId int64, page, limit int) (*model.PaginatedResponse[model.BlogRevisionSummary], error) {
+	if page < 1 {
+		page = 1
+	}
+
+	if limit <= 0 {
+		limit = 20
+	}
+
+	offset := (page - 1) * limit
+
+	query := `
+		SELECT id, revision, title, restored_from, created_at
+		FROM blog_revisions
+		WHERE blog_id = $1
+		ORDER BY revision DESC
+		LIMIT $2 OFFSET $3
+	`
+
+	rows, err := r.DB.Query(query, blogId, limit, offset)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var revisions []model.BlogRevisionSummary
+
+	for rows.Next() {
+		var revision model.BlogRevisionSummary
+
+		err := rows.Scan(&revision.Id, &revision.Revision, &revision.Title, &revision.RestoredFrom, &revision.CreatedAt)
+
+		if err != nil {
+			return nil, err
+		}
+
+		revisions = append(revisions, revision)
+	}
+
+	var total int
+	err = r.DB.QueryRow("SELECT COUNT(*) FROM blog_revisions WHERE blog_id = $1", blogId).Scan(&total)
+
+	if err != nil {
+		return nil, err
+	}
+
+	totalPages := (total + limit - 1) / limit
+
+	return &model.PaginatedResponse[model.BlogRevisionSummary]{
+		Data: revisions,
+		Meta: model.PageMeta{
+			Total: total,
+			Page:  page,
+			Limit: limit,
+			Pages: totalPages,
+		},
+	}, nil
+}
+
+func (r *BlogRevisionRepository) GetRevision(blogId int64, revisionNumber int) (*model.BlogRevision, error) {
+	var revision model.BlogRevision
+
+	query := `
+		SELECT id, blog_id, revision, title, content, photo_urls, added_photo_urls, removed_photo_urls,
+			restored_from, created_at
+		FROM blog_revisions
+		WHERE blog_id = $1 AND revision = $2
+	`
+
+	err := r.DB.QueryRow(query, blogId, revisionNumber).Scan(
+		&revision.Id, &revision.BlogId, &revision.Revision, &revision.Title, &revision.Content,
+		textArray(&revision.PhotoUrls), textArray(&revision.AddedPhotoUrls), textArray(&revision.RemovedPhotoUrls),
+		&revision.RestoredFrom, &revision.CreatedAt,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &revision, nil
+}
+
+// every photo url a blog has referenced in any of its revisions
+func (r *BlogRevisionRepository) GetAllPhotoUrls(blogId int64) ([]string, error) {
+	query := `
+		SELECT DISTINCT url
+		FROM blog_revisions, unnest(photo_urls) AS url
+		WHERE blog_id = $1
+	`
+
+	rows, err := r.DB.Query(query, blogId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var photoUrls []string
+
+	for rows.Next() {
+		var url string
+		if err := rows.Scan(&url); err != nil {
+			return nil, err
+		}
+
+		photoUrls = append(photoUrls, url)
+	}
+
+	return photoUrls, nil
+}
+
+// text[] columns are NOT NULL, so never send a nil slice
+func nonNilUrls(urls []string) []string {
+	if urls == nil {
+		return []string{}
+	}
+
+	return urls
+}
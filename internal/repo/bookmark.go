@@ -17,7 +17,7 @@ func NewBookmarkRepository(db *sql.DB) *BookmarkRepository {
 func (b *BookmarkRepository) Upsert(userId uuid.UUID, blogId int64) error {
 	query := `
 		INSERT INTO bookmarks(user_id, blog_id) VALUES($1, $2)
-		ON CONFLICT(user_id, blog_id) DO NOTHNG
+		ON CONFLICT(user_id, blog_id) DO NOTHING
 	`
 	if _, err := b.DB.Exec(query, userId, blogId); err != nil {
 		return err
@@ -2,6 +2,7 @@ package repo
 
 import (
 	"database/sql"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/harry713j/vibe_writer/internal/model"
@@ -59,6 +60,8 @@ func (c *CommentRepository) GetCommentById(userId uuid.UUID, id int64) (*model.C
 			c.content,
 			c.created_at,
 			c.updated_at,
+			c.edit_count,
+			c.edited_at,
 			COUNT(l.id) FILTER (WHERE l.like_type = 'like') AS likes_count,
 			COUNT(l.id) FILTER (WHERE l.like_type = 'dislike') AS dislikes_count
 		FROM comments c
@@ -69,38 +72,277 @@ func (c *CommentRepository) GetCommentById(userId uuid.UUID, id int64) (*model.C
 
 	err := c.DB.QueryRow(query, userId, id).Scan(
 		&comment.Id, &comment.UserId, &comment.ParentId, &comment.Content,
-		&comment.CreatedAt, &comment.UpdatedAt, &comment.LikeCount, &comment.DislikeCount,
+		&comment.CreatedAt, &comment.UpdatedAt, &comment.EditCount, &comment.EditedAt,
+		&comment.LikeCount, &comment.DislikeCount,
 	)
 
 	if err != nil {
 		return nil, err
 	}
 
+	comment.Edited = comment.EditCount > 0
+
 	return &comment, nil
 }
 
-// Get comments of a blog
-func (c *CommentRepository) GetCommentsByBlogId(blogId int64) ([]model.CommentWithStat, error) {
-	var comments []model.CommentWithStat
+// columns scanned by scanCommentNodes, in order. The viewer is $1 in every query using them,
+// hidden comments are only counted and shown for their writer
+const commentNodeColumns = `
+	c.id,
+	c.user_id,
+	c.parent_id,
+	c.content,
+	c.created_at,
+	c.updated_at,
+	c.edit_count,
+	c.edited_at,
+	c.hidden_at IS NOT NULL AS hidden,
+	c.hidden_at IS NOT NULL AND c.user_id <> $1 AS placeholder,
+	(SELECT COUNT(*) FROM likes l WHERE l.comment_id = c.id AND l.like_type = 'like') AS likes_count,
+	(SELECT COUNT(*) FROM likes l WHERE l.comment_id = c.id AND l.like_type = 'dislike') AS dislikes_count,
+	(SELECT COUNT(*) FROM comments r WHERE r.parent_id = c.id) AS reply_count,
+	u.username,
+	COALESCE(up.full_name, '') AS author_name,
+	COALESCE(up.avatar_url, '') AS author_avatar
+`
+
+const commentNodeJoins = `
+	JOIN users u ON u.id = c.user_id
+	LEFT JOIN user_profiles up ON up.user_id = c.user_id
+`
+
+// a hidden comment keeps its place in the thread so the replies under it stay, everyone but
+// its writer only gets a placeholder without the content or who wrote it
+func scanCommentNodes(rows *sql.Rows) ([]model.CommentNode, error) {
+	defer rows.Close()
+
+	var comments []model.CommentNode
+
+	for rows.Next() {
+		comment := model.CommentNode{}
+		var placeholder bool
+
+		err := rows.Scan(
+			&comment.Id, &comment.UserId, &comment.ParentId, &comment.Content,
+			&comment.CreatedAt, &comment.UpdatedAt, &comment.EditCount, &comment.EditedAt, &comment.Hidden, &placeholder,
+			&comment.LikeCount, &comment.DislikeCount, &comment.ReplyCount,
+			&comment.AuthorUsername, &comment.AuthorName, &comment.AuthorAvatar,
+		)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if placeholder {
+			comment.UserId = uuid.Nil
+			comment.Content = ""
+			comment.AuthorUsername = ""
+			comment.AuthorName = ""
+			comment.AuthorAvatar = ""
+		}
+
+		comment.Edited = comment.EditCount > 0
+
+		comments = append(comments, comment)
+	}
+
+	return comments, rows.Err()
+}
+
+// comment of the blog the viewer can see, replies can only be loaded under these
+func (c *CommentRepository) GetBlogComment(viewerId uuid.UUID, blogId, commentId int64) (*model.CommentWithStat, error) {
+	var comment model.CommentWithStat
+
+	err := c.DB.QueryRow(`SELECT id, user_id, parent_id, content, created_at, updated_at, edit_count, edited_at
+		FROM comments WHERE blog_id = $2 AND id = $3 AND (hidden_at IS NULL OR user_id = $1)`, viewerId, blogId, commentId).Scan(
+		&comment.Id, &comment.UserId, &comment.ParentId, &comment.Content, &comment.CreatedAt, &comment.UpdatedAt,
+		&comment.EditCount, &comment.EditedAt,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	comment.Edited = comment.EditCount > 0
 
+	return &comment, nil
+}
+
+// a top level comment of the blog with its stats
+func (c *CommentRepository) GetRootComment(viewerId uuid.UUID, blogId, commentId int64) (*model.CommentNode, error) {
 	query := `
-		SELECT
-			c.id,
-			c.user_id,
-			c.parent_id,
-			c.content,
-			c.created_at,
-			c.updated_at,
-			COUNT(l.id) FILTER (WHERE l.like_type = 'like') AS likes_count,
-			COUNT(l.id) FILTER (WHERE l.like_type = 'dislike') AS dislikes_count
+		SELECT ` + commentNodeColumns + `
+		FROM comments c` + commentNodeJoins + `
+		WHERE c.blog_id = $2 AND c.id = $3 AND c.parent_id IS NULL
+	`
+
+	rows, err := c.DB.Query(query, viewerId, blogId, commentId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	comments, err := scanCommentNodes(rows)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(comments) == 0 {
+		return nil, sql.ErrNoRows
+	}
+
+	return &comments[0], nil
+}
+
+// top level comments of a blog newest first, the page starts after the cursor when it's given.
+// The pinned comment is left out, it's shown on top of the first page instead
+func (c *CommentRepository) GetRootComments(viewerId uuid.UUID, blogId int64, pinnedId *int64, cursorTime *time.Time,
+	cursorId int64, limit int) ([]model.CommentNode, error) {
+	query := `
+		SELECT ` + commentNodeColumns + `
+		FROM comments c` + commentNodeJoins + `
+		WHERE c.blog_id = $2 AND c.parent_id IS NULL
+			AND c.id IS DISTINCT FROM $3
+			AND ($4::timestamp IS NULL OR (c.created_at, c.id) < ($4, $5))
+		ORDER BY c.created_at DESC, c.id DESC
+		LIMIT $6
+	`
+
+	rows, err := c.DB.Query(query, viewerId, blogId, pinnedId, cursorTime, cursorId, limit)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return scanCommentNodes(rows)
+}
+
+// replies of a comment oldest first, the page starts after the cursor when it's given
+func (c *CommentRepository) GetReplies(viewerId uuid.UUID, parentId int64, cursorTime *time.Time, cursorId int64,
+	limit int) ([]model.CommentNode, error) {
+	query := `
+		SELECT ` + commentNodeColumns + `
+		FROM comments c` + commentNodeJoins + `
+		WHERE c.parent_id = $2
+			AND ($3::timestamp IS NULL OR (c.created_at, c.id) > ($3, $4))
+		ORDER BY c.created_at ASC, c.id ASC
+		LIMIT $5
+	`
+
+	rows, err := c.DB.Query(query, viewerId, parentId, cursorTime, cursorId, limit)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return scanCommentNodes(rows)
+}
+
+// the first perParent replies of every given comment in one query, oldest first
+func (c *CommentRepository) GetFirstReplies(viewerId uuid.UUID, parentIds []int64, perParent int) ([]model.CommentNode, error) {
+	if len(parentIds) == 0 {
+		return nil, nil
+	}
+
+	query := `
+		SELECT ` + commentNodeColumns + `
+		FROM (
+			SELECT r.*, ROW_NUMBER() OVER (PARTITION BY r.parent_id ORDER BY r.created_at, r.id) AS position
+			FROM comments r
+			WHERE r.parent_id = ANY($2)
+		) c` + commentNodeJoins + `
+		WHERE c.position <= $3
+		ORDER BY c.parent_id, c.position
+	`
+
+	rows, err := c.DB.Query(query, viewerId, parentIds, perParent)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return scanCommentNodes(rows)
+}
+
+// the comment with its writer and the author of its blog
+func (c *CommentRepository) GetCommentOwnership(commentId int64) (*model.CommentOwnership, error) {
+	var ownership model.CommentOwnership
+
+	query := `
+		SELECT c.id, c.blog_id, c.parent_id, c.user_id, b.user_id, c.hidden_at, c.hidden_by_author
 		FROM comments c
-		LEFT JOIN likes l ON c.id = l.comment_id
-		WHERE c.blog_id = $1
-		GROUP BY c.id
-		ORDER BY c.created_at DESC
+		JOIN blogs b ON b.id = c.blog_id
+		WHERE c.id = $1
 	`
 
-	rows, err := c.DB.Query(query, blogId)
+	err := c.DB.QueryRow(query, commentId).Scan(&ownership.CommentId, &ownership.BlogId, &ownership.ParentId,
+		&ownership.UserId, &ownership.BlogAuthorId, &ownership.HiddenAt, &ownership.HiddenByAuthor)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &ownership, nil
+}
+
+// hide or show a comment for the blog author, a comment a moderator hid stays hidden
+func (c *CommentRepository) SetCommentHiddenByAuthor(commentId int64, hidden bool) error {
+	query := "UPDATE comments SET hidden_at = NULL, hidden_by_author = FALSE WHERE id = $1 AND hidden_by_author"
+
+	if hidden {
+		query = `UPDATE comments SET hidden_at = CURRENT_TIMESTAMP, hidden_by_author = TRUE
+			WHERE id = $1 AND hidden_at IS NULL`
+	}
+
+	_, err := c.DB.Exec(query, commentId)
+
+	return err
+}
+
+// replace the content of the user's comment, the old content becomes a revision
+func (c *CommentRepository) UpdateComment(userId uuid.UUID, commentId int64, content string, now time.Time) error {
+	tx, err := c.DB.Begin()
+
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	var oldContent string
+	var writtenAt time.Time
+	var editCount int
+
+	// the lock keeps two edits from taking the same revision number
+	err = tx.QueryRow(`SELECT content, COALESCE(edited_at, created_at, CURRENT_TIMESTAMP), edit_count FROM comments
+		WHERE id = $1 AND user_id = $2 FOR UPDATE`, commentId, userId).Scan(&oldContent, &writtenAt, &editCount)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`INSERT INTO comment_revisions(comment_id, revision, content, created_at, replaced_at)
+		VALUES($1, $2, $3, $4, $5)`, commentId, editCount+1, oldContent, writtenAt, now)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`UPDATE comments SET content = $2, edit_count = edit_count + 1, edited_at = $3, updated_at = $3
+		WHERE id = $1`, commentId, content, now)
+
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// earlier versions of a comment, newest first
+func (c *CommentRepository) GetCommentRevisions(commentId int64) ([]model.CommentRevision, error) {
+	rows, err := c.DB.Query(`SELECT id, comment_id, revision, content, created_at, replaced_at
+		FROM comment_revisions WHERE comment_id = $1 ORDER BY revision DESC`, commentId)
 
 	if err != nil {
 		return nil, err
@@ -108,22 +350,31 @@ func (c *CommentRepository) GetCommentsByBlogId(blogId int64) ([]model.CommentWi
 
 	defer rows.Close()
 
+	revisions := []model.CommentRevision{}
+
 	for rows.Next() {
-		comment := model.CommentWithStat{}
+		var revision model.CommentRevision
 
-		err := rows.Scan(
-			&comment.Id, &comment.UserId, &comment.ParentId, &comment.Content,
-			&comment.CreatedAt, &comment.UpdatedAt, &comment.LikeCount, &comment.DislikeCount,
-		)
+		err := rows.Scan(&revision.Id, &revision.CommentId, &revision.Revision, &revision.Content,
+			&revision.CreatedAt, &revision.ReplacedAt)
 
 		if err != nil {
 			return nil, err
 		}
 
-		comments = append(comments, comment)
+		revisions = append(revisions, revision)
 	}
 
-	return comments, nil
+	return revisions, rows.Err()
+}
+
+// whether the comment exists, hidden or not
+func (c *CommentRepository) CommentExists(commentId int64) (bool, error) {
+	var exists bool
+
+	err := c.DB.QueryRow("SELECT EXISTS(SELECT 1 FROM comments WHERE id = $1)", commentId).Scan(&exists)
+
+	return exists, err
 }
 
 // delete a comment
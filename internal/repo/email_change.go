@@ -0,0 +1,104 @@
+package repo
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/harry713j/vibe_writer/internal/model"
+)
+
+type EmailChangeRepository struct {
+	DB *sql.DB
+}
+
+func NewEmailChangeRepository(db *sql.DB) *EmailChangeRepository {
+	return &EmailChangeRepository{DB: db}
+}
+
+func (r *EmailChangeRepository) CreateEmailChange(userId uuid.UUID, newEmail, tokenHash string, expireAt time.Time) error {
+	_, err := r.DB.Exec("INSERT INTO email_changes(user_id, new_email, token_hash, expire_at) VALUES($1, $2, $3, $4)",
+		userId, newEmail, tokenHash, expireAt)
+
+	return err
+}
+
+// most recently requested change of the user
+func (r *EmailChangeRepository) GetLatestEmailChange(userId uuid.UUID) (*model.EmailChange, error) {
+	var change model.EmailChange
+
+	query := `
+		SELECT id, user_id, new_email, expire_at, used_at, created_at
+		FROM email_changes
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+		LIMIT 1
+	`
+
+	err := r.DB.QueryRow(query, userId).Scan(&change.Id, &change.UserId, &change.NewEmail, &change.ExpireAt,
+		&change.UsedAt, &change.CreatedAt)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &change, nil
+}
+
+// an unused, unexpired change
+func (r *EmailChangeRepository) GetEmailChange(tokenHash string, now time.Time) (*model.EmailChange, error) {
+	var change model.EmailChange
+
+	query := `
+		SELECT id, user_id, new_email, expire_at, used_at, created_at
+		FROM email_changes
+		WHERE token_hash = $1 AND used_at IS NULL AND expire_at > $2
+	`
+
+	err := r.DB.QueryRow(query, tokenHash, now).Scan(&change.Id, &change.UserId, &change.NewEmail, &change.ExpireAt,
+		&change.UsedAt, &change.CreatedAt)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &change, nil
+}
+
+// spend the change and switch to the confirmed address in one go, it counts as verified.
+// False when the change was used already, when the address can't be set it stays unused
+func (r *EmailChangeRepository) ApplyEmailChange(change *model.EmailChange, now time.Time) (bool, error) {
+	tx, err := r.DB.Begin()
+
+	if err != nil {
+		return false, err
+	}
+
+	defer tx.Rollback()
+
+	result, err := tx.Exec("UPDATE email_changes SET used_at = $2 WHERE id = $1 AND used_at IS NULL", change.Id, now)
+
+	if err != nil {
+		return false, err
+	}
+
+	if affected, err := result.RowsAffected(); err != nil || affected != 1 {
+		return false, err
+	}
+
+	_, err = tx.Exec("UPDATE users SET email=$2, email_verified_at=$3, updated_at=$3 WHERE id=$1",
+		change.UserId, change.NewEmail, now)
+
+	if err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit()
+}
+
+// drop the links that were never used, so only the newest one works
+func (r *EmailChangeRepository) DeleteUnusedEmailChanges(userId uuid.UUID) error {
+	_, err := r.DB.Exec("DELETE FROM email_changes WHERE user_id = $1 AND used_at IS NULL", userId)
+
+	return err
+}
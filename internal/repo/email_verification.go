@@ -0,0 +1,77 @@
+package repo
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/harry713j/vibe_writer/internal/model"
+)
+
+type EmailVerificationRepository struct {
+	DB *sql.DB
+}
+
+func NewEmailVerificationRepository(db *sql.DB) *EmailVerificationRepository {
+	return &EmailVerificationRepository{DB: db}
+}
+
+func (r *EmailVerificationRepository) CreateVerification(userId, tokenId uuid.UUID, email string, expireAt time.Time) error {
+	_, err := r.DB.Exec("INSERT INTO email_verifications(user_id, token_id, email, expire_at) VALUES($1, $2, $3, $4)",
+		userId, tokenId, email, expireAt)
+
+	return err
+}
+
+// most recently issued verification of the user
+func (r *EmailVerificationRepository) GetLatestVerification(userId uuid.UUID) (*model.EmailVerification, error) {
+	var verification model.EmailVerification
+
+	query := `
+		SELECT id, user_id, token_id, email, expire_at, used_at, created_at
+		FROM email_verifications
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+		LIMIT 1
+	`
+
+	err := r.DB.QueryRow(query, userId).Scan(
+		&verification.Id, &verification.UserId, &verification.TokenId, &verification.Email,
+		&verification.ExpireAt, &verification.UsedAt, &verification.CreatedAt,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &verification, nil
+}
+
+// consume an unused, unexpired verification, a second use finds no row
+func (r *EmailVerificationRepository) UseVerification(tokenId uuid.UUID, now time.Time) (*model.EmailVerification, error) {
+	var verification model.EmailVerification
+
+	query := `
+		UPDATE email_verifications SET used_at = $2
+		WHERE token_id = $1 AND used_at IS NULL AND expire_at > $2
+		RETURNING id, user_id, token_id, email, expire_at, used_at, created_at
+	`
+
+	err := r.DB.QueryRow(query, tokenId, now).Scan(
+		&verification.Id, &verification.UserId, &verification.TokenId, &verification.Email,
+		&verification.ExpireAt, &verification.UsedAt, &verification.CreatedAt,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &verification, nil
+}
+
+// drop the links that were never used, so only the newest one works
+func (r *EmailVerificationRepository) DeleteUnusedVerifications(userId uuid.UUID) error {
+	_, err := r.DB.Exec("DELETE FROM email_verifications WHERE user_id = $1 AND used_at IS NULL", userId)
+
+	return err
+}
@@ -31,6 +31,15 @@ func (f *FollowRepository) Delete(followerId uuid.UUID, followingId uuid.UUID) e
 	return nil
 }
 
+func (f *FollowRepository) IsFollowing(followerId uuid.UUID, followingId uuid.UUID) (bool, error) {
+	var following bool
+
+	err := f.DB.QueryRow("SELECT EXISTS(SELECT 1 FROM follows WHERE follower_id = $1 AND following_id = $2)",
+		followerId, followingId).Scan(&following)
+
+	return following, err
+}
+
 func (f *FollowRepository) GetAllFollower(followingId uuid.UUID, page, limit int) (*model.PaginatedResponse[model.FollowResponse], error) {
 	if page < 1 {
 		page = 1
@@ -15,16 +15,19 @@ func NewLikeRepository(db *sql.DB) *LikeRepository {
 	return &LikeRepository{DB: db}
 }
 
+// a like is on a blog or a comment, the other id reads as 0
+const likeColumns = "id, user_id, COALESCE(blog_id, 0), COALESCE(comment_id, 0), like_type, created_at, updated_at"
+
 // insert and update
 func (r *LikeRepository) UpsertCommentLike(userId uuid.UUID, commentId int64, liketype model.LikeType) (*model.Like, error) {
 	var like model.Like
 
-	err := r.DB.QueryRow(`INSERT INTO likes(user_id, comment_id, liketype) VALUES($1, $2, $3)
-		ON CONFLICT(user_id, comment_id) 
+	err := r.DB.QueryRow(`INSERT INTO likes(user_id, comment_id, like_type) VALUES($1, $2, $3)
+		ON CONFLICT(user_id, comment_id) WHERE comment_id IS NOT NULL
 		DO UPDATE SET
 		like_type = EXCLUDED.like_type,
 		updated_at = CURRENT_TIMESTAMP
-		  RETURNNING *`,
+		RETURNING `+likeColumns,
 		userId, commentId, liketype).Scan(
 		&like.Id, &like.UserId, &like.BlogId, &like.CommentId, &like.LikeType,
 		&like.CreatedAt, &like.UpdatedAt,
@@ -40,12 +43,12 @@ func (r *LikeRepository) UpsertCommentLike(userId uuid.UUID, commentId int64, li
 func (r *LikeRepository) UpsertBlogLike(userId uuid.UUID, blogId int64, liketype model.LikeType) (*model.Like, error) {
 	var like model.Like
 
-	err := r.DB.QueryRow(`INSERT INTO likes(user_id, blog_id, liketype) VALUES($1, $2, $3) 
-			ON CONFLICT(user_id, blog_id)
+	err := r.DB.QueryRow(`INSERT INTO likes(user_id, blog_id, like_type) VALUES($1, $2, $3)
+			ON CONFLICT(user_id, blog_id) WHERE blog_id IS NOT NULL
 			DO UPDATE SET
 			like_type = EXCLUDED.like_type,
 			updated_at = CURRENT_TIMESTAMP
-			RETURNNING *`,
+			RETURNING `+likeColumns,
 		userId, blogId, liketype).Scan(
 		&like.Id, &like.UserId, &like.BlogId, &like.CommentId, &like.LikeType,
 		&like.CreatedAt, &like.UpdatedAt,
@@ -0,0 +1,166 @@
+package repo
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/harry713j/vibe_writer/internal/model"
+)
+
+type MentionRepository struct {
+	DB *sql.DB
+}
+
+func NewMentionRepository(db *sql.DB) *MentionRepository {
+	return &MentionRepository{DB: db}
+}
+
+func (m *MentionRepository) SyncBlogMentions(authorId uuid.UUID, blogId int64, mentions []model.Mention) error {
+	return m.syncMentions("blog_id", authorId, blogId, mentions)
+}
+
+func (m *MentionRepository) SyncCommentMentions(authorId uuid.UUID, commentId int64, mentions []model.Mention) error {
+	return m.syncMentions("comment_id", authorId, commentId, mentions)
+}
+
+// store the users mentioned in the content now. Mentions taken out before anyone was notified
+// are dropped, the notified ones stay so putting them back doesn't notify again
+func (m *MentionRepository) syncMentions(column string, authorId uuid.UUID, contentId int64, mentions []model.Mention) error {
+	tx, err := m.DB.Begin()
+
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	userIds := make([]string, len(mentions))
+
+	for i, mention := range mentions {
+		userIds[i] = mention.UserId.String()
+	}
+
+	_, err = tx.Exec(`DELETE FROM mentions WHERE `+column+` = $1 AND notified_at IS NULL
+		AND NOT (user_id = ANY($2::uuid[]))`, contentId, userIds)
+
+	if err != nil {
+		return err
+	}
+
+	for _, mention := range mentions {
+		_, err := tx.Exec(`INSERT INTO mentions(user_id, author_id, `+column+`, username) VALUES($1, $2, $3, $4)
+			ON CONFLICT (`+column+`, user_id) WHERE `+column+` IS NOT NULL DO NOTHING`,
+			mention.UserId, authorId, contentId, mention.Written)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (m *MentionRepository) GetBlogMentions(blogId int64) ([]model.Mention, error) {
+	mentions, err := m.getMentions("blog_id", []int64{blogId})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return mentions[blogId], nil
+}
+
+// mentions of each of the comments, by comment id
+func (m *MentionRepository) GetCommentMentions(commentIds []int64) (map[int64][]model.Mention, error) {
+	return m.getMentions("comment_id", commentIds)
+}
+
+func (m *MentionRepository) getMentions(column string, contentIds []int64) (map[int64][]model.Mention, error) {
+	mentions := map[int64][]model.Mention{}
+
+	if len(contentIds) == 0 {
+		return mentions, nil
+	}
+
+	rows, err := m.DB.Query(`SELECT m.`+column+`, m.user_id, u.username, m.username
+		FROM mentions m
+		JOIN users u ON u.id = m.user_id
+		WHERE m.`+column+` = ANY($1)`, contentIds)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var contentId int64
+		var mention model.Mention
+
+		if err := rows.Scan(&contentId, &mention.UserId, &mention.Username, &mention.Written); err != nil {
+			return nil, err
+		}
+
+		mentions[contentId] = append(mentions[contentId], mention)
+	}
+
+	return mentions, rows.Err()
+}
+
+// mentions nobody was told about yet in content the mentioned user can read, oldest first.
+// Drafts and hidden comments wait until they can be read
+func (m *MentionRepository) GetPendingMentions(limit int) ([]model.PendingMention, error) {
+	query := `
+		SELECT m.id, m.user_id, mu.email, mu.username, m.author_id, a.username, m.comment_id, b.id, b.title, b.slug,
+			bu.username
+		FROM mentions m
+		JOIN users mu ON mu.id = m.user_id
+		JOIN users a ON a.id = m.author_id
+		LEFT JOIN comments c ON c.id = m.comment_id
+		JOIN blogs b ON b.id = COALESCE(m.blog_id, c.blog_id)
+		JOIN users bu ON bu.id = b.user_id
+		WHERE m.notified_at IS NULL AND ` + reachableBlogCondition + ` AND (c.id IS NULL OR c.hidden_at IS NULL)
+		ORDER BY m.id
+		LIMIT $1
+	`
+
+	rows, err := m.DB.Query(query, limit)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var mentions []model.PendingMention
+
+	for rows.Next() {
+		var mention model.PendingMention
+
+		err := rows.Scan(&mention.Id, &mention.UserId, &mention.Email, &mention.Username, &mention.AuthorId,
+			&mention.AuthorUsername, &mention.CommentId, &mention.BlogId, &mention.BlogTitle, &mention.BlogSlug,
+			&mention.BlogUsername)
+
+		if err != nil {
+			return nil, err
+		}
+
+		mentions = append(mentions, mention)
+	}
+
+	return mentions, rows.Err()
+}
+
+// take the mention for notifying, false when it was already taken
+func (m *MentionRepository) ClaimMention(id int64, now time.Time) (bool, error) {
+	result, err := m.DB.Exec("UPDATE mentions SET notified_at = $2 WHERE id = $1 AND notified_at IS NULL", id, now)
+
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+
+	return affected > 0, err
+}
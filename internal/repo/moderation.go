@@ -0,0 +1,166 @@
+package repo
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/harry713j/vibe_writer/internal/model"
+)
+
+// moderator and admin actions, each one is written to the audit log in the same transaction
+type ModerationRepository struct {
+	DB *sql.DB
+}
+
+func NewModerationRepository(db *sql.DB) *ModerationRepository {
+	return &ModerationRepository{DB: db}
+}
+
+// hide or show a blog, false when it doesn't exist
+func (m *ModerationRepository) SetBlogHidden(actorId uuid.UUID, blogId int64, hidden bool, reason string) (bool, error) {
+	action := model.AUDIT_BLOG_UNHIDE
+	query := "UPDATE blogs SET hidden_at = NULL WHERE id = $1"
+
+	if hidden {
+		action = model.AUDIT_BLOG_HIDE
+		query = "UPDATE blogs SET hidden_at = COALESCE(hidden_at, CURRENT_TIMESTAMP) WHERE id = $1"
+	}
+
+	return m.audited(actorId, action, "blog", fmt.Sprint(blogId), reason, "", query, blogId)
+}
+
+// hide or show a comment, false when it doesn't exist
+func (m *ModerationRepository) SetCommentHidden(actorId uuid.UUID, commentId int64, hidden bool, reason string) (bool, error) {
+	action := model.AUDIT_COMMENT_UNHIDE
+	query := "UPDATE comments SET hidden_at = NULL, hidden_by_author = FALSE WHERE id = $1"
+
+	// a moderator's hide can't be taken back by the blog author
+	if hidden {
+		action = model.AUDIT_COMMENT_HIDE
+		query = "UPDATE comments SET hidden_at = COALESCE(hidden_at, CURRENT_TIMESTAMP), hidden_by_author = FALSE WHERE id = $1"
+	}
+
+	return m.audited(actorId, action, "comment", fmt.Sprint(commentId), reason, "", query, commentId)
+}
+
+// suspend or reinstate a user, false when it doesn't exist
+func (m *ModerationRepository) SetUserSuspended(actorId, userId uuid.UUID, suspended bool, reason string) (bool, error) {
+	action := model.AUDIT_USER_UNSUSPEND
+	query := "UPDATE users SET suspended_at = NULL WHERE id = $1"
+
+	if suspended {
+		action = model.AUDIT_USER_SUSPEND
+		query = "UPDATE users SET suspended_at = COALESCE(suspended_at, CURRENT_TIMESTAMP) WHERE id = $1"
+	}
+
+	return m.audited(actorId, action, "user", userId.String(), reason, "", query, userId)
+}
+
+// give the user another role, false when it doesn't exist
+func (m *ModerationRepository) SetUserRole(actorId uuid.UUID, user *model.User, role model.Role, reason string) (bool, error) {
+	details := fmt.Sprintf("%s -> %s", user.Role, role)
+	query := "UPDATE users SET role = $2, updated_at = CURRENT_TIMESTAMP WHERE id = $1"
+
+	return m.audited(actorId, model.AUDIT_USER_ROLE, "user", user.Id.String(), reason, details, query, user.Id, role)
+}
+
+// latest entries first
+func (m *ModerationRepository) GetAuditLogs(page, limit int) (*model.PaginatedResponse[model.AuditLog], error) {
+	if page < 1 {
+		page = 1
+	}
+
+	if limit <= 0 {
+		limit = 20
+	}
+
+	offset := (page - 1) * limit
+
+	query := `
+		SELECT a.id, a.actor_id, COALESCE(u.username, ''), a.action, a.target_type, a.target_id, a.reason, a.details,
+			a.created_at
+		FROM audit_logs a
+		LEFT JOIN users u ON u.id = a.actor_id
+		ORDER BY a.created_at DESC, a.id DESC
+		LIMIT $1 OFFSET $2
+	`
+
+	rows, err := m.DB.Query(query, limit, offset)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var logs []model.AuditLog
+
+	for rows.Next() {
+		var log model.AuditLog
+
+		err := rows.Scan(&log.Id, &log.ActorId, &log.ActorUsername, &log.Action, &log.TargetType, &log.TargetId,
+			&log.Reason, &log.Details, &log.CreatedAt)
+
+		if err != nil {
+			return nil, err
+		}
+
+		logs = append(logs, log)
+	}
+
+	var total int
+
+	if err := m.DB.QueryRow("SELECT COUNT(*) FROM audit_logs").Scan(&total); err != nil {
+		return nil, err
+	}
+
+	totalPages := (total + limit - 1) / limit
+
+	return &model.PaginatedResponse[model.AuditLog]{
+		Data: logs,
+		Meta: model.PageMeta{
+			Total: total,
+			Page:  page,
+			Limit: limit,
+			Pages: totalPages,
+		},
+	}, nil
+}
+
+// run the change and record it, nothing is logged when the target is missing
+func (m *ModerationRepository) audited(actorId uuid.UUID, action model.AuditAction, targetType, targetId, reason, details string,
+	query string, args ...any) (bool, error) {
+	tx, err := m.DB.Begin()
+
+	if err != nil {
+		return false, err
+	}
+
+	defer tx.Rollback()
+
+	result, err := tx.Exec(query, args...)
+
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+
+	if err != nil {
+		return false, err
+	}
+
+	if affected == 0 {
+		return false, nil
+	}
+
+	_, err = tx.Exec(`INSERT INTO audit_logs(actor_id, action, target_type, target_id, reason, details)
+		VALUES($1, $2, $3, $4, $5, $6)`, actorId, action, targetType, targetId, reason, details)
+
+	if err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit()
+}
@@ -0,0 +1,249 @@
+package repo
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/harry713j/vibe_writer/internal/model"
+)
+
+type NotificationRepository struct {
+	DB *sql.DB
+}
+
+func NewNotificationRepository(db *sql.DB) *NotificationRepository {
+	return &NotificationRepository{DB: db}
+}
+
+// add the actor to the unread notification of the same kind about the same thing, or start one.
+// An actor already in it doesn't count again
+func (n *NotificationRepository) AddNotification(userId, actorId uuid.UUID, kind model.NotificationType, blogId,
+	commentId *int64, now time.Time) error {
+	tx, err := n.DB.Begin()
+
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	var notificationId int64
+
+	err = tx.QueryRow(`INSERT INTO notifications(user_id, type, blog_id, comment_id, created_at, updated_at)
+		VALUES($1, $2, $3, $4, $5, $5)
+		ON CONFLICT (user_id, type, COALESCE(blog_id, 0), COALESCE(comment_id, 0)) WHERE read_at IS NULL
+		DO UPDATE SET updated_at = notifications.updated_at
+		RETURNING id`, userId, kind, blogId, commentId, now).Scan(&notificationId)
+
+	if err != nil {
+		return err
+	}
+
+	result, err := tx.Exec(`INSERT INTO notification_actors(notification_id, actor_id, created_at) VALUES($1, $2, $3)
+		ON CONFLICT (notification_id, actor_id) DO NOTHING`, notificationId, actorId, now)
+
+	if err != nil {
+		return err
+	}
+
+	// the actor is already counted, nothing changed
+	if added, err := result.RowsAffected(); err != nil || added == 0 {
+		return err
+	}
+
+	_, err = tx.Exec("UPDATE notifications SET actor_count = actor_count + 1, updated_at = $2 WHERE id = $1",
+		notificationId, now)
+
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// notifications of the user latest activity first, the page starts after the cursor when it's given.
+// A group that gains an actor moves back to the top. If that happens while the user pages, the group
+// lands before the cursor and only shows on the first page again. That's the cost of keeping it on top
+func (n *NotificationRepository) GetNotifications(userId uuid.UUID, cursorTime *time.Time, cursorId int64,
+	limit int) ([]model.Notification, error) {
+	query := `
+		SELECT n.id, n.type, n.actor_count, n.comment_id, n.read_at, n.created_at, n.updated_at,
+			b.id, b.title, b.slug, bu.username
+		FROM notifications n
+		LEFT JOIN blogs b ON b.id = n.blog_id
+		LEFT JOIN users bu ON bu.id = b.user_id
+		WHERE n.user_id = $1 AND n.actor_count > 0
+			AND ($2::timestamp IS NULL OR (n.updated_at, n.id) < ($2, $3))
+		ORDER BY n.updated_at DESC, n.id DESC
+		LIMIT $4
+	`
+
+	rows, err := n.DB.Query(query, userId, cursorTime, cursorId, limit)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var notifications []model.Notification
+
+	for rows.Next() {
+		var notification model.Notification
+		var blogId *int64
+		var blogTitle, blogSlug, blogUsername *string
+
+		err := rows.Scan(&notification.Id, &notification.Type, &notification.ActorCount, &notification.CommentId,
+			&notification.ReadAt, &notification.CreatedAt, &notification.UpdatedAt,
+			&blogId, &blogTitle, &blogSlug, &blogUsername)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if blogId != nil {
+			notification.Blog = &model.NotificationBlog{
+				Id:             *blogId,
+				Title:          *blogTitle,
+				Slug:           *blogSlug,
+				AuthorUsername: *blogUsername,
+			}
+		}
+
+		notification.Read = notification.ReadAt != nil
+
+		notifications = append(notifications, notification)
+	}
+
+	return notifications, rows.Err()
+}
+
+// the latest perNotification actors of every given notification, by notification id
+func (n *NotificationRepository) GetNotificationActors(notificationIds []int64,
+	perNotification int) (map[int64][]model.NotificationActor, error) {
+	actors := map[int64][]model.NotificationActor{}
+
+	if len(notificationIds) == 0 {
+		return actors, nil
+	}
+
+	query := `
+		SELECT na.notification_id, u.id, u.username, COALESCE(up.avatar_url, '')
+		FROM (
+			SELECT *, ROW_NUMBER() OVER (PARTITION BY notification_id ORDER BY created_at DESC) AS position
+			FROM notification_actors
+			WHERE notification_id = ANY($1)
+		) na
+		JOIN users u ON u.id = na.actor_id
+		LEFT JOIN user_profiles up ON up.user_id = na.actor_id
+		WHERE na.position <= $2
+		ORDER BY na.notification_id, na.position
+	`
+
+	rows, err := n.DB.Query(query, notificationIds, perNotification)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var notificationId int64
+		var actor model.NotificationActor
+
+		if err := rows.Scan(&notificationId, &actor.Id, &actor.Username, &actor.Avatar); err != nil {
+			return nil, err
+		}
+
+		actors[notificationId] = append(actors[notificationId], actor)
+	}
+
+	return actors, rows.Err()
+}
+
+func (n *NotificationRepository) CountUnread(userId uuid.UUID) (int, error) {
+	var count int
+
+	err := n.DB.QueryRow("SELECT COUNT(*) FROM notifications WHERE user_id = $1 AND read_at IS NULL AND actor_count > 0",
+		userId).Scan(&count)
+
+	return count, err
+}
+
+// false when the user has no such notification
+func (n *NotificationRepository) MarkRead(userId uuid.UUID, notificationId int64, now time.Time) (bool, error) {
+	result, err := n.DB.Exec("UPDATE notifications SET read_at = COALESCE(read_at, $3) WHERE id = $1 AND user_id = $2",
+		notificationId, userId, now)
+
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+
+	return affected > 0, err
+}
+
+func (n *NotificationRepository) MarkAllRead(userId uuid.UUID, now time.Time) error {
+	_, err := n.DB.Exec("UPDATE notifications SET read_at = $2 WHERE user_id = $1 AND read_at IS NULL", userId, now)
+
+	return err
+}
+
+// the types the user set, the others are on
+func (n *NotificationRepository) GetPreferences(userId uuid.UUID) (map[model.NotificationType]bool, error) {
+	rows, err := n.DB.Query("SELECT type, enabled FROM notification_preferences WHERE user_id = $1", userId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	preferences := map[model.NotificationType]bool{}
+
+	for rows.Next() {
+		var kind model.NotificationType
+		var enabled bool
+
+		if err := rows.Scan(&kind, &enabled); err != nil {
+			return nil, err
+		}
+
+		preferences[kind] = enabled
+	}
+
+	return preferences, rows.Err()
+}
+
+func (n *NotificationRepository) SetPreferences(userId uuid.UUID, preferences map[model.NotificationType]bool) error {
+	tx, err := n.DB.Begin()
+
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	for kind, enabled := range preferences {
+		_, err := tx.Exec(`INSERT INTO notification_preferences(user_id, type, enabled) VALUES($1, $2, $3)
+			ON CONFLICT (user_id, type) DO UPDATE SET enabled = EXCLUDED.enabled`, userId, kind, enabled)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (n *NotificationRepository) IsEnabled(userId uuid.UUID, kind model.NotificationType) (bool, error) {
+	var enabled bool
+
+	err := n.DB.QueryRow(`SELECT COALESCE((SELECT enabled FROM notification_preferences WHERE user_id = $1 AND type = $2),
+		TRUE)`, userId, kind).Scan(&enabled)
+
+	return enabled, err
+}
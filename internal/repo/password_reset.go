@@ -0,0 +1,71 @@
+package repo
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/harry713j/vibe_writer/internal/model"
+)
+
+type PasswordResetRepository struct {
+	DB *sql.DB
+}
+
+func NewPasswordResetRepository(db *sql.DB) *PasswordResetRepository {
+	return &PasswordResetRepository{DB: db}
+}
+
+func (r *PasswordResetRepository) CreateReset(userId uuid.UUID, tokenHash string, expireAt time.Time) error {
+	_, err := r.DB.Exec("INSERT INTO password_resets(user_id, token_hash, expire_at) VALUES($1, $2, $3)",
+		userId, tokenHash, expireAt)
+
+	return err
+}
+
+// most recently requested reset of the user
+func (r *PasswordResetRepository) GetLatestReset(userId uuid.UUID) (*model.PasswordReset, error) {
+	var reset model.PasswordReset
+
+	query := `
+		SELECT id, user_id, expire_at, used_at, created_at
+		FROM password_resets
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+		LIMIT 1
+	`
+
+	err := r.DB.QueryRow(query, userId).Scan(&reset.Id, &reset.UserId, &reset.ExpireAt, &reset.UsedAt, &reset.CreatedAt)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &reset, nil
+}
+
+// consume an unused, unexpired reset, a second use finds no row
+func (r *PasswordResetRepository) UseReset(tokenHash string, now time.Time) (*model.PasswordReset, error) {
+	var reset model.PasswordReset
+
+	query := `
+		UPDATE password_resets SET used_at = $2
+		WHERE token_hash = $1 AND used_at IS NULL AND expire_at > $2
+		RETURNING id, user_id, expire_at, used_at, created_at
+	`
+
+	err := r.DB.QueryRow(query, tokenHash, now).Scan(&reset.Id, &reset.UserId, &reset.ExpireAt, &reset.UsedAt, &reset.CreatedAt)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &reset, nil
+}
+
+// drop the links that were never used, so only the newest one works
+func (r *PasswordResetRepository) DeleteUnusedResets(userId uuid.UUID) error {
+	_, err := r.DB.Exec("DELETE FROM password_resets WHERE user_id = $1 AND used_at IS NULL", userId)
+
+	return err
+}
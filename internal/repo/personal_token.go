@@ -0,0 +1,110 @@
+package repo
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/harry713j/vibe_writer/internal/model"
+)
+
+type PersonalTokenRepository struct {
+	DB *sql.DB
+}
+
+func NewPersonalTokenRepository(db *sql.DB) *PersonalTokenRepository {
+	return &PersonalTokenRepository{DB: db}
+}
+
+const personalTokenColumns = "id, user_id, name, token_hash, token_prefix, scopes, expire_at, last_used_at, created_at"
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanPersonalToken(row rowScanner) (*model.PersonalAccessToken, error) {
+	var token model.PersonalAccessToken
+	var scopes []string
+
+	err := row.Scan(&token.Id, &token.UserId, &token.Name, &token.TokenHash, &token.TokenPrefix, textArray(&scopes),
+		&token.ExpireAt, &token.LastUsedAt, &token.CreatedAt)
+
+	if err != nil {
+		return nil, err
+	}
+
+	token.Scopes = make([]model.TokenScope, 0, len(scopes))
+
+	for _, scope := range scopes {
+		token.Scopes = append(token.Scopes, model.TokenScope(scope))
+	}
+
+	return &token, nil
+}
+
+func (r *PersonalTokenRepository) CreateToken(userId uuid.UUID, name, tokenHash, tokenPrefix string, scopes []model.TokenScope,
+	expireAt *time.Time) (*model.PersonalAccessToken, error) {
+	scopeNames := make([]string, 0, len(scopes))
+
+	for _, scope := range scopes {
+		scopeNames = append(scopeNames, string(scope))
+	}
+
+	query := `INSERT INTO personal_access_tokens(user_id, name, token_hash, token_prefix, scopes, expire_at)
+		VALUES($1, $2, $3, $4, $5, $6) RETURNING ` + personalTokenColumns
+
+	return scanPersonalToken(r.DB.QueryRow(query, userId, name, tokenHash, tokenPrefix, scopeNames, expireAt))
+}
+
+func (r *PersonalTokenRepository) GetTokenByHash(tokenHash string) (*model.PersonalAccessToken, error) {
+	query := "SELECT " + personalTokenColumns + " FROM personal_access_tokens WHERE token_hash=$1"
+
+	return scanPersonalToken(r.DB.QueryRow(query, tokenHash))
+}
+
+func (r *PersonalTokenRepository) GetTokens(userId uuid.UUID) ([]model.PersonalAccessToken, error) {
+	query := "SELECT " + personalTokenColumns + " FROM personal_access_tokens WHERE user_id=$1 ORDER BY created_at DESC"
+
+	rows, err := r.DB.Query(query, userId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var tokens []model.PersonalAccessToken
+
+	for rows.Next() {
+		token, err := scanPersonalToken(rows)
+
+		if err != nil {
+			return nil, err
+		}
+
+		tokens = append(tokens, *token)
+	}
+
+	return tokens, rows.Err()
+}
+
+// scripts can call many times a second, the last use is only written once a minute
+func (r *PersonalTokenRepository) TouchToken(id int64, now time.Time) error {
+	_, err := r.DB.Exec(`UPDATE personal_access_tokens SET last_used_at=$2
+		WHERE id=$1 AND (last_used_at IS NULL OR last_used_at < $2 - INTERVAL '1 minute')`, id, now)
+
+	return err
+}
+
+// false when the user has no such token
+func (r *PersonalTokenRepository) DeleteToken(userId uuid.UUID, id int64) (bool, error) {
+	result, err := r.DB.Exec("DELETE FROM personal_access_tokens WHERE user_id=$1 AND id=$2", userId, id)
+
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+
+	return affected > 0, err
+}
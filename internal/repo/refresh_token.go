@@ -19,16 +19,19 @@ func NewRefreshTokenRepository(db *sql.DB) *RefreshTokenRepository {
 }
 
 // create refresh token
-func (r *RefreshTokenRepository) CreateRefreshToken(userId uuid.UUID) (*model.RefreshToken, error) {
+func (r *RefreshTokenRepository) CreateRefreshToken(userId, familyId uuid.UUID, tokenHash string) (*model.RefreshToken, error) {
 	token := &model.RefreshToken{
 		UserId:    userId,
-		Token:     uuid.New(),
+		FamilyId:  familyId,
+		TokenHash: tokenHash,
 		CreatedAt: time.Now(),
 		ExpireAt:  time.Now().Add(time.Hour * 24 * 7),
 	}
 
-	_, err := r.DB.Exec("INSERT INTO refresh_tokens(user_id, token, expire_at, created_at) VALUES($1, $2, $3, $4)",
-		token.UserId, token.Token, token.ExpireAt, token.CreatedAt)
+	err := r.DB.QueryRow(`INSERT INTO refresh_tokens(user_id, family_id, token_hash, expire_at, created_at)
+		VALUES($1, $2, $3, $4, $5) RETURNING id`,
+		token.UserId, token.FamilyId, token.TokenHash, token.ExpireAt, token.CreatedAt).Scan(&token.Id)
+
 	if err != nil {
 		return nil, err
 	}
@@ -37,11 +40,18 @@ func (r *RefreshTokenRepository) CreateRefreshToken(userId uuid.UUID) (*model.Re
 }
 
 // get refresh token
-func (r *RefreshTokenRepository) GetRefreshToken(tokenValue uuid.UUID) (*model.RefreshToken, error) {
+func (r *RefreshTokenRepository) GetRefreshToken(tokenHash string) (*model.RefreshToken, error) {
 	var refreshToken model.RefreshToken
 
-	err := r.DB.QueryRow("SELECT * FROM refresh_tokens WHERE token=$1", tokenValue).Scan(
-		&refreshToken.Id, &refreshToken.UserId, &refreshToken.Token, &refreshToken.ExpireAt, &refreshToken.CreatedAt,
+	query := `
+		SELECT id, user_id, family_id, token_hash, expire_at, rotated_at, revoked_at, created_at
+		FROM refresh_tokens
+		WHERE token_hash = $1
+	`
+
+	err := r.DB.QueryRow(query, tokenHash).Scan(
+		&refreshToken.Id, &refreshToken.UserId, &refreshToken.FamilyId, &refreshToken.TokenHash,
+		&refreshToken.ExpireAt, &refreshToken.RotatedAt, &refreshToken.RevokedAt, &refreshToken.CreatedAt,
 	)
 
 	if err != nil {
@@ -51,6 +61,31 @@ func (r *RefreshTokenRepository) GetRefreshToken(tokenValue uuid.UUID) (*model.R
 	return &refreshToken, nil
 }
 
+// mark the token as replaced, false when it was already rotated or revoked by someone else
+func (r *RefreshTokenRepository) RotateRefreshToken(id int64, now time.Time) (bool, error) {
+	result, err := r.DB.Exec(`UPDATE refresh_tokens SET rotated_at = $2
+		WHERE id = $1 AND rotated_at IS NULL AND revoked_at IS NULL`, id, now)
+
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+
+	if err != nil {
+		return false, err
+	}
+
+	return affected == 1, nil
+}
+
+// expired tokens are useless, even for reuse detection
+func (r *RefreshTokenRepository) DeleteExpiredRefreshTokens(userId uuid.UUID, now time.Time) error {
+	_, err := r.DB.Exec("DELETE FROM refresh_tokens WHERE user_id=$1 AND expire_at < $2", userId, now)
+
+	return err
+}
+
 // delete refresh token
 func (r *RefreshTokenRepository) DeleteRefreshToken(userId uuid.UUID) error {
 	_, err := r.DB.Exec("DELETE FROM refresh_tokens WHERE user_id=$1", userId)
@@ -0,0 +1,134 @@
+package repo
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/harry713j/vibe_writer/internal/model"
+)
+
+type SessionRepository struct {
+	DB *sql.DB
+}
+
+func NewSessionRepository(db *sql.DB) *SessionRepository {
+	return &SessionRepository{DB: db}
+}
+
+func (r *SessionRepository) CreateSession(sessionId, userId uuid.UUID, userAgent, ipAddress string, now time.Time) error {
+	_, err := r.DB.Exec(`INSERT INTO sessions(id, user_id, user_agent, ip_address, created_at, last_used_at)
+		VALUES($1, $2, $3, $4, $5, $5)`, sessionId, userId, userAgent, ipAddress, now)
+
+	return err
+}
+
+// remember where and when the session was used last
+func (r *SessionRepository) TouchSession(sessionId uuid.UUID, userAgent, ipAddress string, now time.Time) error {
+	_, err := r.DB.Exec("UPDATE sessions SET user_agent=$2, ip_address=$3, last_used_at=$4 WHERE id=$1",
+		sessionId, userAgent, ipAddress, now)
+
+	return err
+}
+
+// when the session was started by a login, revoked sessions are not found
+func (r *SessionRepository) GetSessionCreatedAt(userId, sessionId uuid.UUID) (time.Time, error) {
+	var createdAt time.Time
+
+	err := r.DB.QueryRow("SELECT created_at FROM sessions WHERE id=$1 AND user_id=$2 AND revoked_at IS NULL",
+		sessionId, userId).Scan(&createdAt)
+
+	return createdAt, err
+}
+
+// sessions that still hold a usable refresh token, most recently used first
+func (r *SessionRepository) GetActiveSessions(userId uuid.UUID, now time.Time) ([]model.Session, error) {
+	query := `
+		SELECT s.id, s.user_id, s.user_agent, s.ip_address, s.created_at, s.last_used_at, s.revoked_at
+		FROM sessions s
+		WHERE s.user_id = $1 AND s.revoked_at IS NULL AND EXISTS (
+			SELECT 1 FROM refresh_tokens rt
+			WHERE rt.family_id = s.id AND rt.rotated_at IS NULL AND rt.revoked_at IS NULL AND rt.expire_at > $2
+		)
+		ORDER BY s.last_used_at DESC
+	`
+
+	rows, err := r.DB.Query(query, userId, now)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var sessions []model.Session
+
+	for rows.Next() {
+		var session model.Session
+
+		err := rows.Scan(&session.Id, &session.UserId, &session.UserAgent, &session.IpAddress,
+			&session.CreatedAt, &session.LastUsedAt, &session.RevokedAt)
+
+		if err != nil {
+			return nil, err
+		}
+
+		sessions = append(sessions, session)
+	}
+
+	return sessions, nil
+}
+
+// revoke the session and its refresh tokens, false when the user has no such active session
+func (r *SessionRepository) RevokeSession(userId, sessionId uuid.UUID, now time.Time) (bool, error) {
+	query := `
+		WITH revoked AS (
+			UPDATE sessions SET revoked_at = $3
+			WHERE user_id = $1 AND id = $2 AND revoked_at IS NULL
+			RETURNING id
+		), tokens AS (
+			UPDATE refresh_tokens SET revoked_at = $3
+			WHERE family_id IN (SELECT id FROM revoked) AND revoked_at IS NULL
+		)
+		SELECT COUNT(*) FROM revoked
+	`
+
+	var revoked int
+
+	if err := r.DB.QueryRow(query, userId, sessionId, now).Scan(&revoked); err != nil {
+		return false, err
+	}
+
+	return revoked > 0, nil
+}
+
+// revoke every session of the user except keepId, uuid.Nil revokes all of them
+func (r *SessionRepository) RevokeOtherSessions(userId, keepId uuid.UUID, now time.Time) (int, error) {
+	query := `
+		WITH revoked AS (
+			UPDATE sessions SET revoked_at = $3
+			WHERE user_id = $1 AND id <> $2 AND revoked_at IS NULL
+			RETURNING id
+		), tokens AS (
+			UPDATE refresh_tokens SET revoked_at = $3
+			WHERE family_id IN (SELECT id FROM revoked) AND revoked_at IS NULL
+		)
+		SELECT COUNT(*) FROM revoked
+	`
+
+	var revoked int
+
+	if err := r.DB.QueryRow(query, userId, keepId, now).Scan(&revoked); err != nil {
+		return 0, err
+	}
+
+	return revoked, nil
+}
+
+// sessions whose refresh tokens have all expired and been cleaned up
+func (r *SessionRepository) DeleteStaleSessions(userId uuid.UUID) error {
+	_, err := r.DB.Exec(`DELETE FROM sessions s WHERE s.user_id = $1
+		AND NOT EXISTS (SELECT 1 FROM refresh_tokens rt WHERE rt.family_id = s.id)`, userId)
+
+	return err
+}
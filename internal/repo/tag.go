@@ -0,0 +1,209 @@
+package repo
+
+import (
+	"database/sql"
+
+	"github.com/harry713j/vibe_writer/internal/model"
+)
+
+type TagRepository struct {
+	DB *sql.DB
+}
+
+func NewTagRepository(db *sql.DB) *TagRepository {
+	return &TagRepository{DB: db}
+}
+
+// create the tags that are new and link exactly these to the blog
+func (t *TagRepository) SetBlogTags(blogId int64, tags []model.Tag) error {
+	tx, err := t.DB.Begin()
+
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	tagIds := make([]int64, 0, len(tags))
+
+	for _, tag := range tags {
+		var tagId int64
+
+		err := tx.QueryRow(`
+			INSERT INTO tags(name, slug) VALUES($1, $2)
+			ON CONFLICT(slug) DO UPDATE SET slug = EXCLUDED.slug
+			RETURNING id
+		`, tag.Name, tag.Slug).Scan(&tagId)
+
+		if err != nil {
+			return err
+		}
+
+		tagIds = append(tagIds, tagId)
+	}
+
+	if _, err := tx.Exec("DELETE FROM blog_tags WHERE blog_id = $1 AND NOT (tag_id = ANY($2))", blogId, tagIds); err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO blog_tags(blog_id, tag_id)
+		SELECT $1, tag_id FROM unnest($2::bigint[]) AS tag_id
+		ON CONFLICT(blog_id, tag_id) DO NOTHING
+	`
+
+	if _, err := tx.Exec(query, blogId, tagIds); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (t *TagRepository) GetTagBySlug(slug string) (*model.Tag, error) {
+	var tag model.Tag
+
+	err := t.DB.QueryRow("SELECT id, name, slug FROM tags WHERE slug = $1", slug).Scan(&tag.Id, &tag.Name, &tag.Slug)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &tag, nil
+}
+
+// tags with the number of public blogs using them, most used first
+func (t *TagRepository) GetAllTags(page, limit int) (*model.PaginatedResponse[model.TagWithCount], error) {
+	if page < 1 {
+		page = 1
+	}
+
+	if limit <= 0 {
+		limit = 20
+	}
+
+	offset := (page - 1) * limit
+
+	query := `
+		SELECT
+			t.id,
+			t.name,
+			t.slug,
+			COUNT(b.id) AS post_count
+		FROM tags t
+		JOIN blog_tags bt ON bt.tag_id = t.id
+		JOIN blogs b ON b.id = bt.blog_id AND ` + publicBlogCondition + `
+		GROUP BY t.id
+		ORDER BY post_count DESC, t.slug ASC
+		LIMIT $1 OFFSET $2
+	`
+
+	rows, err := t.DB.Query(query, limit, offset)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var tags []model.TagWithCount
+
+	for rows.Next() {
+		var tag model.TagWithCount
+
+		if err := rows.Scan(&tag.Id, &tag.Name, &tag.Slug, &tag.PostCount); err != nil {
+			return nil, err
+		}
+
+		tags = append(tags, tag)
+	}
+
+	var total int
+	countQuery := `
+		SELECT COUNT(DISTINCT bt.tag_id)
+		FROM blog_tags bt
+		JOIN blogs b ON b.id = bt.blog_id AND ` + publicBlogCondition
+
+	if err := t.DB.QueryRow(countQuery).Scan(&total); err != nil {
+		return nil, err
+	}
+
+	totalPages := (total + limit - 1) / limit
+
+	return &model.PaginatedResponse[model.TagWithCount]{
+		Data: tags,
+		Meta: model.PageMeta{
+			Total: total,
+			Page:  page,
+			Limit: limit,
+			Pages: totalPages,
+		},
+	}, nil
+}
+
+// tags of every given blog keyed by blog id
+func getTagsByBlogIds(db *sql.DB, blogIds []int64) (map[int64][]model.Tag, error) {
+	tags := make(map[int64][]model.Tag)
+
+	if len(blogIds) == 0 {
+		return tags, nil
+	}
+
+	query := `
+		SELECT bt.blog_id, t.id, t.name, t.slug
+		FROM blog_tags bt
+		JOIN tags t ON t.id = bt.tag_id
+		WHERE bt.blog_id = ANY($1)
+		ORDER BY t.slug ASC
+	`
+
+	rows, err := db.Query(query, blogIds)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var blogId int64
+		var tag model.Tag
+
+		if err := rows.Scan(&blogId, &tag.Id, &tag.Name, &tag.Slug); err != nil {
+			return nil, err
+		}
+
+		tags[blogId] = append(tags[blogId], tag)
+	}
+
+	return tags, nil
+}
+
+// fill the tags of blog summaries in a single query
+func attachTags(db *sql.DB, blogs []model.BlogSummary) error {
+	blogIds := make([]int64, 0, len(blogs))
+
+	for _, blog := range blogs {
+		blogIds = append(blogIds, blog.Id)
+	}
+
+	tags, err := getTagsByBlogIds(db, blogIds)
+
+	if err != nil {
+		return err
+	}
+
+	for i := range blogs {
+		blogs[i].Tags = nonNilTags(tags[blogs[i].Id])
+	}
+
+	return nil
+}
+
+// frontend expects an array, not null
+func nonNilTags(tags []model.Tag) []model.Tag {
+	if tags == nil {
+		return []model.Tag{}
+	}
+
+	return tags
+}
@@ -0,0 +1,201 @@
+package repo
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/harry713j/vibe_writer/internal/model"
+)
+
+type TrendingRepository struct {
+	DB *sql.DB
+}
+
+func NewTrendingRepository(db *sql.DB) *TrendingRepository {
+	return &TrendingRepository{DB: db}
+}
+
+// weight of each kind of activity in the trending score
+type TrendingWeights struct {
+	Like     float64
+	Dislike  float64
+	Comment  float64
+	Bookmark float64
+}
+
+// recompute the scores of public blogs from activity after since, every event decays
+// by half for each halfLife that has passed
+func (t *TrendingRepository) RefreshScores(now, since time.Time, halfLife time.Duration, weights TrendingWeights) error {
+	tx, err := t.DB.Begin()
+
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM blog_trending_scores"); err != nil {
+		return err
+	}
+
+	query := `
+		WITH events AS (
+			SELECT
+				l.blog_id,
+				l.like_type::TEXT AS kind,
+				CASE WHEN l.like_type = 'like' THEN $3::FLOAT8 ELSE -$4::FLOAT8 END AS weight,
+				COALESCE(l.updated_at, l.created_at) AS happened_at
+			FROM likes l
+			WHERE l.blog_id IS NOT NULL AND COALESCE(l.updated_at, l.created_at) >= $2
+
+			UNION ALL
+
+			SELECT c.blog_id, 'comment', $5::FLOAT8, c.created_at
+			FROM comments c
+			WHERE c.created_at >= $2 AND c.hidden_at IS NULL
+
+			UNION ALL
+
+			SELECT bm.blog_id, 'bookmark', $6::FLOAT8, bm.created_at
+			FROM bookmarks bm
+			WHERE bm.created_at >= $2
+		)
+		INSERT INTO blog_trending_scores(blog_id, score, likes_count, dislikes_count, comments_count, bookmarks_count, computed_at)
+		SELECT
+			e.blog_id,
+			SUM(e.weight * power(0.5, EXTRACT(EPOCH FROM ($1 - e.happened_at)) / $7::FLOAT8)) AS score,
+			COUNT(*) FILTER (WHERE e.kind = 'like'),
+			COUNT(*) FILTER (WHERE e.kind = 'dislike'),
+			COUNT(*) FILTER (WHERE e.kind = 'comment'),
+			COUNT(*) FILTER (WHERE e.kind = 'bookmark'),
+			$1
+		FROM events e
+		JOIN blogs b ON b.id = e.blog_id
+		WHERE ` + publicBlogCondition + `
+		GROUP BY e.blog_id
+		HAVING SUM(e.weight * power(0.5, EXTRACT(EPOCH FROM ($1 - e.happened_at)) / $7::FLOAT8)) > 0
+	`
+
+	_, err = tx.Exec(query, now, since, weights.Like, weights.Dislike, weights.Comment, weights.Bookmark,
+		halfLife.Seconds())
+
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// highest scored blogs from the last refresh that are still public
+func (t *TrendingRepository) GetTrending(page, limit int) (*model.PaginatedResponse[model.BlogSummary], error) {
+	if page < 1 {
+		page = 1
+	}
+
+	if limit <= 0 {
+		limit = 10
+	}
+
+	offset := (page - 1) * limit
+
+	query := `
+		SELECT
+			b.id,
+			b.title,
+			b.user_id,
+			b.slug,
+			b.content,
+			b.status,
+			b.publish_at,
+			b.created_at,
+			b.updated_at,
+			COALESCE((
+				SELECT bp.photo_url
+				FROM blog_photos bp
+				WHERE bp.blog_id = b.id
+				ORDER BY bp.id ASC
+				LIMIT 1
+			), '') AS blog_thumbnail,
+			(SELECT COUNT(*) FROM likes l WHERE l.blog_id = b.id AND l.like_type = 'like') AS likes_count,
+			(SELECT COUNT(*) FROM likes l WHERE l.blog_id = b.id AND l.like_type = 'dislike') AS dislikes_count,
+			(SELECT COUNT(*) FROM comments c WHERE c.blog_id = b.id AND c.hidden_at IS NULL) AS comments_count,
+			u.username,
+			COALESCE(up.full_name, '') AS author_name,
+			COALESCE(up.avatar_url, '') AS author_avatar,
+			ts.score
+		FROM blog_trending_scores ts
+		JOIN blogs b ON b.id = ts.blog_id
+		JOIN users u ON u.id = b.user_id
+		LEFT JOIN user_profiles up ON up.user_id = b.user_id
+		WHERE ` + publicBlogCondition + `
+		ORDER BY ts.score DESC, b.id DESC
+		LIMIT $1 OFFSET $2
+	`
+
+	rows, err := t.DB.Query(query, limit, offset)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var blogs []model.BlogSummary
+
+	for rows.Next() {
+		blog := model.BlogSummary{}
+
+		err := rows.Scan(
+			&blog.Id,
+			&blog.Title,
+			&blog.UserId,
+			&blog.Slug,
+			&blog.Content,
+			&blog.Status,
+			&blog.PublishAt,
+			&blog.CreatedAt,
+			&blog.UpdatedAt,
+			&blog.Thumbnail,
+			&blog.LikesCount,
+			&blog.DislikeCount,
+			&blog.CommentCount,
+			&blog.AuthorUsername,
+			&blog.AuthorName,
+			&blog.AuthorAvatar,
+			&blog.TrendingScore,
+		)
+
+		if err != nil {
+			return nil, err
+		}
+
+		blogs = append(blogs, blog)
+	}
+
+	if err := attachTags(t.DB, blogs); err != nil {
+		return nil, err
+	}
+
+	var total int
+	countQuery := `
+		SELECT COUNT(*)
+		FROM blog_trending_scores ts
+		JOIN blogs b ON b.id = ts.blog_id
+		WHERE ` + publicBlogCondition
+
+	if err := t.DB.QueryRow(countQuery).Scan(&total); err != nil {
+		return nil, err
+	}
+
+	totalPages := (total + limit - 1) / limit
+
+	return &model.PaginatedResponse[model.BlogSummary]{
+		Data: blogs,
+		Meta: model.PageMeta{
+			Total: total,
+			Page:  page,
+			Limit: limit,
+			Pages: totalPages,
+		},
+	}, nil
+}
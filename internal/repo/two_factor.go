@@ -0,0 +1,170 @@
+package repo
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/harry713j/vibe_writer/internal/model"
+)
+
+type TwoFactorRepository struct {
+	DB *sql.DB
+}
+
+func NewTwoFactorRepository(db *sql.DB) *TwoFactorRepository {
+	return &TwoFactorRepository{DB: db}
+}
+
+// start a new enrollment, a pending one is replaced
+func (r *TwoFactorRepository) UpsertPendingTOTP(userId uuid.UUID, secret string) error {
+	query := `
+		INSERT INTO user_totp(user_id, secret) VALUES($1, $2)
+		ON CONFLICT(user_id) DO UPDATE SET secret = EXCLUDED.secret, last_used_step = NULL,
+			created_at = CURRENT_TIMESTAMP
+		WHERE user_totp.confirmed_at IS NULL
+	`
+
+	_, err := r.DB.Exec(query, userId, secret)
+
+	return err
+}
+
+func (r *TwoFactorRepository) GetTOTP(userId uuid.UUID) (*model.UserTOTP, error) {
+	var totp model.UserTOTP
+
+	err := r.DB.QueryRow("SELECT user_id, secret, confirmed_at, last_used_step, created_at FROM user_totp WHERE user_id = $1",
+		userId).Scan(&totp.UserId, &totp.Secret, &totp.ConfirmedAt, &totp.LastUsedStep, &totp.CreatedAt)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &totp, nil
+}
+
+// accept the step of a code, false when it, or a later one, was used already
+func (r *TwoFactorRepository) UseStep(userId uuid.UUID, step int64) (bool, error) {
+	result, err := r.DB.Exec(`UPDATE user_totp SET last_used_step = $2
+		WHERE user_id = $1 AND (last_used_step IS NULL OR last_used_step < $2)`, userId, step)
+
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+
+	if err != nil {
+		return false, err
+	}
+
+	return affected == 1, nil
+}
+
+// turn 2FA on and replace the recovery codes in one go
+func (r *TwoFactorRepository) ConfirmTOTP(userId uuid.UUID, codeHashes []string, now time.Time) error {
+	tx, err := r.DB.Begin()
+
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("UPDATE user_totp SET confirmed_at = $2 WHERE user_id = $1", userId, now); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec("DELETE FROM recovery_codes WHERE user_id = $1", userId); err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO recovery_codes(user_id, code_hash)
+		SELECT $1, code_hash FROM unnest($2::text[]) AS code_hash
+	`
+
+	if _, err := tx.Exec(query, userId, codeHashes); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// spend a recovery code, false when it doesn't exist or was used
+func (r *TwoFactorRepository) UseRecoveryCode(userId uuid.UUID, codeHash string, now time.Time) (bool, error) {
+	result, err := r.DB.Exec(`UPDATE recovery_codes SET used_at = $3
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL`, userId, codeHash, now)
+
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+
+	if err != nil {
+		return false, err
+	}
+
+	return affected == 1, nil
+}
+
+// store the challenge of a password step, expired ones of the user are cleaned up on the way
+func (r *TwoFactorRepository) CreateMfaChallenge(userId uuid.UUID, tokenHash string, expiresAt time.Time) error {
+	if _, err := r.DB.Exec("DELETE FROM mfa_challenges WHERE user_id = $1 AND expires_at < CURRENT_TIMESTAMP", userId); err != nil {
+		return err
+	}
+
+	_, err := r.DB.Exec("INSERT INTO mfa_challenges(user_id, token_hash, expires_at) VALUES($1, $2, $3)",
+		userId, tokenHash, expiresAt)
+
+	return err
+}
+
+// user of a challenge that hasn't expired, it stays usable until UseMfaChallenge
+func (r *TwoFactorRepository) GetMfaChallengeUser(tokenHash string, now time.Time) (uuid.UUID, error) {
+	var userId uuid.UUID
+
+	err := r.DB.QueryRow("SELECT user_id FROM mfa_challenges WHERE token_hash = $1 AND expires_at > $2",
+		tokenHash, now).Scan(&userId)
+
+	return userId, err
+}
+
+// spend a challenge, false when it was spent already
+func (r *TwoFactorRepository) UseMfaChallenge(tokenHash string) (bool, error) {
+	result, err := r.DB.Exec("DELETE FROM mfa_challenges WHERE token_hash = $1", tokenHash)
+
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+
+	if err != nil {
+		return false, err
+	}
+
+	return affected == 1, nil
+}
+
+// turn 2FA off, recovery codes go with it
+func (r *TwoFactorRepository) DeleteTOTP(userId uuid.UUID) error {
+	tx, err := r.DB.Begin()
+
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM recovery_codes WHERE user_id = $1", userId); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec("DELETE FROM user_totp WHERE user_id = $1", userId); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -16,6 +16,22 @@ func NewUserRepository(db *sql.DB) *UserRepository {
 	return &UserRepository{DB: db}
 }
 
+// columns scanned by scanUser, in order
+const userColumns = "id, username, email, password_hash, created_at, updated_at, email_verified_at, role, suspended_at, delete_at"
+
+func scanUser(row *sql.Row) (*model.User, error) {
+	var user model.User
+
+	err := row.Scan(&user.Id, &user.Username, &user.Email, &user.Password, &user.CreatedAt, &user.UpdatedAt,
+		&user.EmailVerifiedAt, &user.Role, &user.SuspendedAt, &user.DeleteAt)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
 // create user
 func (r *UserRepository) CreateUser(username, email, password string) (*model.User, error) {
 	user := &model.User{
@@ -25,6 +41,7 @@ func (r *UserRepository) CreateUser(username, email, password string) (*model.Us
 		Password:  password,
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
+		Role:      model.ROLE_USER,
 	}
 
 	_, err := r.DB.Exec("INSERT INTO users(id, username, email, password_hash, created_at, updated_at) VALUES($1, $2, $3, $4, $5, $6)",
@@ -39,54 +56,205 @@ func (r *UserRepository) CreateUser(username, email, password string) (*model.Us
 
 // get user by id and username
 func (r *UserRepository) GetUserById(id uuid.UUID) (*model.User, error) {
-	var user model.User
-	err := r.DB.QueryRow("SELECT * FROM users WHERE id=$1", id).Scan(&user.Id, &user.Username, &user.Email, &user.Password, &user.CreatedAt, &user.UpdatedAt)
+	return scanUser(r.DB.QueryRow("SELECT "+userColumns+" FROM users WHERE id=$1", id))
+}
+
+func (r *UserRepository) GetUserByIdentifier(idntifier string) (*model.User, error) {
+	return scanUser(r.DB.QueryRow("SELECT "+userColumns+" FROM users WHERE username=$1 OR email=$2", idntifier, idntifier))
+}
+
+func (r *UserRepository) GetUserByUsername(username string) (*model.User, error) {
+	return scanUser(r.DB.QueryRow("SELECT "+userColumns+" FROM users WHERE username=$1", username))
+}
+
+// ids of the users with these usernames keyed by username, unknown ones are missing
+func (r *UserRepository) GetUserIdsByUsernames(usernames []string) (map[string]uuid.UUID, error) {
+	rows, err := r.DB.Query("SELECT id, username FROM users WHERE username = ANY($1)", usernames)
 
 	if err != nil {
 		return nil, err
 	}
 
-	return &user, nil
+	defer rows.Close()
+
+	userIds := make(map[string]uuid.UUID, len(usernames))
+
+	for rows.Next() {
+		var userId uuid.UUID
+		var username string
+
+		if err := rows.Scan(&userId, &username); err != nil {
+			return nil, err
+		}
+
+		userIds[username] = userId
+	}
+
+	return userIds, rows.Err()
 }
 
-func (r *UserRepository) GetUserByIdentifier(idntifier string) (*model.User, error) {
-	var user model.User
-	err := r.DB.QueryRow("SELECT * FROM users WHERE username=$1 OR email=$2", idntifier, idntifier).Scan(
-		&user.Id, &user.Username, &user.Email, &user.Password, &user.CreatedAt, &user.UpdatedAt,
-	)
+func (r *UserRepository) GetUserByEmail(email string) (*model.User, error) {
+	return scanUser(r.DB.QueryRow("SELECT "+userColumns+" FROM users WHERE email=$1", email))
+}
+
+// mark the email verified, only while it is still the address of the user
+func (r *UserRepository) MarkEmailVerified(userId uuid.UUID, email string) error {
+	result, err := r.DB.Exec(`UPDATE users SET email_verified_at=CURRENT_TIMESTAMP, updated_at=CURRENT_TIMESTAMP
+		WHERE id=$1 AND email=$2`, userId, email)
 
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	return &user, nil
+	if affected, err := result.RowsAffected(); err != nil || affected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
 }
 
-func (r *UserRepository) GetUserByUsername(username string) (*model.User, error) {
-	var user model.User
-	err := r.DB.QueryRow("SELCT * FROM users WHERE username=$1", username).Scan(&user)
+func (r *UserRepository) UpdatePassword(userId uuid.UUID, passwordHash string) error {
+	_, err := r.DB.Exec("UPDATE users SET password_hash=$1, updated_at=CURRENT_TIMESTAMP WHERE id=$2", passwordHash, userId)
+
+	return err
+}
+
+// rename the user and reserve the old username until holdUntil. A username the user gave up
+// before is theirs to take back
+func (r *UserRepository) ChangeUsername(userId uuid.UUID, oldUsername, newUsername string, now, holdUntil time.Time) error {
+	tx, err := r.DB.Begin()
 
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	return &user, nil
+	defer tx.Rollback()
+
+	result, err := tx.Exec("UPDATE users SET username=$3, updated_at=$4 WHERE id=$1 AND username=$2",
+		userId, oldUsername, newUsername, now)
+
+	if err != nil {
+		return err
+	}
+
+	// someone else renamed the user meanwhile
+	if affected, err := result.RowsAffected(); err != nil || affected == 0 {
+		return sql.ErrNoRows
+	}
+
+	if _, err := tx.Exec("DELETE FROM username_history WHERE user_id=$1 AND username=$2", userId, newUsername); err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("INSERT INTO username_history(user_id, username, released_at, hold_until) VALUES($1, $2, $3, $4)",
+		userId, oldUsername, now, holdUntil)
+
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
-func (r *UserRepository) GetUserByEmail(email string) (*model.User, error) {
-	var user model.User
-	err := r.DB.QueryRow("SELCT * FROM users WHERE email=$1", email).Scan(&user.Id, &user.Username, &user.Email, &user.Password, &user.CreatedAt, &user.UpdatedAt)
+// whether another user gave up the username recently enough that it's still reserved
+func (r *UserRepository) IsUsernameHeld(username string, exceptUserId uuid.UUID, now time.Time) (bool, error) {
+	var held bool
+
+	err := r.DB.QueryRow(`SELECT EXISTS(
+		SELECT 1 FROM username_history WHERE username=$1 AND user_id<>$2 AND hold_until > $3
+	)`, username, exceptUserId, now).Scan(&held)
+
+	return held, err
+}
+
+// current username of whoever gave up username while it's still held and not in use
+func (r *UserRepository) GetRenamedUsername(username string, now time.Time) (string, error) {
+	var current string
+
+	query := `
+		SELECT u.username
+		FROM username_history h
+		JOIN users u ON u.id = h.user_id
+		WHERE h.username = $1 AND h.hold_until > $2
+			AND NOT EXISTS(SELECT 1 FROM users taken WHERE taken.username = $1)
+		ORDER BY h.released_at DESC
+		LIMIT 1
+	`
+
+	err := r.DB.QueryRow(query, username, now).Scan(&current)
+
+	return current, err
+}
+
+// when the user last gave up a username, nil when they never did
+func (r *UserRepository) GetLastUsernameChange(userId uuid.UUID) (*time.Time, error) {
+	var releasedAt *time.Time
+
+	err := r.DB.QueryRow("SELECT MAX(released_at) FROM username_history WHERE user_id=$1", userId).Scan(&releasedAt)
+
+	return releasedAt, err
+}
+
+func (r *UserRepository) ScheduleDeletion(userId uuid.UUID, deleteAt time.Time) error {
+	_, err := r.DB.Exec("UPDATE users SET delete_at=$2 WHERE id=$1", userId, deleteAt)
+
+	return err
+}
+
+// false when no deletion was scheduled
+func (r *UserRepository) CancelDeletion(userId uuid.UUID) (bool, error) {
+	result, err := r.DB.Exec("UPDATE users SET delete_at=NULL WHERE id=$1 AND delete_at IS NOT NULL", userId)
+
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+
+	return affected > 0, err
+}
+
+// accounts whose grace period is over
+func (r *UserRepository) GetDueDeletions(now time.Time, limit int) ([]uuid.UUID, error) {
+	rows, err := r.DB.Query("SELECT id FROM users WHERE delete_at <= $1 ORDER BY delete_at LIMIT $2", now, limit)
 
 	if err != nil {
 		return nil, err
 	}
 
-	return &user, nil
+	defer rows.Close()
+
+	var userIds []uuid.UUID
+
+	for rows.Next() {
+		var userId uuid.UUID
+
+		if err := rows.Scan(&userId); err != nil {
+			return nil, err
+		}
+
+		userIds = append(userIds, userId)
+	}
+
+	return userIds, rows.Err()
+}
+
+// delete the account if its deletion is still due, a login may have cancelled it meanwhile
+func (r *UserRepository) DeleteScheduledUser(userId uuid.UUID, now time.Time) (bool, error) {
+	result, err := r.DB.Exec("DELETE FROM users WHERE id=$1 AND delete_at <= $2", userId, now)
+
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+
+	return affected > 0, err
 }
 
 // delete user
 func (r *UserRepository) DeleteUser(userId uuid.UUID) error {
-	_, err := r.DB.Exec("DELETE FROM users WHERE user_id=$1", userId)
+	_, err := r.DB.Exec("DELETE FROM users WHERE id=$1", userId)
 
 	return err
 }
@@ -0,0 +1,56 @@
+package repo
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/harry713j/vibe_writer/internal/model"
+)
+
+type UserIdentityRepository struct {
+	DB *sql.DB
+}
+
+func NewUserIdentityRepository(db *sql.DB) *UserIdentityRepository {
+	return &UserIdentityRepository{DB: db}
+}
+
+func (r *UserIdentityRepository) GetIdentity(provider, subject string) (*model.UserIdentity, error) {
+	var identity model.UserIdentity
+
+	query := `SELECT id, user_id, provider, subject, email, created_at, last_login_at
+		FROM user_identities WHERE provider=$1 AND subject=$2`
+
+	err := r.DB.QueryRow(query, provider, subject).Scan(&identity.Id, &identity.UserId, &identity.Provider,
+		&identity.Subject, &identity.Email, &identity.CreatedAt, &identity.LastLoginAt)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &identity, nil
+}
+
+func (r *UserIdentityRepository) CreateIdentity(userId uuid.UUID, provider, subject, email string) error {
+	_, err := r.DB.Exec(`INSERT INTO user_identities(user_id, provider, subject, email) VALUES($1, $2, $3, $4)`,
+		userId, provider, subject, email)
+
+	return err
+}
+
+// whether the user can sign in with a provider
+func (r *UserIdentityRepository) HasIdentity(userId uuid.UUID) (bool, error) {
+	var exists bool
+
+	err := r.DB.QueryRow("SELECT EXISTS(SELECT 1 FROM user_identities WHERE user_id=$1)", userId).Scan(&exists)
+
+	return exists, err
+}
+
+// remember the email the provider has now and when the identity was used
+func (r *UserIdentityRepository) TouchIdentity(id int64, email string, now time.Time) error {
+	_, err := r.DB.Exec("UPDATE user_identities SET email=$2, last_login_at=$3 WHERE id=$1", id, email, now)
+
+	return err
+}
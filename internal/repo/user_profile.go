@@ -99,16 +99,18 @@ func (u *UserProfileRepository) GetAllBookmarks(userId uuid.UUID) ([]model.BlogS
 		SELECT
 			b.id,
 			b.title,
+			b.user_id,
 			b.slug,
 			b.content,
-			b.visibility,
-			b.user_id,
+			b.status,
+			b.publish_at,
 			b.created_at,
 			b.updated_at,
 			COALESCE((
 				SELECT bp.photo_url FROM
 				blog_photos bp WHERE bp.blog_id = b.id
 				ORDER BY bp.id ASC
+				LIMIT 1
 			),'') AS blog_thumbnail,
 			COUNT(l.id) FILTER (WHERE like_type = 'like') AS likes_count,
 			COUNT(l.id) FILTER (WHERE like_type = 'dislike') AS dislikes_count,
@@ -117,8 +119,8 @@ func (u *UserProfileRepository) GetAllBookmarks(userId uuid.UUID) ([]model.BlogS
 		FROM bookmarks bm
 		JOIN blogs b ON bm.blog_id = b.id
 		LEFT JOIN likes l ON l.blog_id = b.id
-		LEFT JOIN comments c ON c.blog_id = b.id
-		WHERE bm.user_id = $1
+		LEFT JOIN comments c ON c.blog_id = b.id AND c.hidden_at IS NULL
+		WHERE bm.user_id = $1 AND ` + reachableBlogCondition + `
 		GROUP BY b.id, bm.id
 		ORDER BY bm.id DESC
 	`
@@ -142,7 +144,8 @@ func (u *UserProfileRepository) GetAllBookmarks(userId uuid.UUID) ([]model.BlogS
 			&blog.UserId,
 			&blog.Slug,
 			&blog.Content,
-			&blog.Visibility,
+			&blog.Status,
+			&blog.PublishAt,
 			&blog.CreatedAt,
 			&blog.UpdatedAt,
 			&blog.Thumbnail,
@@ -158,5 +161,9 @@ func (u *UserProfileRepository) GetAllBookmarks(userId uuid.UUID) ([]model.BlogS
 		blogs = append(blogs, blog)
 	}
 
+	if err := attachTags(u.DB, blogs); err != nil {
+		return nil, err
+	}
+
 	return blogs, nil
 }
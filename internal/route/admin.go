@@ -0,0 +1,35 @@
+package route
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/harry713j/vibe_writer/internal/handler"
+)
+
+// content moderation is open to moderators, account management only to admins
+func AdminRoutes(h *handler.AdminHandler, authMiddleware, moderator, admin func(http.Handler) http.Handler) chi.Router {
+	r := chi.NewRouter()
+
+	r.Use(authMiddleware)
+
+	r.Group(func(r chi.Router) {
+		r.Use(moderator)
+
+		r.Post("/blogs/{blogId}/hide", h.HandleHideBlog)
+		r.Post("/blogs/{blogId}/unhide", h.HandleUnhideBlog)
+		r.Post("/comments/{commentId}/hide", h.HandleHideComment)
+		r.Post("/comments/{commentId}/unhide", h.HandleUnhideComment)
+	})
+
+	r.Group(func(r chi.Router) {
+		r.Use(admin)
+
+		r.Post("/users/{username}/suspend", h.HandleSuspendUser)
+		r.Post("/users/{username}/unsuspend", h.HandleUnsuspendUser)
+		r.Patch("/users/{username}/role", h.HandleChangeRole)
+		r.Get("/audit-logs", h.HandleGetAuditLogs)
+	})
+
+	return r
+}
@@ -7,16 +7,34 @@ import (
 	"github.com/harry713j/vibe_writer/internal/handler"
 )
 
-func AuthRoutes(h *handler.AuthHandler, auth func(http.Handler) http.Handler) chi.Router {
+func AuthRoutes(h *handler.AuthHandler, social *handler.SocialLoginHandler, auth func(http.Handler) http.Handler) chi.Router {
 	r := chi.NewRouter()
 
 	r.Post("/signup", h.HandleSignup)
 	r.Post("/login", h.HandleLogin)
+	r.Post("/2fa/verify", h.HandleVerifyLoginCode)
 	r.Post("/refresh", h.HandleRefreshAccessToken)
+	r.Post("/verify-email", h.HandleVerifyEmail)
+	r.Post("/forgot-password", h.HandleForgotPassword)
+	r.Post("/reset-password", h.HandleResetPassword)
+	r.Get("/oidc/providers", social.HandleGetProviders)
+	r.Get("/oidc/{provider}/login", social.HandleBeginLogin)
+	r.Get("/oidc/{provider}/callback", social.HandleCallback)
 
 	r.Group(func(protected chi.Router) {
 		protected.Use(auth)
 		protected.Get("/logout", h.HandleLogout)
+		protected.Post("/resend-verification", h.HandleResendVerification)
+		protected.Post("/change-password", h.HandleChangePassword)
+		protected.Get("/sessions", h.HandleGetSessions)
+		protected.Delete("/sessions", h.HandleRevokeOtherSessions)
+		protected.Delete("/sessions/{id}", h.HandleRevokeSession)
+		protected.Post("/tokens", h.HandleCreatePersonalToken)
+		protected.Get("/tokens", h.HandleGetPersonalTokens)
+		protected.Delete("/tokens/{id}", h.HandleRevokePersonalToken)
+		protected.Post("/2fa/enroll", h.HandleEnrollTOTP)
+		protected.Post("/2fa/confirm", h.HandleConfirmTOTP)
+		protected.Post("/2fa/disable", h.HandleDisableTOTP)
 	})
 
 	return r
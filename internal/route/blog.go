@@ -5,20 +5,36 @@ import (
 
 	"github.com/go-chi/chi/v5"
 	"github.com/harry713j/vibe_writer/internal/handler"
+	"github.com/harry713j/vibe_writer/internal/model"
 )
 
-func BlogRoutes(h *handler.BlogHandler, auth func(http.Handler) http.Handler) chi.Router {
+func BlogRoutes(h *handler.BlogHandler, auth, verified func(http.Handler) http.Handler,
+	scoped func(model.TokenScope) func(http.Handler) http.Handler) chi.Router {
 	r := chi.NewRouter()
 
+	r.Get("/trending", h.HandleGetTrendingBlogs)
+
+	// personal access tokens can reach these with the right scope, writing needs a verified email
+	read := scoped(model.SCOPE_BLOGS_READ)
+	write := scoped(model.SCOPE_BLOGS_WRITE)
+	comment := scoped(model.SCOPE_COMMENTS_WRITE)
+
+	r.With(write, verified).Post("/", h.HandleCreateBlog)
+	r.With(write, verified).Put("/{slug}", h.HandleUpdateBlog)
+	r.With(write, verified).Delete("/{slug}", h.HandleDeleteBlog)
+	r.With(read).Get("/", h.HandleGetBlogs)
+	r.With(read).Get("/{slug}", h.HandleGetBlog)
+	r.With(write, verified).Patch("/{slug}", h.HandleChangeBlogStatus)
+	r.With(comment, verified).Post("/{slug}/comments", h.HandleCreateComment)
+	r.With(write, verified).Patch("/{slug}/comment-settings", h.HandleUpdateCommentSettings)
+	r.With(read).Get("/{slug}/revisions", h.HandleGetRevisions)
+	r.With(read).Get("/{slug}/revisions/diff", h.HandleDiffRevisions)
+	r.With(read).Get("/{slug}/revisions/{revision}", h.HandleGetRevision)
+	r.With(write, verified).Post("/{slug}/revisions/{revision}/restore", h.HandleRestoreRevision)
+
 	r.Group(func(r chi.Router) {
-		r.Use(auth)
-
-		r.Post("/", h.HandleCreateBlog)
-		r.Put("/{slug}", h.HandleUpdateBlog)
-		r.Delete("/{slug}", h.HandleDeleteBlog)
-		r.Get("/", h.HandleGetBlogs)
-		r.Patch("/{slug}", h.HandleChangeBlogVisibility)
-		r.Post("/{slug}/comments", h.HandleCreateComment)
+		r.Use(auth, verified)
+
 		r.Post("/{slug}/reactions", h.HandleToggleBlogLike)
 		r.Delete("/{slug}/reactions", h.HandleRemoveBlogLike)
 		r.Post("/{slug}/bookmarks", h.HandleCreateBookmark)
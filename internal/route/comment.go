@@ -5,16 +5,32 @@ import (
 
 	"github.com/go-chi/chi/v5"
 	"github.com/harry713j/vibe_writer/internal/handler"
+	"github.com/harry713j/vibe_writer/internal/model"
 )
 
-func CommentRoutes(h *handler.CommentHandler, auth func(http.Handler) http.Handler) chi.Router {
+func CommentRoutes(h *handler.CommentHandler, auth, verified, moderator func(http.Handler) http.Handler,
+	scoped func(model.TokenScope) func(http.Handler) http.Handler) chi.Router {
 	r := chi.NewRouter()
 
+	comment := scoped(model.SCOPE_COMMENTS_WRITE)
+
+	r.With(comment, verified).Patch("/{commentId}", h.HandleEditComment)
+	r.With(comment, verified).Delete("/{commentId}", h.HandleDeleteComment)
+
+	// the blog author moderating the discussion
+	r.Group(func(r chi.Router) {
+		r.Use(comment, verified)
+		r.Post("/{commentId}/hide", h.HandleHideComment)
+		r.Post("/{commentId}/unhide", h.HandleUnhideComment)
+		r.Post("/{commentId}/pin", h.HandlePinComment)
+		r.Delete("/{commentId}/pin", h.HandleUnpinComment)
+	})
+
 	r.Group(func(r chi.Router) {
 		r.Use(auth)
-		r.Delete("/{commentId}", h.HandleDeleteComment)
-		r.Post("/{commentId}/reactions", h.HandleToggleCommentLike)
-		r.Delete("/{comment}/reactions", h.HandleRemoveCommentLike)
+		r.With(verified).Post("/{commentId}/reactions", h.HandleToggleCommentLike)
+		r.With(verified).Delete("/{commentId}/reactions", h.HandleRemoveCommentLike)
+		r.With(moderator).Get("/{commentId}/revisions", h.HandleGetCommentRevisions)
 	})
 
 	return r
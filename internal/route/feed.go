@@ -0,0 +1,17 @@
+package route
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/harry713j/vibe_writer/internal/handler"
+)
+
+func FeedRoutes(h *handler.BlogHandler, auth func(http.Handler) http.Handler) chi.Router {
+	r := chi.NewRouter()
+	r.Use(auth)
+
+	r.Get("/", h.HandleGetFeed)
+
+	return r
+}
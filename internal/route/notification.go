@@ -0,0 +1,21 @@
+package route
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/harry713j/vibe_writer/internal/handler"
+)
+
+func NotificationRoutes(h *handler.NotificationHandler, auth func(http.Handler) http.Handler) chi.Router {
+	r := chi.NewRouter()
+	r.Use(auth)
+
+	r.Get("/", h.HandleGetNotifications)
+	r.Post("/read-all", h.HandleMarkAllRead)
+	r.Get("/preferences", h.HandleGetPreferences)
+	r.Patch("/preferences", h.HandleUpdatePreferences)
+	r.Post("/{notificationId}/read", h.HandleMarkRead)
+
+	return r
+}
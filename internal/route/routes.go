@@ -1,27 +1,45 @@
 package route
 
 import (
+	"net/http"
+
 	chiMiddleware "github.com/go-chi/chi/middleware"
 	"github.com/go-chi/chi/v5"
 	"github.com/harry713j/vibe_writer/internal/app"
 	"github.com/harry713j/vibe_writer/internal/handler"
 	"github.com/harry713j/vibe_writer/internal/middleware"
+	"github.com/harry713j/vibe_writer/internal/model"
 )
 
 func RegisterRoutes(app *app.App) *chi.Mux {
 	r := chi.NewRouter()
 
 	r.Use(chiMiddleware.RequestID)
-	r.Use(chiMiddleware.RealIP)
 	r.Use(chiMiddleware.Logger)
 	r.Use(chiMiddleware.Recoverer)
 
+	scoped := func(scope model.TokenScope) func(http.Handler) http.Handler {
+		return middleware.ScopedAuth(app.AuthService, scope)
+	}
+	verified := middleware.RequireVerifiedEmail(app.AuthService)
+
 	r.Get("/health", handler.HandleHealth)
-	r.Mount("/auth", AuthRoutes(app.AuthHandler, middleware.AuthMiddleware(app.AuthService)))
-	r.Mount("/users", UserProfileRoutes(app.UserProfileHandler, middleware.AuthMiddleware(app.AuthService)))
-	r.Mount("/blogs", BlogRoutes(app.BlogHandler, middleware.AuthMiddleware(app.AuthService)))
-	r.Mount("/comments", CommentRoutes(app.CommentHandler, middleware.AuthMiddleware(app.AuthService)))
-	r.Mount("/uploads", UploadRoutes(app.UploadHandler, middleware.AuthMiddleware(app.AuthService)))
+	r.Get("/.well-known/jwks.json", app.AuthHandler.HandleJWKS)
+	r.Get("/search", app.BlogHandler.HandleSearchBlogs)
+	r.Mount("/auth", AuthRoutes(app.AuthHandler, app.SocialLoginHandler, middleware.AuthMiddleware(app.AuthService)))
+	r.Mount("/users", UserProfileRoutes(app.UserProfileHandler, app.AccountHandler, app.BlogHandler, app.SyndicationHandler,
+		middleware.AuthMiddleware(app.AuthService), verified, middleware.OptionalAuth(app.AuthService),
+		middleware.RedirectRenamedUser(app.AccountService), scoped))
+	r.Mount("/blogs", BlogRoutes(app.BlogHandler, middleware.AuthMiddleware(app.AuthService), verified, scoped))
+	r.Mount("/comments", CommentRoutes(app.CommentHandler, middleware.AuthMiddleware(app.AuthService), verified,
+		middleware.RequireRole(app.AuthService, model.ROLE_MODERATOR, model.ROLE_ADMIN), scoped))
+	r.Mount("/uploads", UploadRoutes(app.UploadHandler, scoped(model.SCOPE_UPLOADS_WRITE), verified))
+	r.Mount("/tags", TagRoutes(app.TagHandler, app.SyndicationHandler))
+	r.Mount("/feed", FeedRoutes(app.BlogHandler, middleware.AuthMiddleware(app.AuthService)))
+	r.Mount("/notifications", NotificationRoutes(app.NotificationHandler, middleware.AuthMiddleware(app.AuthService)))
+	r.Mount("/admin", AdminRoutes(app.AdminHandler, middleware.AuthMiddleware(app.AuthService),
+		middleware.RequireRole(app.AuthService, model.ROLE_MODERATOR, model.ROLE_ADMIN),
+		middleware.RequireRole(app.AuthService, model.ROLE_ADMIN)))
 
 	return r
 }
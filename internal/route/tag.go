@@ -0,0 +1,17 @@
+package route
+
+import (
+	"github.com/go-chi/chi/v5"
+	"github.com/harry713j/vibe_writer/internal/handler"
+)
+
+func TagRoutes(h *handler.TagHandler, feeds *handler.SyndicationHandler) chi.Router {
+	r := chi.NewRouter()
+
+	r.Get("/", h.HandleGetTags)
+	r.Get("/{tag}/blogs", h.HandleGetTagBlogs)
+	r.Get("/{tag}/feed.rss", feeds.HandleTagRSS)
+	r.Get("/{tag}/feed.atom", feeds.HandleTagAtom)
+
+	return r
+}
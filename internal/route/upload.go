@@ -7,9 +7,10 @@ import (
 	"github.com/harry713j/vibe_writer/internal/handler"
 )
 
-func UploadRoutes(h *handler.UploadHandler, auth func(http.Handler) http.Handler) chi.Router {
+func UploadRoutes(h *handler.UploadHandler, auth, verified func(http.Handler) http.Handler) chi.Router {
 	r := chi.NewRouter()
-	r.Use(auth)
+	// auth accepts personal access tokens with the uploads:write scope
+	r.Use(auth, verified)
 
 	r.Post("/avatar", h.HandleUploadAvatar)
 	r.Post("/blog", h.HandleUploadBlogImage)
@@ -5,27 +5,61 @@ import (
 
 	"github.com/go-chi/chi/v5"
 	"github.com/harry713j/vibe_writer/internal/handler"
+	"github.com/harry713j/vibe_writer/internal/model"
 )
 
-func UserProfileRoutes(h *handler.UserProfileHandler, auth func(http.Handler) http.Handler) chi.Router {
+func UserProfileRoutes(h *handler.UserProfileHandler, accounts *handler.AccountHandler, blogs *handler.BlogHandler,
+	feeds *handler.SyndicationHandler, auth, verified, optionalAuth, renamed func(http.Handler) http.Handler,
+	scoped func(model.TokenScope) func(http.Handler) http.Handler) chi.Router {
 	r := chi.NewRouter()
 
 	r.Group(func(r chi.Router) {
 		r.Use(auth)
-		r.Patch("/profile", h.HandleUpdateProfile)
-		r.Patch("/avatar", h.HandleUpdateAvatar)
+		r.With(verified).Patch("/profile", h.HandleUpdateProfile)
+		r.With(verified).Patch("/avatar", h.HandleUpdateAvatar)
 		r.Get("/me", h.HandleGetOwnDetails)
+		r.Delete("/me", accounts.HandleDeleteAccount)
+		r.Patch("/me/username", accounts.HandleChangeUsername)
+		r.Patch("/me/email", accounts.HandleRequestEmailChange)
+		r.Post("/me/export", accounts.HandleRequestExport)
+		r.Get("/me/export/{exportId}", accounts.HandleGetExport)
+		r.Get("/me/export/{exportId}/download", accounts.HandleDownloadExport)
 		r.Delete("/avatar", h.HandleRemoveAvatar)
 		r.Get("/bookmarks", h.HandleGetBookmarks)
-		r.Post("/{username}/follow", h.HandleCreateFollow)
-		r.Delete("/{username}/follow", h.HandleRemoveFollow)
+		r.With(verified).Post("/{username}/follow", h.HandleCreateFollow)
+		r.With(verified).Delete("/{username}/follow", h.HandleRemoveFollow)
 		r.Get("/{username}/followings", h.HandleFetchFollowings)
 		r.Get("/{username}/followers", h.HandleFetchFollowers)
 	})
-	r.Get("/{username}", h.HandleGetUserDetails)
-	r.Get("/{username}/blogs", h.HandleGetAllBlog)
-	r.Get("/{username}/blogs/{slug}", h.HandleGetBlog)
-	r.Get("/{username}/blogs/{slug}/comments", h.HandleGetAllComments)
+	r.Post("/me/email/confirm", accounts.HandleConfirmEmailChange)
+
+	// taking part in the blogs of other authors
+	r.With(scoped(model.SCOPE_COMMENTS_WRITE), verified).Post("/{username}/blogs/{slug}/comments", blogs.HandleCreateComment)
+	r.Group(func(r chi.Router) {
+		r.Use(auth, verified)
+
+		r.Post("/{username}/blogs/{slug}/reactions", blogs.HandleToggleBlogLike)
+		r.Delete("/{username}/blogs/{slug}/reactions", blogs.HandleRemoveBlogLike)
+		r.Post("/{username}/blogs/{slug}/bookmarks", blogs.HandleCreateBookmark)
+		r.Delete("/{username}/blogs/{slug}/bookmarks", blogs.HandleRemoveBookmark)
+	})
+
+	// public pages of a user, an old username redirects to the new one
+	r.Group(func(r chi.Router) {
+		r.Use(renamed)
+		r.Get("/{username}", h.HandleGetUserDetails)
+		r.Get("/{username}/blogs", h.HandleGetAllBlog)
+		r.Get("/{username}/feed.rss", feeds.HandleAuthorRSS)
+		r.Get("/{username}/feed.atom", feeds.HandleAuthorAtom)
+
+		// signed in readers also see the comments hidden from everyone but them
+		r.Group(func(r chi.Router) {
+			r.Use(optionalAuth)
+			r.Get("/{username}/blogs/{slug}", h.HandleGetBlog)
+			r.Get("/{username}/blogs/{slug}/comments", h.HandleGetAllComments)
+			r.Get("/{username}/blogs/{slug}/comments/{commentId}/replies", h.HandleGetCommentReplies)
+		})
+	})
 
 	return r
 }
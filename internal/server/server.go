@@ -3,17 +3,21 @@ package server
 import (
 	"log"
 	"net/http"
+	"net/netip"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/cors"
 	"github.com/harry713j/vibe_writer/internal/app"
+	"github.com/harry713j/vibe_writer/internal/middleware"
 	"github.com/harry713j/vibe_writer/internal/route"
 )
 
 type ServerConfig struct {
-	Port string
+	Port           string
+	TrustedProxies []netip.Prefix // proxies whose X-Forwarded-For is believed, none means the socket peer is the client
 }
 
 func LoadServerConfig() *ServerConfig {
@@ -24,7 +28,31 @@ func LoadServerConfig() *ServerConfig {
 		os.Exit(1)
 	}
 
-	return &ServerConfig{Port: port}
+	var trustedProxies []netip.Prefix
+
+	// TRUSTED_PROXIES=10.0.0.0/8,127.0.0.1 takes networks and single addresses
+	for _, value := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		if value = strings.TrimSpace(value); value == "" {
+			continue
+		}
+
+		prefix, err := netip.ParsePrefix(value)
+
+		if err != nil {
+			addr, addrErr := netip.ParseAddr(value)
+
+			if addrErr != nil {
+				log.Println("Ignoring invalid trusted proxy: ", value)
+				continue
+			}
+
+			prefix = netip.PrefixFrom(addr, addr.BitLen())
+		}
+
+		trustedProxies = append(trustedProxies, prefix)
+	}
+
+	return &ServerConfig{Port: port, TrustedProxies: trustedProxies}
 }
 
 func NewServer(config *ServerConfig, app *app.App) *http.Server {
@@ -32,12 +60,13 @@ func NewServer(config *ServerConfig, app *app.App) *http.Server {
 
 	cors := cors.Handler(cors.Options{
 		AllowedOrigins:   []string{os.Getenv("ALLOWED_ORIGIN")},
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
 		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
 		AllowCredentials: true,
 		MaxAge:           300, // Maximum value for preflight request
 	})
 
+	router.Use(middleware.RealIP(config.TrustedProxies))
 	router.Use(cors)
 	v1Router := route.RegisterRoutes(app)
 	router.Mount("/api/v1", v1Router)
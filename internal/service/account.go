@@ -0,0 +1,474 @@
+package service
+
+import (
+	"archive/zip"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/harry713j/vibe_writer/internal/config"
+	"github.com/harry713j/vibe_writer/internal/mailer"
+	"github.com/harry713j/vibe_writer/internal/model"
+	"github.com/harry713j/vibe_writer/internal/repo"
+)
+
+var (
+	ErrExportNotExists          = errors.New("no export exists with this id")
+	ErrExportNotReady           = errors.New("export is not ready yet")
+	ErrExportGone               = errors.New("export was already downloaded or has expired")
+	ErrAccountDeletionScheduled = errors.New("account is scheduled for deletion, log in to cancel it")
+)
+
+// exports that stay in processing this long were abandoned by a crashed server
+const exportStuckAfter = time.Hour
+
+type AccountService struct {
+	userRepo        *repo.UserRepository
+	profileRepo     *repo.UserProfileRepository
+	accountRepo     *repo.AccountRepository
+	sessionRepo     *repo.SessionRepository
+	emailChangeRepo *repo.EmailChangeRepository
+	mailer          mailer.Mailer
+	site            *config.SiteConfig
+	config          *config.AccountConfig
+	wake            chan struct{}
+}
+
+func NewAccountService(userRepo *repo.UserRepository, profileRepo *repo.UserProfileRepository,
+	accountRepo *repo.AccountRepository, sessionRepo *repo.SessionRepository, emailChangeRepo *repo.EmailChangeRepository,
+	mailer mailer.Mailer,
+	site *config.SiteConfig, config *config.AccountConfig) *AccountService {
+	return &AccountService{
+		userRepo:        userRepo,
+		profileRepo:     profileRepo,
+		accountRepo:     accountRepo,
+		sessionRepo:     sessionRepo,
+		emailChangeRepo: emailChangeRepo,
+		mailer:          mailer,
+		site:            site,
+		config:          config,
+		wake:            make(chan struct{}, 1),
+	}
+}
+
+// queue an export of everything the user has, an export still in the works is returned instead
+func (s *AccountService) RequestExport(userId uuid.UUID) (*model.DataExport, error) {
+	if _, err := s.userRepo.GetUserById(userId); err != nil {
+		return nil, ErrUserNotExists
+	}
+
+	export, err := s.accountRepo.GetOpenExport(userId)
+
+	if err == nil {
+		return export, nil
+	}
+
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	export, err = s.accountRepo.CreateExport(userId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// start on it right away instead of waiting for the next tick
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+
+	return export, nil
+}
+
+func (s *AccountService) GetExport(userId, exportId uuid.UUID) (*model.DataExport, error) {
+	export, err := s.accountRepo.GetExport(userId, exportId)
+
+	if err != nil {
+		return nil, ErrExportNotExists
+	}
+
+	return export, nil
+}
+
+// the archive of a ready export, it can be taken once and is removed when closed
+func (s *AccountService) OpenExport(userId, exportId uuid.UUID) (io.ReadCloser, error) {
+	export, err := s.accountRepo.TakeExport(userId, exportId, time.Now())
+
+	if errors.Is(err, sql.ErrNoRows) {
+		current, err := s.GetExport(userId, exportId)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if current.Status == model.EXPORT_PENDING || current.Status == model.EXPORT_PROCESSING {
+			return nil, ErrExportNotReady
+		}
+
+		return nil, ErrExportGone
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(export.FilePath)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &exportFile{File: file}, nil
+}
+
+type exportFile struct {
+	*os.File
+}
+
+func (f *exportFile) Close() error {
+	err := f.File.Close()
+
+	if removeErr := os.Remove(f.Name()); removeErr != nil {
+		log.Println("Failed to remove export file: ", removeErr)
+	}
+
+	return err
+}
+
+// build queued exports and throw away the ones nobody downloaded
+func (s *AccountService) RunExportWorker(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		s.buildPendingExports()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.wake:
+		case <-ticker.C:
+			s.removeStaleExports()
+		}
+	}
+}
+
+func (s *AccountService) buildPendingExports() {
+	for {
+		export, err := s.accountRepo.ClaimPendingExport()
+
+		if errors.Is(err, sql.ErrNoRows) {
+			return
+		}
+
+		if err != nil {
+			log.Println("Failed to claim data export: ", err)
+			return
+		}
+
+		filePath, err := s.buildExport(export)
+
+		if err != nil {
+			log.Println("Failed to build data export: ", err)
+
+			if err := s.accountRepo.FailExport(export.Id); err != nil {
+				log.Println("Failed to mark data export failed: ", err)
+			}
+
+			continue
+		}
+
+		now := time.Now()
+
+		if err := s.accountRepo.FinishExport(export.Id, filePath, now, now.Add(s.config.ExportTTL)); err != nil {
+			log.Println("Failed to finish data export: ", err)
+			os.Remove(filePath)
+		}
+	}
+}
+
+func (s *AccountService) removeStaleExports() {
+	now := time.Now()
+	exports, err := s.accountRepo.GetStaleExports(now, now.Add(-exportStuckAfter))
+
+	if err != nil {
+		log.Println("Failed to load stale data exports: ", err)
+		return
+	}
+
+	for _, export := range exports {
+		if export.FilePath != "" {
+			if err := os.Remove(export.FilePath); err != nil && !errors.Is(err, os.ErrNotExist) {
+				log.Println("Failed to remove export file: ", err)
+				continue
+			}
+		}
+
+		if err := s.accountRepo.FailExport(export.Id); err != nil {
+			log.Println("Failed to expire data export: ", err)
+		}
+	}
+}
+
+// zip with profile.json, every blog as markdown plus json metadata, and the user's comments,
+// reactions, bookmarks and follows
+func (s *AccountService) buildExport(export *model.DataExport) (string, error) {
+	if err := os.MkdirAll(s.config.ExportDir, 0o700); err != nil {
+		return "", err
+	}
+
+	filePath := filepath.Join(s.config.ExportDir, export.Id.String()+".zip")
+	file, err := os.OpenFile(filePath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+
+	if err != nil {
+		return "", err
+	}
+
+	archive := zip.NewWriter(file)
+	err = s.writeExport(archive, export.UserId)
+
+	if closeErr := archive.Close(); err == nil {
+		err = closeErr
+	}
+
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		os.Remove(filePath)
+		return "", err
+	}
+
+	return filePath, nil
+}
+
+func (s *AccountService) writeExport(archive *zip.Writer, userId uuid.UUID) error {
+	user, err := s.userRepo.GetUserById(userId)
+
+	if err != nil {
+		return err
+	}
+
+	details, err := s.profileRepo.GetUserDetails(userId)
+
+	if err != nil {
+		return err
+	}
+
+	profile := map[string]any{
+		"id":                user.Id,
+		"username":          user.Username,
+		"email":             user.Email,
+		"email_verified_at": user.EmailVerifiedAt,
+		"role":              user.Role,
+		"created_at":        user.CreatedAt,
+		"full_name":         details.FullName,
+		"bio":               details.Bio,
+		"avatar_url":        details.AvatarUrl,
+	}
+
+	if err := writeJSON(archive, "profile.json", profile); err != nil {
+		return err
+	}
+
+	blogs, err := s.accountRepo.GetExportBlogs(userId)
+
+	if err != nil {
+		return err
+	}
+
+	for _, blog := range blogs {
+		markdown, err := archive.Create("blogs/" + blog.Slug + ".md")
+
+		if err != nil {
+			return err
+		}
+
+		if _, err := fmt.Fprintf(markdown, "# %s\n\n%s\n", blog.Title, blog.Content); err != nil {
+			return err
+		}
+
+		if err := writeJSON(archive, "blogs/"+blog.Slug+".json", blog); err != nil {
+			return err
+		}
+	}
+
+	comments, err := s.accountRepo.GetExportComments(userId)
+
+	if err != nil {
+		return err
+	}
+
+	likes, err := s.accountRepo.GetExportLikes(userId)
+
+	if err != nil {
+		return err
+	}
+
+	bookmarks, err := s.accountRepo.GetExportBookmarks(userId)
+
+	if err != nil {
+		return err
+	}
+
+	following, err := s.accountRepo.GetExportFollows(userId, false)
+
+	if err != nil {
+		return err
+	}
+
+	followers, err := s.accountRepo.GetExportFollows(userId, true)
+
+	if err != nil {
+		return err
+	}
+
+	files := map[string]any{
+		"comments.json":  nonNil(comments),
+		"reactions.json": nonNil(likes),
+		"bookmarks.json": nonNil(bookmarks),
+		"following.json": nonNil(following),
+		"followers.json": nonNil(followers),
+	}
+
+	for name, data := range files {
+		if err := writeJSON(archive, name, data); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func writeJSON(archive *zip.Writer, name string, data any) error {
+	file, err := archive.Create(name)
+
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(data)
+}
+
+// empty lists are written as [] rather than null
+func nonNil[T any](items []T) []T {
+	if items == nil {
+		return []T{}
+	}
+
+	return items
+}
+
+// the account is deleted once the grace period is over, until then logging in cancels it.
+// Every device is signed out so the next login is a deliberate one
+func (s *AccountService) ScheduleDeletion(userId uuid.UUID) (time.Time, error) {
+	user, err := s.userRepo.GetUserById(userId)
+
+	if err != nil {
+		return time.Time{}, ErrUserNotExists
+	}
+
+	if user.DeleteAt != nil {
+		return *user.DeleteAt, nil
+	}
+
+	now := time.Now()
+	deleteAt := now.Add(s.config.DeletionGrace)
+
+	if err := s.userRepo.ScheduleDeletion(userId, deleteAt); err != nil {
+		return time.Time{}, err
+	}
+
+	if _, err := s.sessionRepo.RevokeOtherSessions(userId, uuid.Nil, now); err != nil {
+		return time.Time{}, err
+	}
+
+	msg := mailer.Message{
+		To:      user.Email,
+		Subject: "Your account will be deleted",
+		Body: fmt.Sprintf("Hi %s,\n\nYour account and everything you posted will be deleted on %s.\n\n"+
+			"Changed your mind? Log in at %s before then and the deletion is cancelled.\n",
+			user.Username, deleteAt.UTC().Format("Jan 2, 2006 15:04 MST"), s.site.URL),
+	}
+
+	s.sendMail(msg, "account deletion")
+
+	return deleteAt, nil
+}
+
+// delete the accounts whose grace period is over, their cloud images go with them
+func (s *AccountService) RunDeletionWorker(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			userIds, err := s.userRepo.GetDueDeletions(now, 50)
+
+			if err != nil {
+				log.Println("Failed to load accounts due for deletion: ", err)
+				continue
+			}
+
+			for _, userId := range userIds {
+				if err := s.deleteAccount(userId, now); err != nil {
+					log.Println("Failed to delete account: ", err)
+				}
+			}
+		}
+	}
+}
+
+func (s *AccountService) deleteAccount(userId uuid.UUID, now time.Time) error {
+	imageUrls, err := s.accountRepo.GetImageUrls(userId)
+
+	if err != nil {
+		return err
+	}
+
+	// an archive waiting for download would outlive its row, it's only removed once the account is gone
+	export, err := s.accountRepo.GetOpenExport(userId)
+
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	deleted, err := s.userRepo.DeleteScheduledUser(userId, now)
+
+	if err != nil || !deleted {
+		return err
+	}
+
+	if export != nil && export.FilePath != "" {
+		if err := os.Remove(export.FilePath); err != nil && !os.IsNotExist(err) {
+			log.Println("Failed to delete export archive: ", err)
+		}
+	}
+
+	for _, imageUrl := range imageUrls {
+		if err := DeleteFromCloud(imageUrl); err != nil {
+			log.Println("Failed to delete image from cloud: ", err)
+		}
+	}
+
+	log.Println("Deleted account ", userId)
+
+	return nil
+}
@@ -0,0 +1,132 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/harry713j/vibe_writer/internal/model"
+	"github.com/harry713j/vibe_writer/internal/repo"
+)
+
+var (
+	ErrBlogIdNotExists   = errors.New("no blog exists with this id")
+	ErrUsernameNotExists = errors.New("no user exists with this username")
+	ErrInvalidRole       = errors.New("role must be user, moderator or admin")
+	ErrSelfModeration    = errors.New("you can not change your own account")
+)
+
+type AdminService struct {
+	userRepo       *repo.UserRepository
+	moderationRepo *repo.ModerationRepository
+	sessionRepo    *repo.SessionRepository
+}
+
+func NewAdminService(userRepo *repo.UserRepository, moderationRepo *repo.ModerationRepository,
+	sessionRepo *repo.SessionRepository) *AdminService {
+	return &AdminService{
+		userRepo:       userRepo,
+		moderationRepo: moderationRepo,
+		sessionRepo:    sessionRepo,
+	}
+}
+
+// hidden blogs drop out of every listing and only their author can open them
+func (s *AdminService) SetBlogHidden(actorId uuid.UUID, blogId int64, hidden bool, reason string) error {
+	found, err := s.moderationRepo.SetBlogHidden(actorId, blogId, hidden, reason)
+
+	if err != nil {
+		return err
+	}
+
+	if !found {
+		return ErrBlogIdNotExists
+	}
+
+	return nil
+}
+
+func (s *AdminService) SetCommentHidden(actorId uuid.UUID, commentId int64, hidden bool, reason string) error {
+	found, err := s.moderationRepo.SetCommentHidden(actorId, commentId, hidden, reason)
+
+	if err != nil {
+		return err
+	}
+
+	if !found {
+		return ErrCommentNotExists
+	}
+
+	return nil
+}
+
+// suspended users can't log in and every device they use is signed out
+func (s *AdminService) SetUserSuspended(actorId uuid.UUID, username string, suspended bool, reason string) error {
+	user, err := s.targetUser(actorId, username)
+
+	if err != nil {
+		return err
+	}
+
+	found, err := s.moderationRepo.SetUserSuspended(actorId, user.Id, suspended, reason)
+
+	if err != nil {
+		return err
+	}
+
+	if !found {
+		return ErrUsernameNotExists
+	}
+
+	if suspended {
+		if _, err := s.sessionRepo.RevokeOtherSessions(user.Id, uuid.Nil, time.Now()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// the new role reaches the user's access token with its next refresh
+func (s *AdminService) ChangeRole(actorId uuid.UUID, username string, role model.Role, reason string) error {
+	if role != model.ROLE_USER && role != model.ROLE_MODERATOR && role != model.ROLE_ADMIN {
+		return ErrInvalidRole
+	}
+
+	user, err := s.targetUser(actorId, username)
+
+	if err != nil {
+		return err
+	}
+
+	found, err := s.moderationRepo.SetUserRole(actorId, user, role, reason)
+
+	if err != nil {
+		return err
+	}
+
+	if !found {
+		return ErrUsernameNotExists
+	}
+
+	return nil
+}
+
+func (s *AdminService) GetAuditLogs(page, limit int) (*model.PaginatedResponse[model.AuditLog], error) {
+	return s.moderationRepo.GetAuditLogs(page, limit)
+}
+
+// admins can't act on themselves, it keeps the last admin from locking everyone out
+func (s *AdminService) targetUser(actorId uuid.UUID, username string) (*model.User, error) {
+	user, err := s.userRepo.GetUserByUsername(username)
+
+	if err != nil {
+		return nil, ErrUsernameNotExists
+	}
+
+	if user.Id == actorId {
+		return nil, ErrSelfModeration
+	}
+
+	return user, nil
+}
@@ -2,10 +2,15 @@ package service
 
 import (
 	"errors"
+	"log"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
+	"github.com/harry713j/vibe_writer/internal/config"
+	"github.com/harry713j/vibe_writer/internal/keyring"
+	"github.com/harry713j/vibe_writer/internal/limiter"
+	"github.com/harry713j/vibe_writer/internal/mailer"
 	"github.com/harry713j/vibe_writer/internal/model"
 	"github.com/harry713j/vibe_writer/internal/repo"
 	"github.com/harry713j/vibe_writer/internal/utils"
@@ -19,26 +24,53 @@ var (
 	ErrExpiredToken        = errors.New("expired token")
 	ErrExpiredRefreshToken = errors.New("refresh token is expired")
 	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token was already used, please log in again")
 	ErrUserNotExists       = errors.New("invalid user credentials")
+	ErrUserSuspended       = errors.New("this account is suspended")
 )
 
 type AuthService struct {
-	userRepo         *repo.UserRepository
-	profileRepo      *repo.UserProfileRepository
-	refreshTokenRepo *repo.RefreshTokenRepository
-	jwtSecret        []byte
-	accessTokenTTL   time.Duration
+	userRepo          *repo.UserRepository
+	profileRepo       *repo.UserProfileRepository
+	refreshTokenRepo  *repo.RefreshTokenRepository
+	sessionRepo       *repo.SessionRepository
+	verificationRepo  *repo.EmailVerificationRepository
+	resetRepo         *repo.PasswordResetRepository
+	twoFactorRepo     *repo.TwoFactorRepository
+	personalTokenRepo *repo.PersonalTokenRepository
+	identityRepo      *repo.UserIdentityRepository
+	limiter           *limiter.Limiter
+	mailer            mailer.Mailer
+	site              *config.SiteConfig
+	config            *config.AuthConfig
+	keyring           *keyring.Keyring
+	accessTokenTTL    time.Duration
 }
 
 func NewAuthService(userRepo *repo.UserRepository, profileRepo *repo.UserProfileRepository,
-	refreshTokenRepo *repo.RefreshTokenRepository, jwtSecret string, accessTokenTTL time.Duration) *AuthService {
+	refreshTokenRepo *repo.RefreshTokenRepository, sessionRepo *repo.SessionRepository,
+	verificationRepo *repo.EmailVerificationRepository,
+	resetRepo *repo.PasswordResetRepository, twoFactorRepo *repo.TwoFactorRepository,
+	personalTokenRepo *repo.PersonalTokenRepository, identityRepo *repo.UserIdentityRepository, limiter *limiter.Limiter,
+	mailer mailer.Mailer, site *config.SiteConfig, config *config.AuthConfig,
+	keyring *keyring.Keyring, accessTokenTTL time.Duration) *AuthService {
 
 	return &AuthService{
-		userRepo:         userRepo,
-		profileRepo:      profileRepo,
-		refreshTokenRepo: refreshTokenRepo,
-		jwtSecret:        []byte(jwtSecret),
-		accessTokenTTL:   accessTokenTTL,
+		userRepo:          userRepo,
+		profileRepo:       profileRepo,
+		refreshTokenRepo:  refreshTokenRepo,
+		sessionRepo:       sessionRepo,
+		verificationRepo:  verificationRepo,
+		resetRepo:         resetRepo,
+		twoFactorRepo:     twoFactorRepo,
+		personalTokenRepo: personalTokenRepo,
+		identityRepo:      identityRepo,
+		limiter:           limiter,
+		mailer:            mailer,
+		site:              site,
+		config:            config,
+		keyring:           keyring,
+		accessTokenTTL:    accessTokenTTL,
 	}
 }
 
@@ -51,6 +83,17 @@ func (service *AuthService) RegisterUser(username, email, password string) (*mod
 		return nil, ErrUsernameExists
 	}
 
+	// released usernames stay reserved for a while so their old links can't be taken over
+	held, err := service.userRepo.IsUsernameHeld(username, uuid.Nil, time.Now())
+
+	if err != nil {
+		return nil, err
+	}
+
+	if held {
+		return nil, ErrUsernameHeld
+	}
+
 	_, err = service.userRepo.GetUserByEmail(email)
 
 	if err == nil {
@@ -86,112 +129,280 @@ func (service *AuthService) RegisterUser(username, email, password string) (*mod
 		return nil, err
 	}
 
+	// a failed mail doesn't fail the signup, the user can ask for another one
+	if err := service.sendVerificationEmail(user); err != nil {
+		log.Println("Failed to send verification email: ", err)
+	}
+
 	return user, nil
 }
 
-func (service *AuthService) LoginUser(identifier, password string) (accessToken string, refreshToken string, err error) {
+// sign in from a new device, userAgent and ipAddress describe the device in the session list.
+// Accounts with two factor authentication get an mfa token to finish the login with instead.
+// Repeated failures slow the identifier and the address down and finally lock them out
+func (service *AuthService) LoginUser(identifier, password, userAgent, ipAddress string) (*model.LoginResult, error) {
 	// identifier can be username or email
 	user, err := service.userRepo.GetUserByIdentifier(identifier)
 
 	if err != nil {
-		return "", "", ErrUserNotExists
+		user = nil
+	}
+
+	attempts := service.passwordAttempts(user, identifier, ipAddress)
+
+	// the attempt counts as failed unless the password turns out right
+	if err := service.reserveAttempts(attempts); err != nil {
+		return nil, err
+	}
+
+	if user == nil {
+		return nil, ErrUserNotExists
 	}
 
 	// check the password
 	err = VerifyPassword(user.Password, password)
 
 	if err != nil {
-		return "", "", ErrWrongPassword
+		return nil, ErrWrongPassword
+	}
+
+	service.resetAttempts(attempts)
+
+	return service.finishLogin(user, userAgent, ipAddress)
+}
+
+// the user proved who they are, accounts with two factor authentication still need their code
+func (service *AuthService) finishLogin(user *model.User, userAgent, ipAddress string) (*model.LoginResult, error) {
+	if user.SuspendedAt != nil {
+		return nil, ErrUserSuspended
+	}
+
+	if service.hasTwoFactor(user.Id) {
+		mfaToken, err := service.generateMfaToken(user)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return &model.LoginResult{MfaToken: mfaToken}, nil
+	}
+
+	return service.startSession(user, userAgent, ipAddress)
+}
+
+// create the session of a new login and its first token pair
+func (service *AuthService) startSession(user *model.User, userAgent, ipAddress string) (*model.LoginResult, error) {
+	now := time.Now()
+
+	// tokens that expired can go, login is a good moment
+	if err := service.refreshTokenRepo.DeleteExpiredRefreshTokens(user.Id, now); err != nil {
+		log.Println("Failed to delete expired refresh tokens: ", err)
+	} else if err := service.sessionRepo.DeleteStaleSessions(user.Id); err != nil {
+		log.Println("Failed to delete stale sessions: ", err)
+	}
+
+	// logging in during the grace period keeps the account
+	if user.DeleteAt != nil {
+		if _, err := service.userRepo.CancelDeletion(user.Id); err != nil {
+			return nil, err
+		}
+
+		user.DeleteAt = nil
 	}
 
-	// create refresh token
-	refresh, err := service.refreshTokenRepo.CreateRefreshToken(user.Id)
+	// every login starts a new session, its id is the family of its refresh tokens
+	sessionId := uuid.New()
+
+	if err := service.sessionRepo.CreateSession(sessionId, user.Id, userAgent, ipAddress, now); err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := service.issueRefreshToken(user.Id, sessionId)
 
 	if err != nil {
-		return "", "", err
+		return nil, err
 	}
 	// generate the access token
-	accessToken, err = service.generateAccessToken(user)
+	accessToken, err := service.generateAccessToken(user, sessionId)
 
 	if err != nil {
-		return "", "", err
+		return nil, err
 	}
 
-	return accessToken, refresh.Token.String(), nil
+	return &model.LoginResult{AccessToken: accessToken, RefreshToken: refreshToken}, nil
 }
 
-func (service *AuthService) LogoutUser(userId uuid.UUID) error {
+// end the session the access token belongs to, other devices stay signed in
+func (service *AuthService) LogoutUser(userId, sessionId uuid.UUID) error {
 
 	if _, err := service.userRepo.GetUserById(userId); err != nil {
 		return ErrUserNotExists
 	}
 
-	go service.refreshTokenRepo.DeleteRefreshToken(userId)
+	// access tokens from before sessions existed don't say which one is theirs
+	if sessionId == uuid.Nil {
+		go service.refreshTokenRepo.DeleteRefreshToken(userId)
+		return nil
+	}
+
+	if _, err := service.sessionRepo.RevokeSession(userId, sessionId, time.Now()); err != nil {
+		return err
+	}
 
 	return nil
 }
 
-func (service *AuthService) RefreshAccessToken(refreshTokenStr string) (string, error) {
+// trade a refresh token for a new access token and a new refresh token, the old refresh
+// token stops working. Presenting a token that was already traded means it leaked, so the
+// whole family is revoked and its owner has to log in again
+func (service *AuthService) RefreshAccessToken(refreshTokenStr, userAgent, ipAddress string) (accessToken string, refreshToken string, err error) {
 	// get the corresponding refresh token
-	refreshTokenUUID, err := uuid.Parse(refreshTokenStr)
+	current, err := service.refreshTokenRepo.GetRefreshToken(utils.HashToken(refreshTokenStr))
 
 	if err != nil {
-		return "", ErrInvalidRefreshToken
+		return "", "", ErrInvalidRefreshToken
 	}
 
-	refreshToken, err := service.refreshTokenRepo.GetRefreshToken(refreshTokenUUID)
-	if err != nil {
-		return "", ErrInvalidRefreshToken
+	if current.RevokedAt != nil {
+		return "", "", ErrInvalidRefreshToken
+	}
+
+	now := time.Now()
+
+	if current.RotatedAt != nil {
+		return "", "", service.revokeFamily(current, now)
 	}
 	// check validity of the token
-	if time.Now().After(refreshToken.ExpireAt) {
-		return "", ErrExpiredRefreshToken
+	if now.After(current.ExpireAt) {
+		return "", "", ErrExpiredRefreshToken
+	}
+
+	rotated, err := service.refreshTokenRepo.RotateRefreshToken(current.Id, now)
+
+	if err != nil {
+		return "", "", err
+	}
+
+	// lost the race against another use of the same token
+	if !rotated {
+		return "", "", service.revokeFamily(current, now)
 	}
 	// get the user
-	user, err := service.userRepo.GetUserById(refreshToken.UserId)
+	user, err := service.userRepo.GetUserById(current.UserId)
 
 	if err != nil {
-		return "", ErrUserNotExists
+		return "", "", ErrUserNotExists
+	}
+
+	if user.SuspendedAt != nil {
+		return "", "", ErrUserSuspended
+	}
+
+	refreshToken, err = service.issueRefreshToken(user.Id, current.FamilyId)
+
+	if err != nil {
+		return "", "", err
+	}
+
+	if err := service.sessionRepo.TouchSession(current.FamilyId, userAgent, ipAddress, now); err != nil {
+		log.Println("Failed to update session: ", err)
 	}
 	// generate access token
-	newAccessToken, err := service.generateAccessToken(user)
+	accessToken, err = service.generateAccessToken(user, current.FamilyId)
+
+	if err != nil {
+		return "", "", err
+	}
+
+	return accessToken, refreshToken, nil
+}
+
+// create a refresh token in the family and return the raw token, only its hash is stored
+func (service *AuthService) issueRefreshToken(userId, familyId uuid.UUID) (string, error) {
+	token, err := utils.RandomHex(64)
 
 	if err != nil {
 		return "", err
 	}
 
-	return newAccessToken, nil
+	if _, err := service.refreshTokenRepo.CreateRefreshToken(userId, familyId, utils.HashToken(token)); err != nil {
+		return "", err
+	}
+
+	return token, nil
 }
 
-func (service *AuthService) generateAccessToken(user *model.User) (string, error) {
+// current role of the user, suspended users have none
+func (service *AuthService) GetActiveRole(userId uuid.UUID) (model.Role, error) {
+	user, err := service.userRepo.GetUserById(userId)
+
+	if err != nil {
+		return "", ErrUserNotExists
+	}
+
+	if user.SuspendedAt != nil {
+		return "", ErrUserSuspended
+	}
+
+	return user.Role, nil
+}
+
+// the family is the session, so the device holding it is signed out as well
+func (service *AuthService) revokeFamily(token *model.RefreshToken, now time.Time) error {
+	log.Println("Refresh token reuse detected, revoking token family ", token.FamilyId)
+
+	if _, err := service.sessionRepo.RevokeSession(token.UserId, token.FamilyId, now); err != nil {
+		return err
+	}
+
+	return ErrRefreshTokenReused
+}
+
+// access token of the user for the session, the sid claim tells logout which session to end
+func (service *AuthService) generateAccessToken(user *model.User, sessionId uuid.UUID) (string, error) {
 	expirationTime := time.Now().Add(service.accessTokenTTL)
 
 	claims := jwt.MapClaims{
 		"sub":      user.Id.String(),
+		"sid":      sessionId.String(),
+		"role":     string(user.Role),
 		"username": user.Username,
 		"email":    user.Email,
 		"iat":      time.Now().Unix(),
 		"exp":      expirationTime.Unix(),
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenStr, err := token.SignedString(service.jwtSecret)
+	return service.signToken(claims)
+}
 
-	if err != nil {
-		return "", err
-	}
+// signed with the current key of the ring, the kid header names it
+func (service *AuthService) signToken(claims jwt.MapClaims) (string, error) {
+	return service.keyring.Sign(claims)
+}
 
-	return tokenStr, nil
+// public keys verifying our tokens
+func (service *AuthService) JWKS() keyring.JWKSet {
+	return service.keyring.JWKS()
 }
 
+// validate an access token, tokens signed for another purpose are rejected
 func (service *AuthService) ValidateJwtToken(tokenStr string) (jwt.MapClaims, error) {
-	token, err := jwt.Parse(tokenStr, func(token *jwt.Token) (any, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, ErrInvalidToken
-		}
+	claims, err := service.parseToken(tokenStr)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := claims["purpose"]; ok {
+		return nil, ErrInvalidToken
+	}
+
+	return claims, nil
+}
 
-		return service.jwtSecret, nil
-	})
+func (service *AuthService) parseToken(tokenStr string) (jwt.MapClaims, error) {
+	// tokens with an unknown kid or an algorithm their key doesn't use are rejected
+	token, err := jwt.Parse(tokenStr, service.keyring.Keyfunc, jwt.WithValidMethods(service.keyring.Methods()))
 
 	if err != nil {
 		if errors.Is(err, jwt.ErrTokenExpired) {
@@ -1,7 +1,11 @@
 package service
 
 import (
+	"context"
 	"errors"
+	"log"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/harry713j/vibe_writer/internal/model"
@@ -10,31 +14,60 @@ import (
 )
 
 type BlogService struct {
-	blogRepo     *repo.BlogRepository
-	userRepo     *repo.UserRepository
-	commentRepo  *repo.CommentRepository
-	likeRepo     *repo.LikeRepository
-	bookmarkRepo *repo.BookmarkRepository
+	blogRepo            *repo.BlogRepository
+	userRepo            *repo.UserRepository
+	commentRepo         *repo.CommentRepository
+	likeRepo            *repo.LikeRepository
+	bookmarkRepo        *repo.BookmarkRepository
+	revisionRepo        *repo.BlogRevisionRepository
+	tagRepo             *repo.TagRepository
+	followRepo          *repo.FollowRepository
+	mentionService      *MentionService
+	notificationService *NotificationService
 }
 
 var (
-	ErrBlogNotExists = errors.New("no blog exists with this slug")
-	ErrTitleExists   = errors.New("blog with this title already exists")
+	ErrBlogNotExists           = errors.New("no blog exists with this slug")
+	ErrTitleExists             = errors.New("blog with this title already exists")
+	ErrInvalidBlogStatus       = errors.New("invalid blog status")
+	ErrInvalidStatusTransition = errors.New("blog can not move to this status")
+	ErrInvalidPublishTime      = errors.New("scheduled blogs need a publish time in the future")
+	ErrEmptySearchQuery        = errors.New("search query is required")
+	ErrInvalidDateRange        = errors.New("from date must be before to date")
+	ErrCommentsLocked          = errors.New("comments are locked on this blog")
+	ErrCommentsFollowersOnly   = errors.New("only followers of the author can comment on this blog")
 )
 
+// allowed moves between blog statuses
+var blogStatusTransitions = map[model.BlogStatus][]model.BlogStatus{
+	model.DRAFT:     {model.SCHEDULED, model.PUBLISHED, model.UNLISTED, model.ARCHIVED},
+	model.SCHEDULED: {model.DRAFT, model.PUBLISHED, model.ARCHIVED},
+	model.PUBLISHED: {model.DRAFT, model.UNLISTED, model.ARCHIVED},
+	model.UNLISTED:  {model.DRAFT, model.PUBLISHED, model.ARCHIVED},
+	model.ARCHIVED:  {model.DRAFT, model.PUBLISHED, model.UNLISTED},
+}
+
 func NewBlogService(blogRepo *repo.BlogRepository, userRepo *repo.UserRepository,
-	commentRepo *repo.CommentRepository, likeRepo *repo.LikeRepository, bookmarkRepo *repo.BookmarkRepository) *BlogService {
+	commentRepo *repo.CommentRepository, likeRepo *repo.LikeRepository, bookmarkRepo *repo.BookmarkRepository,
+	revisionRepo *repo.BlogRevisionRepository, tagRepo *repo.TagRepository, followRepo *repo.FollowRepository,
+	mentionService *MentionService, notificationService *NotificationService) *BlogService {
 	return &BlogService{
-		blogRepo:     blogRepo,
-		userRepo:     userRepo,
-		commentRepo:  commentRepo,
-		likeRepo:     likeRepo,
-		bookmarkRepo: bookmarkRepo,
+		blogRepo:            blogRepo,
+		userRepo:            userRepo,
+		commentRepo:         commentRepo,
+		likeRepo:            likeRepo,
+		bookmarkRepo:        bookmarkRepo,
+		revisionRepo:        revisionRepo,
+		tagRepo:             tagRepo,
+		followRepo:          followRepo,
+		mentionService:      mentionService,
+		notificationService: notificationService,
 	}
 }
 
-// create blog
-func (r *BlogService) CreateBlog(userId uuid.UUID, title, slug, content string, photoUrls []string) (*model.BlogResponse, error) {
+// create blog, an empty status publishes it right away
+func (r *BlogService) CreateBlog(userId uuid.UUID, title, slug, content string, photoUrls, tags []string,
+	status model.BlogStatus, publishAt *time.Time) (*model.BlogResponse, error) {
 
 	err := r.blogRepo.GetBlogByTitle(userId, title)
 
@@ -42,14 +75,41 @@ func (r *BlogService) CreateBlog(userId uuid.UUID, title, slug, content string,
 		return nil, ErrTitleExists
 	}
 
+	// same default as the status column
+	if status == "" {
+		status = model.PUBLISHED
+	}
+
+	if _, ok := blogStatusTransitions[status]; !ok {
+		return nil, ErrInvalidBlogStatus
+	}
+
+	publishAt, err = r.resolvePublishAt(status, publishAt)
+
+	if err != nil {
+		return nil, err
+	}
+
+	blogTags, err := normalizeTags(tags)
+
+	if err != nil {
+		return nil, err
+	}
+
 	randomHex, err := utils.RandomHex(16) // give a random hex of length 16
 	if err != nil {
 		return nil, err
 	}
 
+	contentHtml, err := utils.RenderMarkdown(content)
+
+	if err != nil {
+		return nil, err
+	}
+
 	transformedSlug := slug + "-" + randomHex
 	// create the blog
-	blogId, err := r.blogRepo.CreateBlog(userId, title, transformedSlug, content)
+	blogId, err := r.blogRepo.CreateBlog(userId, title, transformedSlug, content, contentHtml, status, publishAt)
 
 	if err != nil {
 		return nil, err
@@ -61,6 +121,17 @@ func (r *BlogService) CreateBlog(userId uuid.UUID, title, slug, content string,
 		}
 	}
 
+	if err := r.tagRepo.SetBlogTags(blogId, blogTags); err != nil {
+		return nil, err
+	}
+
+	// first revision
+	if _, err := r.revisionRepo.CreateRevision(blogId, title, content, photoUrls, photoUrls, nil, nil); err != nil {
+		return nil, err
+	}
+
+	r.mentionService.SyncBlogMentions(userId, blogId, content)
+
 	// get that blog
 	blog, err := r.blogRepo.GetBlogById(userId, blogId)
 
@@ -68,44 +139,60 @@ func (r *BlogService) CreateBlog(userId uuid.UUID, title, slug, content string,
 		return nil, err
 	}
 
+	if err := r.mentionService.AttachBlogMentions(blog); err != nil {
+		return nil, err
+	}
+
 	return blog, nil
 }
 
-func (r *BlogService) UpdateBlog(userId uuid.UUID, slug, title, content string, photoUrls []string) (*model.BlogResponse, error) {
+// nil tags keep the current ones, an empty list removes them
+func (r *BlogService) UpdateBlog(userId uuid.UUID, slug, title, content string, photoUrls, tags []string) (*model.BlogResponse, error) {
 
 	// check blog exists or not
 	if _, err := r.blogRepo.GetBlogBySlug(userId, slug); err != nil {
 		return nil, ErrBlogNotExists
 	}
 
-	blogId, err := r.blogRepo.UpdateBlog(userId, slug, title, content)
+	var blogTags []model.Tag
 
-	if err != nil {
-		return nil, err
+	if tags != nil {
+		normalized, err := normalizeTags(tags)
+
+		if err != nil {
+			return nil, err
+		}
+
+		blogTags = normalized
 	}
 
-	existingPhotoUrls, err := r.blogRepo.GetPhotoUrls(blogId)
+	return r.updateBlog(userId, slug, title, content, photoUrls, blogTags, nil)
+}
+
+// update the blog and record the result as a new revision, nil tags are left alone
+func (r *BlogService) updateBlog(userId uuid.UUID, slug, title, content string, photoUrls []string, tags []model.Tag,
+	restoredFrom *int) (*model.BlogResponse, error) {
+	contentHtml, err := utils.RenderMarkdown(content)
 
 	if err != nil {
 		return nil, err
 	}
 
-	removedUrls := r.differentUrls(existingPhotoUrls, photoUrls) // old photo urls to be remove
-	addUrls := r.differentUrls(photoUrls, existingPhotoUrls)     // new photo urls to be add
-
-	// remove from table
-	err = r.blogRepo.DeleteBlogPhotosByURLs(blogId, removedUrls)
+	// removed photos stay in the cloud, older revisions still point to them
+	blogId, err := r.blogRepo.UpdateBlog(userId, slug, title, content, contentHtml, photoUrls, restoredFrom)
 
 	if err != nil {
 		return nil, err
 	}
-	// create new photoUrls
-	for _, url := range addUrls {
-		if _, err := r.blogRepo.CreateBlogImage(blogId, url); err != nil {
+
+	if tags != nil {
+		if err := r.tagRepo.SetBlogTags(blogId, tags); err != nil {
 			return nil, err
 		}
 	}
 
+	r.mentionService.SyncBlogMentions(userId, blogId, content)
+
 	// get that blog
 	blog, err := r.blogRepo.GetBlogById(userId, blogId)
 
@@ -113,18 +200,15 @@ func (r *BlogService) UpdateBlog(userId uuid.UUID, slug, title, content string,
 		return nil, err
 	}
 
-	// remove the old photo url from cloud
-	go func(removedUrls []string) {
-		for _, url := range removedUrls {
-			DeleteFromCloud(url)
-		}
-	}(removedUrls)
+	if err := r.mentionService.AttachBlogMentions(blog); err != nil {
+		return nil, err
+	}
 
 	return blog, err
 }
 
-// Change the blog visibility
-func (r *BlogService) ChangeBlogVisibility(userId uuid.UUID, slug string) (*model.BlogResponse, error) {
+// Change the blog status
+func (r *BlogService) ChangeBlogStatus(userId uuid.UUID, slug string, status model.BlogStatus, publishAt *time.Time) (*model.BlogResponse, error) {
 	if _, err := r.userRepo.GetUserById(userId); err != nil {
 		return nil, ErrUserNotExists
 	}
@@ -134,16 +218,136 @@ func (r *BlogService) ChangeBlogVisibility(userId uuid.UUID, slug string) (*mode
 		return nil, ErrBlogNotExists
 	}
 
-	err = r.blogRepo.UpdateBlogVisibility(userId, slug)
+	if _, ok := blogStatusTransitions[status]; !ok {
+		return nil, ErrInvalidBlogStatus
+	}
+
+	if !r.canTransition(blog.Status, status) {
+		return nil, ErrInvalidStatusTransition
+	}
+
+	// keep the original publish time when a blog goes back to public
+	if publishAt == nil && blog.PublishAt != nil && status != model.SCHEDULED {
+		publishAt = blog.PublishAt
+	}
+
+	publishAt, err = r.resolvePublishAt(status, publishAt)
 
 	if err != nil {
 		return nil, err
 	}
 
-	blog.Visibility = !blog.Visibility
+	err = r.blogRepo.UpdateBlogStatus(userId, slug, status, publishAt)
+
+	if err != nil {
+		return nil, err
+	}
+
+	blog.Status = status
+	blog.PublishAt = publishAt
 	return blog, nil
 }
 
+// promote scheduled blogs once their publish time arrives, runs until ctx is done
+func (r *BlogService) RunScheduledPublisher(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			published, err := r.blogRepo.PublishScheduledBlogs(now)
+
+			if err != nil {
+				log.Println("Failed to publish scheduled blogs: ", err)
+				continue
+			}
+
+			if published > 0 {
+				log.Printf("Published %d scheduled blogs\n", published)
+			}
+		}
+	}
+}
+
+// render the html of blogs stored before markdown rendering existed
+func (r *BlogService) RenderMissingHtml() {
+	rendered := 0
+
+	for {
+		blogs, err := r.blogRepo.GetBlogsWithoutHtml(100)
+
+		if err != nil {
+			log.Println("Failed to load blogs without html: ", err)
+			return
+		}
+
+		if len(blogs) == 0 {
+			break
+		}
+
+		for _, blog := range blogs {
+			contentHtml, err := utils.RenderMarkdown(blog.Content)
+
+			if err != nil {
+				// store something so the blog isn't picked up again forever
+				log.Printf("Failed to render blog %d: %v\n", blog.Id, err)
+				contentHtml = ""
+			}
+
+			if err := r.blogRepo.UpdateBlogHtml(blog.Id, contentHtml); err != nil {
+				log.Println("Failed to store rendered html: ", err)
+				return
+			}
+
+			rendered++
+		}
+	}
+
+	if rendered > 0 {
+		log.Printf("Rendered html of %d blogs\n", rendered)
+	}
+}
+
+func (r *BlogService) canTransition(from, to model.BlogStatus) bool {
+	if from == to {
+		// rescheduling a scheduled blog is fine, anything else is a no-op
+		return from == model.SCHEDULED
+	}
+
+	for _, status := range blogStatusTransitions[from] {
+		if status == to {
+			return true
+		}
+	}
+
+	return false
+}
+
+// publish time a blog should carry for the given status
+func (r *BlogService) resolvePublishAt(status model.BlogStatus, publishAt *time.Time) (*time.Time, error) {
+	now := time.Now()
+
+	switch status {
+	case model.SCHEDULED:
+		if publishAt == nil || !publishAt.After(now) {
+			return nil, ErrInvalidPublishTime
+		}
+		return publishAt, nil
+	case model.PUBLISHED, model.UNLISTED:
+		if publishAt == nil || publishAt.After(now) {
+			return &now, nil
+		}
+		return publishAt, nil
+	case model.DRAFT:
+		return nil, nil
+	default:
+		return publishAt, nil
+	}
+}
+
 // public blogs of an user
 func (r *BlogService) GetAllUserBlog(username string, page, limit int) (*model.PaginatedResponse[model.BlogSummary], error) {
 	user, err := r.userRepo.GetUserByUsername(username)
@@ -161,6 +365,79 @@ func (r *BlogService) GetAllUserBlog(username string, page, limit int) (*model.P
 	return blogs, nil
 }
 
+// blogs from followed authors, cursor is empty for the first page
+func (r *BlogService) GetFeed(userId uuid.UUID, cursor string, limit int) (*model.CursorResponse[model.BlogSummary], error) {
+	if _, err := r.userRepo.GetUserById(userId); err != nil {
+		return nil, ErrUserNotExists
+	}
+
+	if limit <= 0 || limit > 50 {
+		limit = 10
+	}
+
+	var cursorTime *time.Time
+	var cursorId int64
+
+	if cursor != "" {
+		t, id, err := utils.DecodeCursor(cursor)
+
+		if err != nil {
+			return nil, err
+		}
+
+		cursorTime, cursorId = &t, id
+	}
+
+	// one extra row tells whether there is a next page
+	blogs, err := r.blogRepo.GetFeed(userId, cursorTime, cursorId, limit+1)
+
+	if err != nil {
+		return nil, err
+	}
+
+	feed := &model.CursorResponse[model.BlogSummary]{Data: blogs}
+
+	if len(blogs) > limit {
+		feed.Data = blogs[:limit]
+		last := feed.Data[limit-1]
+		feed.NextCursor = utils.EncodeCursor(*last.PublishAt, last.Id)
+	}
+
+	if feed.Data == nil {
+		feed.Data = []model.BlogSummary{}
+	}
+
+	return feed, nil
+}
+
+// search public blogs, authorUsername narrows it to one author
+func (r *BlogService) SearchBlogs(searchQuery, authorUsername string, from, to *time.Time,
+	page, limit int) (*model.PaginatedResponse[model.BlogSummary], error) {
+	searchQuery = strings.TrimSpace(searchQuery)
+
+	if searchQuery == "" {
+		return nil, ErrEmptySearchQuery
+	}
+
+	if from != nil && to != nil && from.After(*to) {
+		return nil, ErrInvalidDateRange
+	}
+
+	var authorId *uuid.UUID
+
+	if authorUsername != "" {
+		author, err := r.userRepo.GetUserByUsername(authorUsername)
+
+		if err != nil {
+			return nil, ErrInvalidAuthor
+		}
+
+		authorId = &author.Id
+	}
+
+	return r.blogRepo.SearchPublicBlogs(searchQuery, authorId, from, to, page, limit)
+}
+
 // All the blogs of an user
 func (r *BlogService) GetAllBlog(userId uuid.UUID, page, limit int) (*model.PaginatedResponse[model.BlogSummary], error) {
 	if _, err := r.userRepo.GetUserById(userId); err != nil {
@@ -176,8 +453,8 @@ func (r *BlogService) GetAllBlog(userId uuid.UUID, page, limit int) (*model.Pagi
 	return blogs, nil
 }
 
-// return `BlogDetails` with error
-func (r *BlogService) GetBlog(username string, slug string) (*model.BlogResponse, error) {
+// return `BlogDetails` with error, viewerId is uuid.Nil for anonymous readers
+func (r *BlogService) GetBlog(username string, slug string, viewerId uuid.UUID) (*model.BlogResponse, error) {
 	// get the user details by username
 	user, err := r.userRepo.GetUserByUsername(username)
 
@@ -191,16 +468,75 @@ func (r *BlogService) GetBlog(username string, slug string) (*model.BlogResponse
 		return nil, ErrBlogNotExists
 	}
 
+	// drafts, scheduled, archived and hidden blogs are only visible to the author
+	if blog.Status != model.PUBLISHED && blog.Status != model.UNLISTED || blog.HiddenAt != nil {
+		return nil, ErrBlogNotExists
+	}
+
+	blog.Comments, err = loadCommentThreads(r.commentRepo, r.mentionService, viewerId, blog.Id, blog.PinnedCommentId, "", 0)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.mentionService.AttachBlogMentions(blog); err != nil {
+		return nil, err
+	}
+
+	return blog, nil
+}
+
+// blog of the logged in user whatever its status
+func (r *BlogService) GetOwnBlog(userId uuid.UUID, slug string) (*model.BlogResponse, error) {
+	if _, err := r.userRepo.GetUserById(userId); err != nil {
+		return nil, ErrUserNotExists
+	}
+
+	blog, err := r.blogRepo.GetBlogBySlug(userId, slug)
+
+	if err != nil {
+		return nil, ErrBlogNotExists
+	}
+
+	blog.Comments, err = loadCommentThreads(r.commentRepo, r.mentionService, userId, blog.Id, blog.PinnedCommentId, "", 0)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.mentionService.AttachBlogMentions(blog); err != nil {
+		return nil, err
+	}
+
 	return blog, nil
 }
 
 func (r *BlogService) DeleteBlog(userId uuid.UUID, slug string) error {
 	// check the blog exists or not
-	if _, err := r.blogRepo.GetBlogBySlug(userId, slug); err != nil {
+	blog, err := r.blogRepo.GetBlogBySlug(userId, slug)
+
+	if err != nil {
 		return ErrBlogNotExists
 	}
 
-	return r.blogRepo.DeleteBlog(userId, slug)
+	// photos of every revision, revisions go away with the blog
+	photoUrls, err := r.revisionRepo.GetAllPhotoUrls(blog.Id)
+
+	if err != nil {
+		return err
+	}
+
+	if err := r.blogRepo.DeleteBlog(userId, slug); err != nil {
+		return err
+	}
+
+	go func(photoUrls []string) {
+		for _, url := range photoUrls {
+			DeleteFromCloud(url)
+		}
+	}(append(photoUrls, r.differentUrls(blog.PhotoUrls, photoUrls)...))
+
+	return nil
 }
 
 // returns string in a that are not present in b
@@ -221,48 +557,137 @@ func (r *BlogService) differentUrls(a, b []string) []string {
 	return diff
 }
 
-// comment
-func (s *BlogService) CreateComment(userId uuid.UUID, slug string, parentId int64, content string) (*model.CommentWithStat, error) {
+// the blog of the author the user can reach by its slug, slugs are only unique per author.
+// An empty author means the user's own blog
+func (s *BlogService) getReachableBlog(userId uuid.UUID, author, slug string) (*model.BlogWithStat, error) {
+	authorId := userId
+
+	if author != "" {
+		user, err := s.userRepo.GetUserByUsername(author)
+
+		if err != nil {
+			return nil, ErrBlogNotExists
+		}
+
+		authorId = user.Id
+	}
+
+	blog, err := s.blogRepo.GetReachableBlog(userId, authorId, slug)
+
+	if err != nil {
+		return nil, ErrBlogNotExists
+	}
+
+	return blog, nil
+}
+
+// comment on a blog of author, an empty author is the user's own blog
+func (s *BlogService) CreateComment(userId uuid.UUID, author, slug string, parentId int64, content string) (*model.CommentWithStat, error) {
 	// check user exists or not
 	if _, err := s.userRepo.GetUserById(userId); err != nil {
 		return nil, ErrUserNotExists
 	}
-	// check blog with blog id exists or not
-	blog, err := s.blogRepo.GetBlogBySlug(userId, slug)
+
+	blog, err := s.getReachableBlog(userId, author, slug)
 
 	if err != nil {
-		return nil, ErrBlogNotExists
+		return nil, err
 	}
 
 	if content == "" {
 		return nil, ErrInvalidCommentContent
 	}
 
+	if blog.CommentsLocked {
+		return nil, ErrCommentsLocked
+	}
+
+	if blog.CommentsFollowersOnly && blog.UserId != userId {
+		following, err := s.followRepo.IsFollowing(userId, blog.UserId)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if !following {
+			return nil, ErrCommentsFollowersOnly
+		}
+	}
+
+	// a reply belongs in a thread of the same blog
+	var parent *model.CommentWithStat
+
+	if parentId != 0 {
+		if parent, err = s.commentRepo.GetBlogComment(userId, blog.Id, parentId); err != nil {
+			return nil, ErrCommentNotExists
+		}
+	}
+
 	commentId, err := s.commentRepo.CreateComment(userId, blog.Id, parentId, content)
 
 	if err != nil {
 		return nil, err
 	}
 
+	s.mentionService.SyncCommentMentions(userId, commentId, content)
+
+	// the writer of the comment replied to hears about the reply, not about a comment too
+	if parent != nil {
+		s.notificationService.Notify(parent.UserId, userId, model.NOTIFY_REPLY, &blog.Id, &parent.Id)
+	}
+
+	if parent == nil || parent.UserId != blog.UserId {
+		s.notificationService.Notify(blog.UserId, userId, model.NOTIFY_COMMENT, &blog.Id, nil)
+	}
+
 	comment, err := s.commentRepo.GetCommentById(userId, commentId)
 
 	if err != nil {
 		return nil, err
 	}
 
+	if err := s.mentionService.AttachCommentMentions(comment); err != nil {
+		return nil, err
+	}
+
 	return comment, nil
 }
 
-// like
-func (s *BlogService) ToggleBlogLike(userId uuid.UUID, slug string, liketype model.LikeType) (*model.Like, error) {
+// lock the comments of the user's blog or open them to followers only, nil leaves a setting as it is
+func (s *BlogService) UpdateCommentSettings(userId uuid.UUID, slug string, locked, followersOnly *bool) (*model.CommentSettings, error) {
+	blog, err := s.blogRepo.GetBlogBySlug(userId, slug)
+
+	if err != nil {
+		return nil, ErrBlogNotExists
+	}
+
+	if err := s.blogRepo.UpdateCommentSettings(blog.Id, locked, followersOnly); err != nil {
+		return nil, err
+	}
+
+	settings := blog.CommentSettings
+
+	if locked != nil {
+		settings.CommentsLocked = *locked
+	}
+
+	if followersOnly != nil {
+		settings.CommentsFollowersOnly = *followersOnly
+	}
+
+	return &settings, nil
+}
+
+// like a blog of author, an empty author is the user's own blog
+func (s *BlogService) ToggleBlogLike(userId uuid.UUID, author, slug string, liketype model.LikeType) (*model.Like, error) {
 	if _, err := s.userRepo.GetUserById(userId); err != nil {
 		return nil, ErrUserNotExists
 	}
 
-	blog, err := s.blogRepo.GetBlogBySlug(userId, slug)
+	blog, err := s.getReachableBlog(userId, author, slug)
 
 	if err != nil {
-		return nil, ErrBlogNotExists
+		return nil, err
 	}
 
 	if liketype != "like" && liketype != "dislike" {
@@ -275,46 +700,57 @@ func (s *BlogService) ToggleBlogLike(userId uuid.UUID, slug string, liketype mod
 		return nil, err
 	}
 
+	// dislikes are kept quiet
+	if liketype == model.LIKE {
+		s.notificationService.Notify(blog.UserId, userId, model.NOTIFY_BLOG_LIKE, &blog.Id, nil)
+	}
+
 	return like, nil
 }
 
-func (s *BlogService) RemoveBlogLike(userId uuid.UUID, slug string) error {
+func (s *BlogService) RemoveBlogLike(userId uuid.UUID, author, slug string) error {
 	if _, err := s.userRepo.GetUserById(userId); err != nil {
 		return ErrUserNotExists
 	}
 
-	blog, err := s.blogRepo.GetBlogBySlug(userId, slug)
+	blog, err := s.getReachableBlog(userId, author, slug)
 
 	if err != nil {
-		return ErrBlogNotExists
+		return err
 	}
 
 	return s.likeRepo.DeleteBlogLike(userId, blog.Id)
 }
 
-func (s *BlogService) CreateBookmark(userId uuid.UUID, slug string) error {
+func (s *BlogService) CreateBookmark(userId uuid.UUID, author, slug string) error {
 	if _, err := s.userRepo.GetUserById(userId); err != nil {
 		return ErrUserNotExists
 	}
 
-	blog, err := s.blogRepo.GetBlogBySlug(userId, slug)
+	blog, err := s.getReachableBlog(userId, author, slug)
 
 	if err != nil {
-		return ErrBlogNotExists
+		return err
+	}
+
+	if err := s.bookmarkRepo.Upsert(userId, blog.Id); err != nil {
+		return err
 	}
 
-	return s.bookmarkRepo.Upsert(userId, blog.Id)
+	s.notificationService.Notify(blog.UserId, userId, model.NOTIFY_BOOKMARK, &blog.Id, nil)
+
+	return nil
 }
 
-func (s *BlogService) RemoveBookmark(userId uuid.UUID, slug string) error {
+func (s *BlogService) RemoveBookmark(userId uuid.UUID, author, slug string) error {
 	if _, err := s.userRepo.GetUserById(userId); err != nil {
 		return ErrUserNotExists
 	}
 
-	blog, err := s.blogRepo.GetBlogBySlug(userId, slug)
+	blog, err := s.getReachableBlog(userId, author, slug)
 
 	if err != nil {
-		return ErrBlogNotExists
+		return err
 	}
 
 	return s.bookmarkRepo.Delete(userId, blog.Id)
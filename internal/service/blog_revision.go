@@ -0,0 +1,93 @@
+package service
+
+import (
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/harry713j/vibe_writer/internal/model"
+	"github.com/harry713j/vibe_writer/internal/utils"
+)
+
+var (
+	ErrRevisionNotExists = errors.New("no revision exists with this number")
+)
+
+func (r *BlogService) GetRevisions(userId uuid.UUID, slug string, page, limit int) (*model.PaginatedResponse[model.BlogRevisionSummary], error) {
+	blog, err := r.blogRepo.GetBlogBySlug(userId, slug)
+
+	if err != nil {
+		return nil, ErrBlogNotExists
+	}
+
+	return r.revisionRepo.GetRevisions(blog.Id, page, limit)
+}
+
+func (r *BlogService) GetRevision(userId uuid.UUID, slug string, revisionNumber int) (*model.BlogRevision, error) {
+	blog, err := r.blogRepo.GetBlogBySlug(userId, slug)
+
+	if err != nil {
+		return nil, ErrBlogNotExists
+	}
+
+	revision, err := r.revisionRepo.GetRevision(blog.Id, revisionNumber)
+
+	if err != nil {
+		return nil, ErrRevisionNotExists
+	}
+
+	return revision, nil
+}
+
+// line level diff going from revision `from` to revision `to`
+func (r *BlogService) DiffRevisions(userId uuid.UUID, slug string, from, to int) (*model.BlogRevisionDiff, error) {
+	blog, err := r.blogRepo.GetBlogBySlug(userId, slug)
+
+	if err != nil {
+		return nil, ErrBlogNotExists
+	}
+
+	fromRevision, err := r.revisionRepo.GetRevision(blog.Id, from)
+
+	if err != nil {
+		return nil, ErrRevisionNotExists
+	}
+
+	toRevision, err := r.revisionRepo.GetRevision(blog.Id, to)
+
+	if err != nil {
+		return nil, ErrRevisionNotExists
+	}
+
+	lines, err := utils.DiffLines(fromRevision.Content, toRevision.Content)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.BlogRevisionDiff{
+		From:             fromRevision.Revision,
+		To:               toRevision.Revision,
+		FromTitle:        fromRevision.Title,
+		ToTitle:          toRevision.Title,
+		Lines:            lines,
+		AddedPhotoUrls:   r.differentUrls(toRevision.PhotoUrls, fromRevision.PhotoUrls),
+		RemovedPhotoUrls: r.differentUrls(fromRevision.PhotoUrls, toRevision.PhotoUrls),
+	}, nil
+}
+
+// make an old revision the current content, this is recorded as a new revision
+func (r *BlogService) RestoreRevision(userId uuid.UUID, slug string, revisionNumber int) (*model.BlogResponse, error) {
+	blog, err := r.blogRepo.GetBlogBySlug(userId, slug)
+
+	if err != nil {
+		return nil, ErrBlogNotExists
+	}
+
+	revision, err := r.revisionRepo.GetRevision(blog.Id, revisionNumber)
+
+	if err != nil {
+		return nil, ErrRevisionNotExists
+	}
+
+	return r.updateBlog(userId, slug, revision.Title, revision.Content, revision.PhotoUrls, nil, &revision.Revision)
+}
@@ -2,6 +2,8 @@ package service
 
 import (
 	"errors"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/harry713j/vibe_writer/internal/model"
@@ -12,33 +14,47 @@ var (
 	ErrInvalidCommentContent = errors.New("comment content is required")
 	ErrCommentNotExists      = errors.New("comment not exists")
 	ErrInvalidLikeType       = errors.New("invalid like type")
+	ErrCommentEditClosed     = errors.New("comments can only be edited within 30 minutes of posting")
+	ErrNotBlogAuthor         = errors.New("only the author of the blog can do this")
+	ErrCommentHiddenByMod    = errors.New("comment was hidden by a moderator")
+	ErrCannotPinComment      = errors.New("only visible top level comments can be pinned")
 )
 
+// how long after posting the author can still edit a comment
+const commentEditWindow = 30 * time.Minute
+
 type CommentService struct {
-	commentRepo *repo.CommentRepository
-	userRepo    *repo.UserRepository
-	likeRepo    *repo.LikeRepository
+	commentRepo    *repo.CommentRepository
+	userRepo       *repo.UserRepository
+	likeRepo       *repo.LikeRepository
+	blogRepo       *repo.BlogRepository
+	mentionService *MentionService
 }
 
 func NewCommentService(commentRepo *repo.CommentRepository, userRepo *repo.UserRepository,
-	likeRepo *repo.LikeRepository) *CommentService {
+	likeRepo *repo.LikeRepository, blogRepo *repo.BlogRepository, mentionService *MentionService) *CommentService {
 	return &CommentService{
-		commentRepo: commentRepo,
-		likeRepo:    likeRepo,
-		userRepo:    userRepo,
+		commentRepo:    commentRepo,
+		likeRepo:       likeRepo,
+		userRepo:       userRepo,
+		blogRepo:       blogRepo,
+		mentionService: mentionService,
 	}
 }
 
+// the writer of the comment or the author of its blog can delete it
 func (s *CommentService) DeleteComment(userId uuid.UUID, commentId int64) error {
 	if _, err := s.userRepo.GetUserById(userId); err != nil {
 		return ErrUserNotExists
 	}
 
-	if _, err := s.commentRepo.GetCommentById(userId, commentId); err != nil {
+	comment, err := s.commentRepo.GetCommentOwnership(commentId)
+
+	if err != nil || comment.UserId != userId && comment.BlogAuthorId != userId {
 		return ErrCommentNotExists
 	}
 
-	err := s.commentRepo.DeleteCommentById(userId, commentId)
+	err = s.commentRepo.DeleteCommentById(comment.UserId, commentId)
 
 	if err != nil {
 		return err
@@ -47,6 +63,136 @@ func (s *CommentService) DeleteComment(userId uuid.UUID, commentId int64) error
 	return nil
 }
 
+// comment on one of the user's own blogs
+func (s *CommentService) getAuthoredComment(userId uuid.UUID, commentId int64) (*model.CommentOwnership, error) {
+	comment, err := s.commentRepo.GetCommentOwnership(commentId)
+
+	if err != nil {
+		return nil, ErrCommentNotExists
+	}
+
+	if comment.BlogAuthorId != userId {
+		return nil, ErrNotBlogAuthor
+	}
+
+	return comment, nil
+}
+
+// hide a comment on the user's blog from everyone but its writer
+func (s *CommentService) HideComment(userId uuid.UUID, commentId int64) error {
+	if _, err := s.getAuthoredComment(userId, commentId); err != nil {
+		return err
+	}
+
+	return s.commentRepo.SetCommentHiddenByAuthor(commentId, true)
+}
+
+// show a comment the blog author hid, the ones hidden by a moderator stay hidden
+func (s *CommentService) UnhideComment(userId uuid.UUID, commentId int64) error {
+	comment, err := s.getAuthoredComment(userId, commentId)
+
+	if err != nil {
+		return err
+	}
+
+	if comment.HiddenAt != nil && !comment.HiddenByAuthor {
+		return ErrCommentHiddenByMod
+	}
+
+	return s.commentRepo.SetCommentHiddenByAuthor(commentId, false)
+}
+
+// pin a top level comment to the top of the user's blog
+func (s *CommentService) PinComment(userId uuid.UUID, commentId int64) error {
+	comment, err := s.getAuthoredComment(userId, commentId)
+
+	if err != nil {
+		return err
+	}
+
+	if comment.ParentId != nil || comment.HiddenAt != nil {
+		return ErrCannotPinComment
+	}
+
+	return s.blogRepo.SetPinnedComment(comment.BlogId, commentId)
+}
+
+func (s *CommentService) UnpinComment(userId uuid.UUID, commentId int64) error {
+	comment, err := s.getAuthoredComment(userId, commentId)
+
+	if err != nil {
+		return err
+	}
+
+	return s.blogRepo.UnpinComment(comment.BlogId, commentId)
+}
+
+// replace the content of the user's own comment while the edit window is open, every
+// earlier version is kept as a revision
+func (s *CommentService) EditComment(userId uuid.UUID, commentId int64, content string) (*model.CommentWithStat, error) {
+	if _, err := s.userRepo.GetUserById(userId); err != nil {
+		return nil, ErrUserNotExists
+	}
+
+	comment, err := s.commentRepo.GetCommentById(userId, commentId)
+
+	if err != nil {
+		return nil, ErrCommentNotExists
+	}
+
+	if strings.TrimSpace(content) == "" {
+		return nil, ErrInvalidCommentContent
+	}
+
+	// nothing changed, no revision to keep
+	if content == comment.Content {
+		if err := s.mentionService.AttachCommentMentions(comment); err != nil {
+			return nil, err
+		}
+
+		return comment, nil
+	}
+
+	now := time.Now()
+
+	if comment.CreatedAt != nil && now.Sub(*comment.CreatedAt) > commentEditWindow {
+		return nil, ErrCommentEditClosed
+	}
+
+	if err := s.commentRepo.UpdateComment(userId, commentId, content, now); err != nil {
+		return nil, err
+	}
+
+	s.mentionService.SyncCommentMentions(userId, commentId, content)
+
+	comment, err = s.commentRepo.GetCommentById(userId, commentId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.mentionService.AttachCommentMentions(comment); err != nil {
+		return nil, err
+	}
+
+	return comment, nil
+}
+
+// every earlier version of a comment, for moderators reviewing what it used to say
+func (s *CommentService) GetCommentRevisions(commentId int64) ([]model.CommentRevision, error) {
+	exists, err := s.commentRepo.CommentExists(commentId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !exists {
+		return nil, ErrCommentNotExists
+	}
+
+	return s.commentRepo.GetCommentRevisions(commentId)
+}
+
 func (s *CommentService) ToggleCommentLike(userId uuid.UUID, commentId int64, liketype model.LikeType) (*model.Like, error) {
 	if _, err := s.userRepo.GetUserById(userId); err != nil {
 		return nil, ErrUserNotExists
@@ -0,0 +1,216 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/harry713j/vibe_writer/internal/model"
+	"github.com/harry713j/vibe_writer/internal/repo"
+	"github.com/harry713j/vibe_writer/internal/utils"
+)
+
+const (
+	commentPageLimit    = 20 // top level comments on a page unless asked otherwise
+	maxCommentPageLimit = 50
+	commentReplyDepth   = 3 // reply levels nested in a response, deeper ones are collapsed
+	commentReplyPreview = 3 // replies shown under each comment before "load more"
+)
+
+// replies_cursor of a comment whose replies were collapsed, it starts at the first reply
+var firstReplyCursor = utils.EncodeCursor(time.Unix(0, 0), 0)
+
+func commentPageSize(limit int) int {
+	if limit <= 0 || limit > maxCommentPageLimit {
+		return commentPageLimit
+	}
+
+	return limit
+}
+
+func decodeCursor(cursor string) (*time.Time, int64, error) {
+	if cursor == "" {
+		return nil, 0, nil
+	}
+
+	t, id, err := utils.DecodeCursor(cursor)
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return &t, id, nil
+}
+
+func commentCursor(comment model.CommentNode) string {
+	if comment.CreatedAt == nil {
+		return utils.EncodeCursor(time.Unix(0, 0), comment.Id)
+	}
+
+	return utils.EncodeCursor(*comment.CreatedAt, comment.Id)
+}
+
+// a page of top level comments newest first, each with its replies nested under it. The pinned
+// comment comes first on the first page, hidden comments are only shown to their writer
+func loadCommentThreads(commentRepo *repo.CommentRepository, mentions *MentionService, viewerId uuid.UUID, blogId int64,
+	pinnedId *int64, cursor string, limit int) (*model.CursorResponse[model.CommentNode], error) {
+	limit = commentPageSize(limit)
+	cursorTime, cursorId, err := decodeCursor(cursor)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// one extra row tells whether there is a next page
+	comments, err := commentRepo.GetRootComments(viewerId, blogId, pinnedId, cursorTime, cursorId, limit+1)
+
+	if err != nil {
+		return nil, err
+	}
+
+	page, err := commentPage(commentRepo, viewerId, comments, limit)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if cursor == "" && pinnedId != nil {
+		pinned, err := commentRepo.GetRootComment(viewerId, blogId, *pinnedId)
+
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+
+		if err == nil {
+			pinned.Pinned = true
+			thread := []model.CommentNode{*pinned}
+
+			if err := nestReplies(commentRepo, viewerId, thread); err != nil {
+				return nil, err
+			}
+
+			page.Data = append(thread, page.Data...)
+		}
+	}
+
+	if err := mentions.attachThreadMentions(page.Data); err != nil {
+		return nil, err
+	}
+
+	return page, nil
+}
+
+// a page of the replies of a comment oldest first, nested the same way as the threads
+func loadCommentReplies(commentRepo *repo.CommentRepository, mentions *MentionService, viewerId uuid.UUID, parentId int64,
+	cursor string, limit int) (*model.CursorResponse[model.CommentNode], error) {
+	limit = commentPageSize(limit)
+	cursorTime, cursorId, err := decodeCursor(cursor)
+
+	if err != nil {
+		return nil, err
+	}
+
+	comments, err := commentRepo.GetReplies(viewerId, parentId, cursorTime, cursorId, limit+1)
+
+	if err != nil {
+		return nil, err
+	}
+
+	page, err := commentPage(commentRepo, viewerId, comments, limit)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := mentions.attachThreadMentions(page.Data); err != nil {
+		return nil, err
+	}
+
+	return page, nil
+}
+
+func commentPage(commentRepo *repo.CommentRepository, viewerId uuid.UUID, comments []model.CommentNode,
+	limit int) (*model.CursorResponse[model.CommentNode], error) {
+	page := &model.CursorResponse[model.CommentNode]{Data: comments}
+
+	if len(comments) > limit {
+		page.Data = comments[:limit]
+		page.NextCursor = commentCursor(page.Data[limit-1])
+	}
+
+	if page.Data == nil {
+		page.Data = []model.CommentNode{}
+	}
+
+	if err := nestReplies(commentRepo, viewerId, page.Data); err != nil {
+		return nil, err
+	}
+
+	return page, nil
+}
+
+// fill the replies level by level with one query per level. Every comment shows its first
+// few replies, the rest and anything below commentReplyDepth is left behind replies_cursor
+func nestReplies(commentRepo *repo.CommentRepository, viewerId uuid.UUID, comments []model.CommentNode) error {
+	level := make([]*model.CommentNode, len(comments))
+
+	for i := range comments {
+		level[i] = &comments[i]
+	}
+
+	for depth := 1; len(level) > 0; depth++ {
+		var parentIds []int64
+
+		for _, comment := range level {
+			comment.Replies = []model.CommentNode{}
+
+			if comment.ReplyCount == 0 {
+				continue
+			}
+
+			if depth > commentReplyDepth {
+				comment.RepliesCursor = firstReplyCursor
+				continue
+			}
+
+			parentIds = append(parentIds, comment.Id)
+		}
+
+		replies, err := commentRepo.GetFirstReplies(viewerId, parentIds, commentReplyPreview)
+
+		if err != nil {
+			return err
+		}
+
+		byParent := map[int64][]model.CommentNode{}
+
+		for _, reply := range replies {
+			byParent[*reply.ParentId] = append(byParent[*reply.ParentId], reply)
+		}
+
+		var next []*model.CommentNode
+
+		for _, comment := range level {
+			shown, ok := byParent[comment.Id]
+
+			if !ok {
+				continue
+			}
+
+			comment.Replies = shown
+
+			if comment.ReplyCount > len(shown) {
+				comment.RepliesCursor = commentCursor(shown[len(shown)-1])
+			}
+
+			for i := range comment.Replies {
+				next = append(next, &comment.Replies[i])
+			}
+		}
+
+		level = next
+	}
+
+	return nil
+}
@@ -0,0 +1,137 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/harry713j/vibe_writer/internal/mailer"
+	"github.com/harry713j/vibe_writer/internal/model"
+	"github.com/harry713j/vibe_writer/internal/utils"
+)
+
+const emailChangeDelay = time.Minute
+
+var (
+	ErrInvalidEmailChangeToken = errors.New("invalid or expired email change token")
+	ErrSameEmail               = errors.New("this is already your email")
+	ErrEmailChangeTooSoon      = errors.New("please wait a minute before requesting another email change")
+)
+
+// mail a confirmation link to the new address, the email only changes once it's opened.
+// The caller reauthenticates the user first so a stolen access token can't take the account over
+func (s *AccountService) RequestEmailChange(userId uuid.UUID, newEmail string) error {
+	user, err := s.userRepo.GetUserById(userId)
+
+	if err != nil {
+		return ErrUserNotExists
+	}
+
+	// addresses are stored lower cased, as social login does
+	newEmail = strings.ToLower(strings.TrimSpace(newEmail))
+
+	if newEmail == strings.ToLower(user.Email) {
+		return ErrSameEmail
+	}
+
+	if err := utils.ValidateEmail(newEmail); err != nil {
+		return err
+	}
+
+	if _, err := s.userRepo.GetUserByEmail(newEmail); err == nil {
+		return ErrEmailExists
+	}
+
+	latest, err := s.emailChangeRepo.GetLatestEmailChange(userId)
+
+	if err == nil && time.Since(latest.CreatedAt) < emailChangeDelay {
+		return ErrEmailChangeTooSoon
+	}
+
+	if err := s.emailChangeRepo.DeleteUnusedEmailChanges(userId); err != nil {
+		return err
+	}
+
+	token, err := utils.RandomHex(64)
+
+	if err != nil {
+		return err
+	}
+
+	expireAt := time.Now().Add(s.config.EmailChangeTTL)
+
+	if err := s.emailChangeRepo.CreateEmailChange(userId, newEmail, utils.HashToken(token), expireAt); err != nil {
+		return err
+	}
+
+	link := s.site.URL + "/confirm-email?token=" + url.QueryEscape(token)
+
+	s.sendMail(mailer.Message{
+		To:      newEmail,
+		Subject: "Confirm your new email address",
+		Body: fmt.Sprintf("Hi %s,\n\nConfirm that this is your new email address by opening the link below:\n\n%s\n\n"+
+			"The link works once and expires on %s. Until then your account keeps using %s.\n",
+			user.Username, link, expireAt.UTC().Format("Jan 2, 2006 15:04 MST"), user.Email),
+	}, "email change confirmation")
+
+	return nil
+}
+
+// switch to the confirmed address and let the old one know about it
+func (s *AccountService) ConfirmEmailChange(token string) (*model.User, error) {
+	now := time.Now()
+	change, err := s.emailChangeRepo.GetEmailChange(utils.HashToken(token), now)
+
+	if err != nil {
+		return nil, ErrInvalidEmailChangeToken
+	}
+
+	user, err := s.userRepo.GetUserById(change.UserId)
+
+	if err != nil {
+		return nil, ErrInvalidEmailChangeToken
+	}
+
+	// someone may have registered the address since the link was sent, the link keeps
+	// working in case they give it up again
+	if _, err := s.userRepo.GetUserByEmail(change.NewEmail); err == nil {
+		return nil, ErrEmailExists
+	}
+
+	applied, err := s.emailChangeRepo.ApplyEmailChange(change, now)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !applied {
+		return nil, ErrInvalidEmailChangeToken
+	}
+
+	s.sendMail(mailer.Message{
+		To:      user.Email,
+		Subject: "Your email address was changed",
+		Body: fmt.Sprintf("Hi %s,\n\nThe email address of your account was changed to %s.\n\n"+
+			"If you didn't do this, reset your password at %s and contact us.\n",
+			user.Username, change.NewEmail, s.site.URL),
+	}, "email change notice")
+
+	user.Email = change.NewEmail
+	user.EmailVerifiedAt = &now
+	user.UpdatedAt = now
+
+	return user, nil
+}
+
+// delivery happens in the background, a failure is only logged
+func (s *AccountService) sendMail(msg mailer.Message, kind string) {
+	go func() {
+		if err := s.mailer.Send(msg); err != nil {
+			log.Printf("Failed to deliver %s email: %v", kind, err)
+		}
+	}()
+}
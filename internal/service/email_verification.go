@@ -0,0 +1,140 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/harry713j/vibe_writer/internal/mailer"
+	"github.com/harry713j/vibe_writer/internal/model"
+)
+
+const (
+	emailVerificationPurpose = "email_verification"
+	verificationResendDelay  = time.Minute
+)
+
+var (
+	ErrInvalidVerificationToken = errors.New("invalid or expired verification token")
+	ErrEmailAlreadyVerified     = errors.New("email is already verified")
+	ErrVerificationTooSoon      = errors.New("please wait a minute before requesting another verification email")
+	ErrEmailNotVerified         = errors.New("verify your email address first")
+)
+
+// mark the email of the token verified, every token works once
+func (service *AuthService) VerifyEmail(tokenStr string) error {
+	claims, err := service.parseToken(tokenStr)
+
+	if err != nil {
+		return ErrInvalidVerificationToken
+	}
+
+	if purpose, _ := claims["purpose"].(string); purpose != emailVerificationPurpose {
+		return ErrInvalidVerificationToken
+	}
+
+	jti, _ := claims["jti"].(string)
+	tokenId, err := uuid.Parse(jti)
+
+	if err != nil {
+		return ErrInvalidVerificationToken
+	}
+
+	verification, err := service.verificationRepo.UseVerification(tokenId, time.Now())
+
+	if err != nil {
+		return ErrInvalidVerificationToken
+	}
+
+	// fails when the user changed the email after the link was sent
+	if err := service.userRepo.MarkEmailVerified(verification.UserId, verification.Email); err != nil {
+		return ErrInvalidVerificationToken
+	}
+
+	return nil
+}
+
+// send a new verification link, older links stop working
+func (service *AuthService) ResendVerification(userId uuid.UUID) error {
+	user, err := service.userRepo.GetUserById(userId)
+
+	if err != nil {
+		return ErrUserNotExists
+	}
+
+	if user.EmailVerifiedAt != nil {
+		return ErrEmailAlreadyVerified
+	}
+
+	latest, err := service.verificationRepo.GetLatestVerification(userId)
+
+	if err == nil && time.Since(latest.CreatedAt) < verificationResendDelay {
+		return ErrVerificationTooSoon
+	}
+
+	if err := service.verificationRepo.DeleteUnusedVerifications(userId); err != nil {
+		return err
+	}
+
+	return service.sendVerificationEmail(user)
+}
+
+func (service *AuthService) RequiresVerifiedEmail() bool {
+	return service.config.RequireVerifiedEmail
+}
+
+func (service *AuthService) IsEmailVerified(userId uuid.UUID) (bool, error) {
+	user, err := service.userRepo.GetUserById(userId)
+
+	if err != nil {
+		return false, ErrUserNotExists
+	}
+
+	return user.EmailVerifiedAt != nil, nil
+}
+
+// store the verification and mail the signed link, delivery happens in the background
+func (service *AuthService) sendVerificationEmail(user *model.User) error {
+	tokenId := uuid.New()
+	now := time.Now()
+	expireAt := now.Add(service.config.VerificationTokenTTL)
+
+	token, err := service.signToken(jwt.MapClaims{
+		"sub":     user.Id.String(),
+		"email":   user.Email,
+		"jti":     tokenId.String(),
+		"purpose": emailVerificationPurpose,
+		"iat":     now.Unix(),
+		"exp":     expireAt.Unix(),
+	})
+
+	if err != nil {
+		return err
+	}
+
+	if err := service.verificationRepo.CreateVerification(user.Id, tokenId, user.Email, expireAt); err != nil {
+		return err
+	}
+
+	link := service.site.URL + "/verify-email?token=" + url.QueryEscape(token)
+
+	msg := mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf("Hi %s,\n\nConfirm your email address by opening the link below:\n\n%s\n\n"+
+			"The link works once and expires on %s. If you didn't sign up, you can ignore this email.\n",
+			user.Username, link, expireAt.UTC().Format("Jan 2, 2006 15:04 MST")),
+	}
+
+	go func() {
+		if err := service.mailer.Send(msg); err != nil {
+			log.Println("Failed to deliver verification email: ", err)
+		}
+	}()
+
+	return nil
+}
@@ -0,0 +1,116 @@
+package service
+
+import (
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/harry713j/vibe_writer/internal/limiter"
+	"github.com/harry713j/vibe_writer/internal/model"
+)
+
+// returned instead of checking credentials while a login key is backing off or locked out
+type TooManyAttemptsError struct {
+	RetryAfter time.Duration
+}
+
+func (e *TooManyAttemptsError) Error() string {
+	return "too many failed attempts, try again later"
+}
+
+// a limiter key with the policy it is judged by
+type loginAttempt struct {
+	key    string
+	policy limiter.Policy
+}
+
+// password attempts are tracked per account and per client address, an address gets more
+// room since many users can share one. The username and the email of an account count
+// together, identifiers of no account are tracked on their own
+func (service *AuthService) passwordAttempts(user *model.User, identifier, ipAddress string) []loginAttempt {
+	key := "login:id:" + strings.ToLower(strings.TrimSpace(identifier))
+
+	if user != nil {
+		key = "login:user:" + user.Id.String()
+	}
+
+	attempts := []loginAttempt{{key: key, policy: service.identifierPolicy()}}
+
+	if ipAddress != "" {
+		attempts = append(attempts, loginAttempt{key: "login:ip:" + ipAddress, policy: limiter.Policy{
+			FreeAttempts: service.config.LoginMaxAttemptsIP / 5,
+			BaseDelay:    time.Second,
+			MaxDelay:     time.Minute,
+			LockoutAfter: service.config.LoginMaxAttemptsIP,
+			LockoutFor:   service.config.LoginLockout,
+		}})
+	}
+
+	return attempts
+}
+
+// codes of the second login step, six digits are guessable without a limit
+func (service *AuthService) codeAttempts(userId uuid.UUID) []loginAttempt {
+	return []loginAttempt{{key: "login:mfa:" + userId.String(), policy: service.identifierPolicy()}}
+}
+
+func (service *AuthService) identifierPolicy() limiter.Policy {
+	return limiter.Policy{
+		FreeAttempts: 3,
+		BaseDelay:    time.Second,
+		MaxDelay:     time.Minute,
+		LockoutAfter: service.config.LoginMaxAttempts,
+		LockoutFor:   service.config.LoginLockout,
+	}
+}
+
+// count the attempt on every key before the credentials are checked, a failure then needs
+// nothing more. When a key has to wait the others are given back and the longest wait is
+// returned, a broken store lets the login through
+func (service *AuthService) reserveAttempts(attempts []loginAttempt) error {
+	var wait time.Duration
+	var reserved []loginAttempt
+	now := time.Now()
+
+	for _, attempt := range attempts {
+		retryAfter, err := service.limiter.Reserve(attempt.key, attempt.policy, now)
+
+		if err != nil {
+			log.Println("Failed to check login attempts: ", err)
+			continue
+		}
+
+		if retryAfter > 0 {
+			wait = max(wait, retryAfter)
+			continue
+		}
+
+		reserved = append(reserved, attempt)
+	}
+
+	if wait > 0 {
+		service.releaseAttempts(reserved)
+		return &TooManyAttemptsError{RetryAfter: wait}
+	}
+
+	return nil
+}
+
+func (service *AuthService) releaseAttempts(attempts []loginAttempt) {
+	for _, attempt := range attempts {
+		if err := service.limiter.Release(attempt.key); err != nil {
+			log.Println("Failed to release login attempt: ", err)
+		}
+	}
+}
+
+// success clears the first key, the account or user, the address only gets this attempt
+// back so one working account can't be used to reset it
+func (service *AuthService) resetAttempts(attempts []loginAttempt) {
+	if err := service.limiter.Reset(attempts[0].key); err != nil {
+		log.Println("Failed to reset login attempts: ", err)
+	}
+
+	service.releaseAttempts(attempts[1:])
+}
@@ -0,0 +1,281 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/harry713j/vibe_writer/internal/config"
+	"github.com/harry713j/vibe_writer/internal/mailer"
+	"github.com/harry713j/vibe_writer/internal/model"
+	"github.com/harry713j/vibe_writer/internal/repo"
+	"github.com/harry713j/vibe_writer/internal/utils"
+)
+
+const (
+	maxMentions        = 20 // users looked up per blog or comment, the rest stay plain text
+	mentionNotifyBatch = 100
+)
+
+type MentionService struct {
+	mentionRepo         *repo.MentionRepository
+	userRepo            *repo.UserRepository
+	notificationService *NotificationService
+	mailer              mailer.Mailer
+	site                *config.SiteConfig
+	wake                chan struct{}
+}
+
+func NewMentionService(mentionRepo *repo.MentionRepository, userRepo *repo.UserRepository,
+	notificationService *NotificationService, mailer mailer.Mailer, site *config.SiteConfig) *MentionService {
+	return &MentionService{
+		mentionRepo:         mentionRepo,
+		userRepo:            userRepo,
+		notificationService: notificationService,
+		mailer:              mailer,
+		site:                site,
+		wake:                make(chan struct{}, 1),
+	}
+}
+
+// store who the blog mentions, they hear about it once the blog can be read. The blog is
+// saved already, a failure here costs the notifications and is only logged
+func (s *MentionService) SyncBlogMentions(authorId uuid.UUID, blogId int64, content string) {
+	mentions, err := s.resolveMentions(authorId, content)
+
+	if err == nil {
+		err = s.mentionRepo.SyncBlogMentions(authorId, blogId, mentions)
+	}
+
+	if err != nil {
+		log.Printf("Failed to store the mentions of blog %d: %v", blogId, err)
+		return
+	}
+
+	s.notifySoon()
+}
+
+func (s *MentionService) SyncCommentMentions(authorId uuid.UUID, commentId int64, content string) {
+	mentions, err := s.resolveMentions(authorId, content)
+
+	if err == nil {
+		err = s.mentionRepo.SyncCommentMentions(authorId, commentId, mentions)
+	}
+
+	if err != nil {
+		log.Printf("Failed to store the mentions of comment %d: %v", commentId, err)
+		return
+	}
+
+	s.notifySoon()
+}
+
+// users the content mentions, unknown names and the author mentioning themselves are skipped
+func (s *MentionService) resolveMentions(authorId uuid.UUID, content string) ([]model.Mention, error) {
+	var usernames []string
+	seen := map[string]bool{}
+
+	for _, token := range utils.ParseMentions(content) {
+		if seen[token.Username] {
+			continue
+		}
+
+		if len(seen) == maxMentions {
+			break
+		}
+
+		seen[token.Username] = true
+		usernames = append(usernames, token.Username)
+	}
+
+	if len(usernames) == 0 {
+		return nil, nil
+	}
+
+	userIds, err := s.userRepo.GetUserIdsByUsernames(usernames)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var mentions []model.Mention
+
+	for _, username := range usernames {
+		userId, ok := userIds[username]
+
+		if !ok || userId == authorId {
+			continue
+		}
+
+		mentions = append(mentions, model.Mention{UserId: userId, Username: username, Written: username})
+	}
+
+	return mentions, nil
+}
+
+func (s *MentionService) AttachBlogMentions(blog *model.BlogResponse) error {
+	mentions, err := s.mentionRepo.GetBlogMentions(blog.Id)
+
+	if err != nil {
+		return err
+	}
+
+	blog.Mentions = mentionSpans(blog.Content, mentions)
+
+	return nil
+}
+
+func (s *MentionService) AttachCommentMentions(comments ...*model.CommentWithStat) error {
+	commentIds := make([]int64, len(comments))
+
+	for i, comment := range comments {
+		commentIds[i] = comment.Id
+	}
+
+	mentions, err := s.mentionRepo.GetCommentMentions(commentIds)
+
+	if err != nil {
+		return err
+	}
+
+	for _, comment := range comments {
+		comment.Mentions = mentionSpans(comment.Content, mentions[comment.Id])
+	}
+
+	return nil
+}
+
+// mentions of every comment in the threads, replies included
+func (s *MentionService) attachThreadMentions(threads []model.CommentNode) error {
+	var comments []*model.CommentWithStat
+	level := threads
+
+	for len(level) > 0 {
+		var next []model.CommentNode
+
+		for i := range level {
+			comments = append(comments, &level[i].CommentWithStat)
+			next = append(next, level[i].Replies...)
+		}
+
+		level = next
+	}
+
+	return s.AttachCommentMentions(comments...)
+}
+
+// the stored mentions found in the content, a name that resolved to nobody gets no span
+func mentionSpans(content string, mentions []model.Mention) []model.MentionSpan {
+	spans := []model.MentionSpan{}
+
+	if len(mentions) == 0 {
+		return spans
+	}
+
+	byName := map[string]model.Mention{}
+
+	for _, mention := range mentions {
+		byName[mention.Written] = mention
+	}
+
+	for _, token := range utils.ParseMentions(content) {
+		mention, ok := byName[token.Username]
+
+		if !ok {
+			continue
+		}
+
+		spans = append(spans, model.MentionSpan{
+			Start:    token.Start,
+			End:      token.End,
+			UserId:   mention.UserId,
+			Username: mention.Username,
+		})
+	}
+
+	return spans
+}
+
+func (s *MentionService) notifySoon() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// tell mentioned users in the app and by email, every mention is delivered once
+func (s *MentionService) RunNotifier(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		s.notifyPendingMentions()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *MentionService) notifyPendingMentions() {
+	for {
+		mentions, err := s.mentionRepo.GetPendingMentions(mentionNotifyBatch)
+
+		if err != nil {
+			log.Println("Failed to load pending mentions: ", err)
+			return
+		}
+
+		for _, mention := range mentions {
+			claimed, err := s.mentionRepo.ClaimMention(mention.Id, time.Now())
+
+			if err != nil {
+				log.Println("Failed to claim mention: ", err)
+				return
+			}
+
+			if !claimed {
+				continue
+			}
+
+			// users who turned mentions off get neither the notification nor the email
+			if s.notificationService.Notify(mention.UserId, mention.AuthorId, model.NOTIFY_MENTION, &mention.BlogId,
+				mention.CommentId) {
+				s.sendMentionMail(mention)
+			}
+		}
+
+		if len(mentions) < mentionNotifyBatch {
+			return
+		}
+	}
+}
+
+func (s *MentionService) sendMentionMail(mention model.PendingMention) {
+	where := fmt.Sprintf("their blog %q", mention.BlogTitle)
+
+	if mention.CommentId != nil {
+		where = fmt.Sprintf("a comment on %q", mention.BlogTitle)
+	}
+
+	link := s.site.URL + "/users/" + mention.BlogUsername + "/blogs/" + mention.BlogSlug
+
+	msg := mailer.Message{
+		To:      mention.Email,
+		Subject: mention.AuthorUsername + " mentioned you",
+		Body: fmt.Sprintf("Hi %s,\n\n@%s mentioned you in %s.\n\nRead it at %s\n",
+			mention.Username, mention.AuthorUsername, where, link),
+	}
+
+	// delivery happens in the background, a failure is only logged
+	go func() {
+		if err := s.mailer.Send(msg); err != nil {
+			log.Printf("Failed to deliver mention email: %v", err)
+		}
+	}()
+}
@@ -0,0 +1,242 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/harry713j/vibe_writer/internal/model"
+	"github.com/harry713j/vibe_writer/internal/repo"
+	"github.com/harry713j/vibe_writer/internal/utils"
+)
+
+const (
+	notificationPageLimit    = 20
+	maxNotificationPageLimit = 50
+	notificationActorPreview = 3 // actors listed on a grouped notification
+)
+
+var (
+	ErrNotificationNotExists   = errors.New("notification not exists")
+	ErrInvalidNotificationType = errors.New("invalid notification type")
+)
+
+type NotificationService struct {
+	notificationRepo *repo.NotificationRepository
+	userRepo         *repo.UserRepository
+}
+
+func NewNotificationService(notificationRepo *repo.NotificationRepository, userRepo *repo.UserRepository) *NotificationService {
+	return &NotificationService{
+		notificationRepo: notificationRepo,
+		userRepo:         userRepo,
+	}
+}
+
+// tell the user the actor did something, unless it's their own doing or they turned the type off.
+// The action that caused it already happened, so a failure is only logged. Reports whether the user
+// wants to hear about it, other ways of telling them follow the same preference
+func (s *NotificationService) Notify(userId, actorId uuid.UUID, kind model.NotificationType, blogId, commentId *int64) bool {
+	if userId == actorId || !s.isEnabled(userId, kind) {
+		return false
+	}
+
+	if err := s.notificationRepo.AddNotification(userId, actorId, kind, blogId, commentId, time.Now()); err != nil {
+		log.Printf("Failed to add %s notification: %v", kind, err)
+	}
+
+	return true
+}
+
+// whether the user wants to hear about the type, on when it can't be told
+func (s *NotificationService) isEnabled(userId uuid.UUID, kind model.NotificationType) bool {
+	enabled, err := s.notificationRepo.IsEnabled(userId, kind)
+
+	if err != nil {
+		log.Println("Failed to load notification preference: ", err)
+		return true
+	}
+
+	return enabled
+}
+
+// a page of the user's notifications latest activity first, cursor is empty for the first page
+func (s *NotificationService) GetNotifications(userId uuid.UUID, cursor string, limit int) (*model.NotificationPage, error) {
+	if _, err := s.userRepo.GetUserById(userId); err != nil {
+		return nil, ErrUserNotExists
+	}
+
+	if limit <= 0 || limit > maxNotificationPageLimit {
+		limit = notificationPageLimit
+	}
+
+	cursorTime, cursorId, err := decodeCursor(cursor)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// one extra row tells whether there is a next page
+	notifications, err := s.notificationRepo.GetNotifications(userId, cursorTime, cursorId, limit+1)
+
+	if err != nil {
+		return nil, err
+	}
+
+	page := &model.NotificationPage{}
+	page.Data = notifications
+
+	if len(notifications) > limit {
+		page.Data = notifications[:limit]
+		last := page.Data[limit-1]
+		page.NextCursor = utils.EncodeCursor(last.UpdatedAt, last.Id)
+	}
+
+	if page.Data == nil {
+		page.Data = []model.Notification{}
+	}
+
+	notificationIds := make([]int64, len(page.Data))
+
+	for i, notification := range page.Data {
+		notificationIds[i] = notification.Id
+	}
+
+	actors, err := s.notificationRepo.GetNotificationActors(notificationIds, notificationActorPreview)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range page.Data {
+		notification := &page.Data[i]
+		notification.Actors = actors[notification.Id]
+
+		if notification.Actors == nil {
+			notification.Actors = []model.NotificationActor{}
+		}
+
+		notification.Message = notificationMessage(notification)
+	}
+
+	if page.UnreadCount, err = s.notificationRepo.CountUnread(userId); err != nil {
+		return nil, err
+	}
+
+	return page, nil
+}
+
+// "alice", "alice and bob" or "alice and 11 others" followed by what they did
+func notificationMessage(notification *model.Notification) string {
+	who := "Someone"
+
+	if len(notification.Actors) > 0 {
+		who = notification.Actors[0].Username
+	}
+
+	switch {
+	case notification.ActorCount == 2 && len(notification.Actors) == 2:
+		who += " and " + notification.Actors[1].Username
+	case notification.ActorCount > 2:
+		who += fmt.Sprintf(" and %d others", notification.ActorCount-1)
+	}
+
+	title := ""
+
+	if notification.Blog != nil {
+		title = notification.Blog.Title
+	}
+
+	switch notification.Type {
+	case model.NOTIFY_BLOG_LIKE:
+		return fmt.Sprintf("%s liked your blog %q", who, title)
+	case model.NOTIFY_COMMENT:
+		return fmt.Sprintf("%s commented on your blog %q", who, title)
+	case model.NOTIFY_REPLY:
+		return fmt.Sprintf("%s replied to your comment on %q", who, title)
+	case model.NOTIFY_BOOKMARK:
+		return fmt.Sprintf("%s bookmarked your blog %q", who, title)
+	case model.NOTIFY_FOLLOW:
+		return who + " started following you"
+	case model.NOTIFY_MENTION:
+		if notification.CommentId != nil {
+			return fmt.Sprintf("%s mentioned you in a comment on %q", who, title)
+		}
+
+		return fmt.Sprintf("%s mentioned you in %q", who, title)
+	default:
+		return who + " did something"
+	}
+}
+
+func (s *NotificationService) MarkRead(userId uuid.UUID, notificationId int64) error {
+	found, err := s.notificationRepo.MarkRead(userId, notificationId, time.Now())
+
+	if err != nil {
+		return err
+	}
+
+	if !found {
+		return ErrNotificationNotExists
+	}
+
+	return nil
+}
+
+func (s *NotificationService) MarkAllRead(userId uuid.UUID) error {
+	return s.notificationRepo.MarkAllRead(userId, time.Now())
+}
+
+// every notification type with whether the user gets it
+func (s *NotificationService) GetPreferences(userId uuid.UUID) (map[model.NotificationType]bool, error) {
+	if _, err := s.userRepo.GetUserById(userId); err != nil {
+		return nil, ErrUserNotExists
+	}
+
+	stored, err := s.notificationRepo.GetPreferences(userId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	preferences := map[model.NotificationType]bool{}
+
+	for _, kind := range model.NotificationTypes {
+		enabled, ok := stored[kind]
+		preferences[kind] = enabled || !ok
+	}
+
+	return preferences, nil
+}
+
+// turn types on or off, the ones left out keep their setting
+func (s *NotificationService) UpdatePreferences(userId uuid.UUID,
+	preferences map[model.NotificationType]bool) (map[model.NotificationType]bool, error) {
+	if _, err := s.userRepo.GetUserById(userId); err != nil {
+		return nil, ErrUserNotExists
+	}
+
+	for kind := range preferences {
+		if !isNotificationType(kind) {
+			return nil, ErrInvalidNotificationType
+		}
+	}
+
+	if err := s.notificationRepo.SetPreferences(userId, preferences); err != nil {
+		return nil, err
+	}
+
+	return s.GetPreferences(userId)
+}
+
+func isNotificationType(kind model.NotificationType) bool {
+	for _, known := range model.NotificationTypes {
+		if kind == known {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,206 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/harry713j/vibe_writer/internal/mailer"
+	"github.com/harry713j/vibe_writer/internal/utils"
+)
+
+const (
+	passwordResetDelay = time.Minute
+	reauthWindow       = 10 * time.Minute
+)
+
+var (
+	ErrInvalidResetToken = errors.New("invalid or expired reset token")
+	ErrReauthRequired    = errors.New("confirm it's you with your password or a two factor code")
+)
+
+// mail a reset link when the email belongs to an account, unknown emails are ignored
+// silently so the response doesn't tell which addresses are registered
+func (service *AuthService) ForgotPassword(email string) error {
+	user, err := service.userRepo.GetUserByEmail(email)
+
+	if err != nil {
+		return nil
+	}
+
+	// one mail a minute is plenty, the newest link is the only one that works
+	latest, err := service.resetRepo.GetLatestReset(user.Id)
+
+	if err == nil && time.Since(latest.CreatedAt) < passwordResetDelay {
+		return nil
+	}
+
+	if err := service.resetRepo.DeleteUnusedResets(user.Id); err != nil {
+		return err
+	}
+
+	token, err := utils.RandomHex(64)
+
+	if err != nil {
+		return err
+	}
+
+	expireAt := time.Now().Add(service.config.ResetTokenTTL)
+
+	if err := service.resetRepo.CreateReset(user.Id, utils.HashToken(token), expireAt); err != nil {
+		return err
+	}
+
+	link := service.site.URL + "/reset-password?token=" + url.QueryEscape(token)
+
+	msg := mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hi %s,\n\nSomeone asked to reset the password of your account. Choose a new one here:\n\n%s\n\n"+
+			"The link works once and expires on %s. If it wasn't you, you can ignore this email.\n",
+			user.Username, link, expireAt.UTC().Format("Jan 2, 2006 15:04 MST")),
+	}
+
+	go func() {
+		if err := service.mailer.Send(msg); err != nil {
+			log.Println("Failed to deliver password reset email: ", err)
+		}
+	}()
+
+	return nil
+}
+
+// set a new password with a reset token and sign the user out everywhere
+func (service *AuthService) ResetPassword(token, newPassword string) error {
+	// check the password before spending the token on it
+	if err := utils.ValidatePassword(newPassword); err != nil {
+		return err
+	}
+
+	reset, err := service.resetRepo.UseReset(utils.HashToken(token), time.Now())
+
+	if err != nil {
+		return ErrInvalidResetToken
+	}
+
+	if err := service.setPassword(reset.UserId, newPassword); err != nil {
+		return err
+	}
+
+	_, err = service.sessionRepo.RevokeOtherSessions(reset.UserId, uuid.Nil, time.Now())
+
+	return err
+}
+
+// change the password of a logged in user, the current one has to match. Every other
+// session is signed out, the one making the change stays
+func (service *AuthService) ChangePassword(userId, sessionId uuid.UUID, currentPassword, newPassword string) error {
+	user, err := service.userRepo.GetUserById(userId)
+
+	if err != nil {
+		return ErrUserNotExists
+	}
+
+	if err := VerifyPassword(user.Password, currentPassword); err != nil {
+		return ErrWrongPassword
+	}
+
+	if err := utils.ValidatePassword(newPassword); err != nil {
+		return err
+	}
+
+	if err := service.setPassword(userId, newPassword); err != nil {
+		return err
+	}
+
+	_, err = service.sessionRepo.RevokeOtherSessions(userId, sessionId, time.Now())
+
+	return err
+}
+
+// proof beyond the access token for sensitive actions: the password, a code from the app when
+// 2FA is on, or for accounts that sign in with a provider a login in the last few minutes
+func (service *AuthService) Reauthenticate(userId, sessionId uuid.UUID, password, code string) error {
+	user, err := service.userRepo.GetUserById(userId)
+
+	if err != nil {
+		return ErrUserNotExists
+	}
+
+	if password != "" {
+		attempts := service.passwordAttempts(user, user.Username, "")
+
+		if err := service.reserveAttempts(attempts); err != nil {
+			return err
+		}
+
+		if err := VerifyPassword(user.Password, password); err != nil {
+			return ErrWrongPassword
+		}
+
+		service.resetAttempts(attempts)
+
+		return nil
+	}
+
+	if totp, err := service.twoFactorRepo.GetTOTP(userId); err == nil && totp.ConfirmedAt != nil {
+		if code == "" {
+			return ErrReauthRequired
+		}
+
+		attempts := service.codeAttempts(userId)
+
+		if err := service.reserveAttempts(attempts); err != nil {
+			return err
+		}
+
+		if err := service.checkTOTP(totp, code); err != nil {
+			if !errors.Is(err, ErrInvalidTwoFactorCode) {
+				service.releaseAttempts(attempts)
+			}
+
+			return err
+		}
+
+		service.resetAttempts(attempts)
+
+		return nil
+	}
+
+	// the password of a provider account is random, nobody knows it
+	social, err := service.identityRepo.HasIdentity(userId)
+
+	if err != nil {
+		return err
+	}
+
+	if !social {
+		return ErrReauthRequired
+	}
+
+	loginAt, err := service.sessionRepo.GetSessionCreatedAt(userId, sessionId)
+
+	if err != nil || time.Since(loginAt) > reauthWindow {
+		return ErrReauthRequired
+	}
+
+	return nil
+}
+
+func (service *AuthService) setPassword(userId uuid.UUID, password string) error {
+	hashedPassword, err := HashPassword(password)
+
+	if err != nil {
+		return err
+	}
+
+	if err := service.userRepo.UpdatePassword(userId, hashedPassword); err != nil {
+		return err
+	}
+
+	// an outstanding reset link shouldn't outlive the password it was meant for
+	return service.resetRepo.DeleteUnusedResets(userId)
+}
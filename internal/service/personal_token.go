@@ -0,0 +1,155 @@
+package service
+
+import (
+	"errors"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/harry713j/vibe_writer/internal/model"
+	"github.com/harry713j/vibe_writer/internal/utils"
+)
+
+// personal tokens carry this prefix so they can't be mistaken for a jwt
+const PersonalTokenPrefix = "vwp_"
+
+const maxPersonalTokens = 20
+
+var (
+	ErrTokenNameRequired      = errors.New("token name is required")
+	ErrInvalidScope           = errors.New("scopes must be one or more of blogs:read, blogs:write, comments:write, uploads:write")
+	ErrInvalidTokenExpiry     = errors.New("token expiry must be in the future")
+	ErrTooManyPersonalTokens  = errors.New("you can have at most 20 personal access tokens")
+	ErrPersonalTokenNotExists = errors.New("no personal access token exists with this id")
+	ErrInsufficientScope      = errors.New("token does not have the scope for this request")
+)
+
+var validScopes = []model.TokenScope{
+	model.SCOPE_BLOGS_READ,
+	model.SCOPE_BLOGS_WRITE,
+	model.SCOPE_COMMENTS_WRITE,
+	model.SCOPE_UPLOADS_WRITE,
+}
+
+func IsPersonalToken(token string) bool {
+	return strings.HasPrefix(token, PersonalTokenPrefix)
+}
+
+func (service *AuthService) CreatePersonalToken(userId uuid.UUID, name string, scopes []model.TokenScope,
+	expireAt *time.Time) (*model.CreatedPersonalAccessToken, error) {
+	name = strings.TrimSpace(name)
+
+	if name == "" {
+		return nil, ErrTokenNameRequired
+	}
+
+	if len(scopes) == 0 {
+		return nil, ErrInvalidScope
+	}
+
+	for _, scope := range scopes {
+		if !slices.Contains(validScopes, scope) {
+			return nil, ErrInvalidScope
+		}
+	}
+
+	slices.Sort(scopes)
+	scopes = slices.Compact(scopes)
+
+	if expireAt != nil && !expireAt.After(time.Now()) {
+		return nil, ErrInvalidTokenExpiry
+	}
+
+	tokens, err := service.personalTokenRepo.GetTokens(userId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(tokens) >= maxPersonalTokens {
+		return nil, ErrTooManyPersonalTokens
+	}
+
+	secret, err := utils.RandomHex(40)
+
+	if err != nil {
+		return nil, err
+	}
+
+	token := PersonalTokenPrefix + secret
+
+	created, err := service.personalTokenRepo.CreateToken(userId, name, utils.HashToken(token),
+		token[:len(PersonalTokenPrefix)+6], scopes, expireAt)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.CreatedPersonalAccessToken{PersonalAccessToken: *created, Token: token}, nil
+}
+
+func (service *AuthService) GetPersonalTokens(userId uuid.UUID) ([]model.PersonalAccessToken, error) {
+	tokens, err := service.personalTokenRepo.GetTokens(userId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if tokens == nil {
+		tokens = []model.PersonalAccessToken{}
+	}
+
+	return tokens, nil
+}
+
+func (service *AuthService) RevokePersonalToken(userId uuid.UUID, id int64) error {
+	deleted, err := service.personalTokenRepo.DeleteToken(userId, id)
+
+	if err != nil {
+		return err
+	}
+
+	if !deleted {
+		return ErrPersonalTokenNotExists
+	}
+
+	return nil
+}
+
+// the token behind an Authorization header, expired tokens and tokens of suspended
+// users are rejected
+func (service *AuthService) ValidatePersonalToken(tokenStr string) (*model.PersonalAccessToken, error) {
+	token, err := service.personalTokenRepo.GetTokenByHash(utils.HashToken(tokenStr))
+
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	now := time.Now()
+
+	if token.ExpireAt != nil && !token.ExpireAt.After(now) {
+		return nil, ErrExpiredToken
+	}
+
+	user, err := service.userRepo.GetUserById(token.UserId)
+
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	if user.SuspendedAt != nil {
+		return nil, ErrUserSuspended
+	}
+
+	if user.DeleteAt != nil {
+		return nil, ErrAccountDeletionScheduled
+	}
+
+	if err := service.personalTokenRepo.TouchToken(token.Id, now); err != nil {
+		log.Println("Failed to update personal token last use: ", err)
+	}
+
+	return token, nil
+}
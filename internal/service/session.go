@@ -0,0 +1,60 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/harry713j/vibe_writer/internal/model"
+)
+
+var (
+	ErrSessionNotExists = errors.New("no active session exists with this id")
+)
+
+// devices the user is signed in on, currentId is flagged as the one asking
+func (service *AuthService) GetSessions(userId, currentId uuid.UUID) ([]model.Session, error) {
+	if _, err := service.userRepo.GetUserById(userId); err != nil {
+		return nil, ErrUserNotExists
+	}
+
+	sessions, err := service.sessionRepo.GetActiveSessions(userId, time.Now())
+
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range sessions {
+		sessions[i].Current = sessions[i].Id == currentId
+	}
+
+	if sessions == nil {
+		sessions = []model.Session{}
+	}
+
+	return sessions, nil
+}
+
+// sign one device out, access tokens it holds keep working until they expire
+func (service *AuthService) RevokeSession(userId, sessionId uuid.UUID) error {
+	revoked, err := service.sessionRepo.RevokeSession(userId, sessionId, time.Now())
+
+	if err != nil {
+		return err
+	}
+
+	if !revoked {
+		return ErrSessionNotExists
+	}
+
+	return nil
+}
+
+// sign out every device but the current one, returns how many were signed out
+func (service *AuthService) RevokeOtherSessions(userId, currentId uuid.UUID) (int, error) {
+	if _, err := service.userRepo.GetUserById(userId); err != nil {
+		return 0, ErrUserNotExists
+	}
+
+	return service.sessionRepo.RevokeOtherSessions(userId, currentId, time.Now())
+}
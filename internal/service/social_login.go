@@ -0,0 +1,352 @@
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/harry713j/vibe_writer/internal/identity"
+	"github.com/harry713j/vibe_writer/internal/model"
+	"github.com/harry713j/vibe_writer/internal/repo"
+	"github.com/harry713j/vibe_writer/internal/utils"
+	"golang.org/x/oauth2"
+)
+
+const (
+	socialLoginPurpose = "oidc_login"
+	socialLoginTTL     = 10 * time.Minute
+	maxUsernameLength  = 20
+)
+
+var (
+	ErrUnknownProvider      = errors.New("no login provider exists with this name")
+	ErrInvalidSocialLogin   = errors.New("login expired or was started in another browser, please try again")
+	ErrSocialEmailRequired  = errors.New("the provider did not share an email address")
+	ErrSocialEmailConflict  = errors.New("an account with this email already exists, log in with your password")
+	ErrSocialProviderFailed = errors.New("could not complete the login with the provider")
+)
+
+// what social login reads and writes of the accounts, the repositories in production
+type socialLoginUsers interface {
+	CreateUser(username, email, password string) (*model.User, error)
+	GetUserById(id uuid.UUID) (*model.User, error)
+	GetUserByUsername(username string) (*model.User, error)
+	GetUserByEmail(email string) (*model.User, error)
+	MarkEmailVerified(userId uuid.UUID, email string) error
+	IsUsernameHeld(username string, exceptUserId uuid.UUID, now time.Time) (bool, error)
+}
+
+type socialLoginProfiles interface {
+	CreateUserProfile(userId uuid.UUID) (*model.UserProfile, error)
+	UpdateProfile(userId uuid.UUID, fullName, bio string) error
+}
+
+type socialLoginIdentities interface {
+	GetIdentity(provider, subject string) (*model.UserIdentity, error)
+	CreateIdentity(userId uuid.UUID, provider, subject, email string) error
+	TouchIdentity(id int64, email string, now time.Time) error
+}
+
+type SocialLoginService struct {
+	authService  *AuthService
+	userRepo     socialLoginUsers
+	profileRepo  socialLoginProfiles
+	identityRepo socialLoginIdentities
+	providers    *identity.Registry
+}
+
+func NewSocialLoginService(authService *AuthService, userRepo *repo.UserRepository, profileRepo *repo.UserProfileRepository,
+	identityRepo *repo.UserIdentityRepository, providers *identity.Registry) *SocialLoginService {
+	return &SocialLoginService{
+		authService:  authService,
+		userRepo:     userRepo,
+		profileRepo:  profileRepo,
+		identityRepo: identityRepo,
+		providers:    providers,
+	}
+}
+
+func (s *SocialLoginService) GetProviders() []string {
+	names := s.providers.Names()
+
+	if names == nil {
+		names = []string{}
+	}
+
+	return names
+}
+
+// start the authorization code flow. The returned state token holds the state, nonce and
+// PKCE verifier of the login and has to come back with the callback, the handler keeps it
+// in a cookie so only the browser that started the login can finish it
+func (s *SocialLoginService) BeginLogin(ctx context.Context, providerName string) (authUrl, stateToken string, err error) {
+	provider, ok := s.providers.Get(providerName)
+
+	if !ok {
+		return "", "", ErrUnknownProvider
+	}
+
+	state, err := utils.RandomHex(32)
+
+	if err != nil {
+		return "", "", err
+	}
+
+	nonce, err := utils.RandomHex(32)
+
+	if err != nil {
+		return "", "", err
+	}
+
+	verifier := oauth2.GenerateVerifier()
+
+	authUrl, err = provider.AuthCodeURL(ctx, state, nonce, verifier)
+
+	if err != nil {
+		log.Printf("OIDC discovery for %s failed: %v\n", providerName, err)
+		return "", "", ErrSocialProviderFailed
+	}
+
+	now := time.Now()
+
+	stateToken, err = s.authService.signToken(jwt.MapClaims{
+		"purpose":  socialLoginPurpose,
+		"provider": providerName,
+		"state":    state,
+		"nonce":    nonce,
+		"verifier": verifier,
+		"iat":      now.Unix(),
+		"exp":      now.Add(socialLoginTTL).Unix(),
+	})
+
+	if err != nil {
+		return "", "", err
+	}
+
+	return authUrl, stateToken, nil
+}
+
+// finish the login the provider sent back, the account is found by the identity, linked by
+// verified email or created
+func (s *SocialLoginService) CompleteLogin(ctx context.Context, providerName, stateToken, state, code, userAgent,
+	ipAddress string) (*model.LoginResult, error) {
+	user, err := s.authenticate(ctx, providerName, stateToken, state, code)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return s.authService.finishLogin(user, userAgent, ipAddress)
+}
+
+// the account the provider vouches for, the callback has to belong to the login the state token started
+func (s *SocialLoginService) authenticate(ctx context.Context, providerName, stateToken, state,
+	code string) (*model.User, error) {
+	provider, ok := s.providers.Get(providerName)
+
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+
+	claims, err := s.authService.parseToken(stateToken)
+
+	if err != nil {
+		return nil, ErrInvalidSocialLogin
+	}
+
+	purpose, _ := claims["purpose"].(string)
+	tokenProvider, _ := claims["provider"].(string)
+	expectedState, _ := claims["state"].(string)
+	nonce, _ := claims["nonce"].(string)
+	verifier, _ := claims["verifier"].(string)
+
+	if purpose != socialLoginPurpose || tokenProvider != providerName || expectedState == "" ||
+		subtle.ConstantTimeCompare([]byte(expectedState), []byte(state)) != 1 {
+		return nil, ErrInvalidSocialLogin
+	}
+
+	external, err := provider.Exchange(ctx, code, verifier, nonce)
+
+	if err != nil {
+		log.Printf("OIDC login with %s failed: %v\n", providerName, err)
+		return nil, ErrSocialProviderFailed
+	}
+
+	return s.resolveUser(external)
+}
+
+func (s *SocialLoginService) resolveUser(external *identity.Identity) (*model.User, error) {
+	email := strings.ToLower(strings.TrimSpace(external.Email))
+	linked, err := s.identityRepo.GetIdentity(external.Provider, external.Subject)
+
+	if err == nil {
+		if err := s.identityRepo.TouchIdentity(linked.Id, email, time.Now()); err != nil {
+			log.Println("Failed to update identity: ", err)
+		}
+
+		user, err := s.userRepo.GetUserById(linked.UserId)
+
+		if err != nil {
+			return nil, ErrUserNotExists
+		}
+
+		return user, nil
+	}
+
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	if email == "" {
+		return nil, ErrSocialEmailRequired
+	}
+
+	user, err := s.userRepo.GetUserByEmail(email)
+
+	switch {
+	case err == nil:
+		// both sides must have proven the address, otherwise whoever registered it first
+		// without owning it could take over the other account
+		if !external.EmailVerified || user.EmailVerifiedAt == nil {
+			return nil, ErrSocialEmailConflict
+		}
+	case errors.Is(err, sql.ErrNoRows):
+		user, err = s.createUser(external, email)
+
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, err
+	}
+
+	if err := s.identityRepo.CreateIdentity(user.Id, external.Provider, external.Subject, email); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// the account gets a random password, the user can set one with the password reset
+func (s *SocialLoginService) createUser(external *identity.Identity, email string) (*model.User, error) {
+	if err := utils.ValidateEmail(email); err != nil {
+		return nil, err
+	}
+
+	username, err := s.generateUsername(external, email)
+
+	if err != nil {
+		return nil, err
+	}
+
+	password, err := utils.RandomHex(64)
+
+	if err != nil {
+		return nil, err
+	}
+
+	hashedPassword, err := HashPassword(password)
+
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.CreateUser(username, email, hashedPassword)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.profileRepo.CreateUserProfile(user.Id); err != nil {
+		return nil, err
+	}
+
+	if external.Name != "" {
+		if err := s.profileRepo.UpdateProfile(user.Id, external.Name, ""); err != nil {
+			log.Println("Failed to set profile name: ", err)
+		}
+	}
+
+	if external.EmailVerified {
+		if err := s.userRepo.MarkEmailVerified(user.Id, email); err != nil {
+			return nil, err
+		}
+
+		now := time.Now()
+		user.EmailVerifiedAt = &now
+	} else if err := s.authService.sendVerificationEmail(user); err != nil {
+		log.Println("Failed to send verification email: ", err)
+	}
+
+	return user, nil
+}
+
+// a free username from the provider's username, the email or the name, cut down to what
+// utils.ValidateUsername allows and numbered when taken
+func (s *SocialLoginService) generateUsername(external *identity.Identity, email string) (string, error) {
+	localPart, _, _ := strings.Cut(email, "@")
+	base := ""
+
+	for _, candidate := range []string{external.PreferredUsername, localPart, external.Name} {
+		if base = usernameBase(candidate); base != "" {
+			break
+		}
+	}
+
+	if len(base) < 4 {
+		base = "user" + base
+	}
+
+	for attempt := 0; attempt < 20; attempt++ {
+		username := base
+
+		if attempt > 0 {
+			suffix := fmt.Sprintf("%d", rand.IntN(9000)+1000)
+			username = base[:min(len(base), maxUsernameLength-len(suffix))] + suffix
+		}
+
+		if utils.ValidateUsername(username) != nil {
+			continue
+		}
+
+		if _, err := s.userRepo.GetUserByUsername(username); err == nil {
+			continue
+		} else if !errors.Is(err, sql.ErrNoRows) {
+			return "", err
+		}
+
+		held, err := s.userRepo.IsUsernameHeld(username, uuid.Nil, time.Now())
+
+		if err != nil {
+			return "", err
+		}
+
+		if !held {
+			return username, nil
+		}
+	}
+
+	return "", ErrUsernameExists
+}
+
+func usernameBase(value string) string {
+	var builder strings.Builder
+
+	for _, r := range strings.ToLower(value) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			builder.WriteRune(r)
+		}
+	}
+
+	base := builder.String()
+
+	return base[:min(len(base), maxUsernameLength)]
+}
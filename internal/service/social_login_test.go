@@ -0,0 +1,406 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/harry713j/vibe_writer/internal/config"
+	"github.com/harry713j/vibe_writer/internal/identity"
+	"github.com/harry713j/vibe_writer/internal/identity/oidctest"
+	"github.com/harry713j/vibe_writer/internal/keyring"
+	"github.com/harry713j/vibe_writer/internal/model"
+	"github.com/harry713j/vibe_writer/internal/utils"
+)
+
+// accounts kept in memory in place of the repositories
+type fakeAccounts struct {
+	users      map[uuid.UUID]*model.User
+	identities []model.UserIdentity
+	profiles   map[uuid.UUID]string
+	held       map[string]bool
+}
+
+func newFakeAccounts() *fakeAccounts {
+	return &fakeAccounts{
+		users:    map[uuid.UUID]*model.User{},
+		profiles: map[uuid.UUID]string{},
+		held:     map[string]bool{},
+	}
+}
+
+func (f *fakeAccounts) addUser(username, email string, verified bool) *model.User {
+	user := &model.User{Id: uuid.New(), Username: username, Email: email}
+
+	if verified {
+		now := time.Now()
+		user.EmailVerifiedAt = &now
+	}
+
+	f.users[user.Id] = user
+
+	return user
+}
+
+func (f *fakeAccounts) CreateUser(username, email, password string) (*model.User, error) {
+	user := f.addUser(username, email, false)
+	user.Password = password
+
+	return user, nil
+}
+
+func (f *fakeAccounts) GetUserById(id uuid.UUID) (*model.User, error) {
+	if user, ok := f.users[id]; ok {
+		return user, nil
+	}
+
+	return nil, sql.ErrNoRows
+}
+
+func (f *fakeAccounts) GetUserByUsername(username string) (*model.User, error) {
+	for _, user := range f.users {
+		if user.Username == username {
+			return user, nil
+		}
+	}
+
+	return nil, sql.ErrNoRows
+}
+
+func (f *fakeAccounts) GetUserByEmail(email string) (*model.User, error) {
+	for _, user := range f.users {
+		if user.Email == email {
+			return user, nil
+		}
+	}
+
+	return nil, sql.ErrNoRows
+}
+
+func (f *fakeAccounts) MarkEmailVerified(userId uuid.UUID, email string) error {
+	now := time.Now()
+	f.users[userId].EmailVerifiedAt = &now
+
+	return nil
+}
+
+func (f *fakeAccounts) IsUsernameHeld(username string, exceptUserId uuid.UUID, now time.Time) (bool, error) {
+	return f.held[username], nil
+}
+
+func (f *fakeAccounts) CreateUserProfile(userId uuid.UUID) (*model.UserProfile, error) {
+	f.profiles[userId] = ""
+
+	return &model.UserProfile{UserId: userId}, nil
+}
+
+func (f *fakeAccounts) UpdateProfile(userId uuid.UUID, fullName, bio string) error {
+	f.profiles[userId] = fullName
+
+	return nil
+}
+
+func (f *fakeAccounts) GetIdentity(provider, subject string) (*model.UserIdentity, error) {
+	for _, identity := range f.identities {
+		if identity.Provider == provider && identity.Subject == subject {
+			return &identity, nil
+		}
+	}
+
+	return nil, sql.ErrNoRows
+}
+
+func (f *fakeAccounts) CreateIdentity(userId uuid.UUID, provider, subject, email string) error {
+	f.identities = append(f.identities, model.UserIdentity{
+		Id:       int64(len(f.identities) + 1),
+		UserId:   userId,
+		Provider: provider,
+		Subject:  subject,
+		Email:    email,
+	})
+
+	return nil
+}
+
+func (f *fakeAccounts) TouchIdentity(id int64, email string, now time.Time) error {
+	return nil
+}
+
+func newTestSocialLogin(t *testing.T, accounts *fakeAccounts) (*SocialLoginService, *oidctest.Server) {
+	t.Helper()
+
+	server, err := oidctest.NewServer("vibewriter", "secret")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(server.Close)
+
+	keys, err := keyring.New(keyring.NewHMACKey("test", []byte("a secret long enough for the test key")))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	providers := identity.NewRegistry(&config.OIDCConfig{
+		CallbackURL: "http://api.test",
+		Providers: []config.OIDCProviderConfig{
+			{Name: "mock", Issuer: server.URL, ClientID: "vibewriter", ClientSecret: "secret"},
+		},
+	})
+
+	return &SocialLoginService{
+		authService:  &AuthService{keyring: keys},
+		userRepo:     accounts,
+		profileRepo:  accounts,
+		identityRepo: accounts,
+		providers:    providers,
+	}, server
+}
+
+// the login up to the account, through the mock provider the way a browser would go
+func loginThroughMock(t *testing.T, s *SocialLoginService, server *oidctest.Server, user oidctest.User) (*model.User, error) {
+	t.Helper()
+	ctx := context.Background()
+
+	authUrl, stateToken, err := s.BeginLogin(ctx, "mock")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	callback, err := server.Login(authUrl, user)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	query := callback.Query()
+
+	return s.authenticate(ctx, "mock", stateToken, query.Get("state"), query.Get("code"))
+}
+
+func TestSocialLoginAuthenticate(t *testing.T) {
+	tests := []struct {
+		name string
+		// accounts before the login, returns the user the login has to end up with or nil
+		setup    func(accounts *fakeAccounts) *model.User
+		user     oidctest.User
+		wantErr  error
+		wantName string
+	}{
+		{
+			name:     "new account",
+			user:     oidctest.User{Email: "Reader@Example.com", EmailVerified: true, Name: "Ada Reader", PreferredUsername: "ada"},
+			wantName: "userada",
+		},
+		{
+			name: "linked identity",
+			setup: func(accounts *fakeAccounts) *model.User {
+				user := accounts.addUser("reader", "old@example.com", true)
+				accounts.CreateIdentity(user.Id, "mock", oidctest.Subject("reader@example.com"), "old@example.com")
+				return user
+			},
+			// the identity decides, not the email
+			user: oidctest.User{Email: "reader@example.com", EmailVerified: false},
+		},
+		{
+			name: "verified email on both sides links",
+			setup: func(accounts *fakeAccounts) *model.User {
+				return accounts.addUser("reader", "reader@example.com", true)
+			},
+			user: oidctest.User{Email: "reader@example.com", EmailVerified: true},
+		},
+		{
+			name: "email the provider didn't verify",
+			setup: func(accounts *fakeAccounts) *model.User {
+				accounts.addUser("reader", "reader@example.com", true)
+				return nil
+			},
+			user:    oidctest.User{Email: "reader@example.com", EmailVerified: false},
+			wantErr: ErrSocialEmailConflict,
+		},
+		{
+			name: "email the account never verified",
+			setup: func(accounts *fakeAccounts) *model.User {
+				accounts.addUser("reader", "reader@example.com", false)
+				return nil
+			},
+			user:    oidctest.User{Email: "reader@example.com", EmailVerified: true},
+			wantErr: ErrSocialEmailConflict,
+		},
+		{
+			name:    "no email",
+			user:    oidctest.User{EmailVerified: true},
+			wantErr: ErrSocialEmailRequired,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			accounts := newFakeAccounts()
+			var want *model.User
+
+			if tt.setup != nil {
+				want = tt.setup(accounts)
+			}
+
+			s, server := newTestSocialLogin(t, accounts)
+			before := len(accounts.users)
+
+			got, err := loginThroughMock(t, s, server, tt.user)
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("authenticate() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr != nil {
+				if len(accounts.users) != before {
+					t.Errorf("a rejected login created an account")
+				}
+				return
+			}
+
+			if want != nil && got.Id != want.Id {
+				t.Errorf("authenticate() = user %s, want %s", got.Username, want.Username)
+			}
+
+			if tt.wantName != "" {
+				if got.Username != tt.wantName || got.Email != "reader@example.com" || got.EmailVerifiedAt == nil {
+					t.Errorf("authenticate() created %+v", got)
+				}
+
+				if accounts.profiles[got.Id] != tt.user.Name {
+					t.Errorf("profile name = %q, want %q", accounts.profiles[got.Id], tt.user.Name)
+				}
+			}
+
+			linked, err := accounts.GetIdentity("mock", oidctest.Subject(tt.user.Email))
+
+			if err != nil || linked.UserId != got.Id {
+				t.Errorf("identity is not linked to the user")
+			}
+		})
+	}
+}
+
+func TestSocialLoginRejectsForeignState(t *testing.T) {
+	accounts := newFakeAccounts()
+	s, server := newTestSocialLogin(t, accounts)
+	ctx := context.Background()
+
+	authUrl, stateToken, err := s.BeginLogin(ctx, "mock")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	callback, err := server.Login(authUrl, oidctest.User{Email: "reader@example.com", EmailVerified: true})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a callback of another browser's login
+	_, otherToken, err := s.BeginLogin(ctx, "mock")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	code := callback.Query().Get("code")
+
+	if _, err := s.authenticate(ctx, "mock", otherToken, callback.Query().Get("state"), code); !errors.Is(err, ErrInvalidSocialLogin) {
+		t.Errorf("authenticate() with another state token error = %v, want %v", err, ErrInvalidSocialLogin)
+	}
+
+	if _, err := s.authenticate(ctx, "mock", stateToken, "forged", code); !errors.Is(err, ErrInvalidSocialLogin) {
+		t.Errorf("authenticate() with another state error = %v, want %v", err, ErrInvalidSocialLogin)
+	}
+
+	if len(accounts.users) != 0 {
+		t.Errorf("a rejected login created an account")
+	}
+}
+
+func TestGenerateUsername(t *testing.T) {
+	tests := []struct {
+		name     string
+		taken    []string
+		held     []string
+		external identity.Identity
+		email    string
+		// the exact name, or the start of a numbered one when taken
+		want     string
+		numbered bool
+	}{
+		{name: "preferred username", external: identity.Identity{PreferredUsername: "Ada.Lovelace"}, email: "x@example.com",
+			want: "adalovelace"},
+		{name: "email local part", email: "ada_reader@example.com", want: "adareader"},
+		{name: "name when nothing else fits", external: identity.Identity{Name: "Ada Reader"}, email: "__@example.com",
+			want: "adareader"},
+		{name: "short base", email: "ab@example.com", want: "userab"},
+		{name: "cut to the limit", email: "averyveryveryverylongname@example.com", want: "averyveryveryverylon"},
+		{name: "taken", taken: []string{"adareader"}, email: "adareader@example.com", want: "adareader", numbered: true},
+		{name: "held by a rename", held: []string{"adareader"}, email: "adareader@example.com", want: "adareader",
+			numbered: true},
+		{name: "taken at the limit", taken: []string{"averyveryveryverylon"}, email: "averyveryveryverylongname@example.com",
+			want: "averyveryveryver", numbered: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			accounts := newFakeAccounts()
+
+			for _, username := range tt.taken {
+				accounts.addUser(username, username+"@example.org", true)
+			}
+
+			for _, username := range tt.held {
+				accounts.held[username] = true
+			}
+
+			s := &SocialLoginService{userRepo: accounts}
+
+			got, err := s.generateUsername(&tt.external, tt.email)
+
+			if err != nil {
+				t.Fatalf("generateUsername() error = %v", err)
+			}
+
+			if err := utils.ValidateUsername(got); err != nil || len(got) > maxUsernameLength {
+				t.Fatalf("generateUsername() = %q, not a valid username", got)
+			}
+
+			if !tt.numbered && got != tt.want {
+				t.Errorf("generateUsername() = %q, want %q", got, tt.want)
+			}
+
+			if tt.numbered && (!strings.HasPrefix(got, tt.want) || len(got) != len(tt.want)+4) {
+				t.Errorf("generateUsername() = %q, want %q with a number", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerateUsernameGivesUp(t *testing.T) {
+	accounts := newFakeAccounts()
+	accounts.addUser("adareader", "adareader@example.org", true)
+
+	// every numbered name is held too
+	for i := 1000; i < 10000; i++ {
+		accounts.held["adareader"+strconv.Itoa(i)] = true
+	}
+
+	s := &SocialLoginService{userRepo: accounts}
+
+	if _, err := s.generateUsername(&identity.Identity{}, "adareader@example.com"); !errors.Is(err, ErrUsernameExists) {
+		t.Errorf("generateUsername() error = %v, want %v", err, ErrUsernameExists)
+	}
+}
@@ -0,0 +1,174 @@
+package service
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/harry713j/vibe_writer/internal/config"
+	"github.com/harry713j/vibe_writer/internal/model"
+	"github.com/harry713j/vibe_writer/internal/repo"
+	"github.com/harry713j/vibe_writer/internal/utils"
+)
+
+const (
+	feedItemLimit   = 20
+	feedSummarySize = 300
+)
+
+// builds RSS and Atom feeds out of public blogs
+type SyndicationService struct {
+	userRepo    *repo.UserRepository
+	profileRepo *repo.UserProfileRepository
+	blogRepo    *repo.BlogRepository
+	tagRepo     *repo.TagRepository
+	site        *config.SiteConfig
+}
+
+func NewSyndicationService(userRepo *repo.UserRepository, profileRepo *repo.UserProfileRepository,
+	blogRepo *repo.BlogRepository, tagRepo *repo.TagRepository, site *config.SiteConfig) *SyndicationService {
+	return &SyndicationService{
+		userRepo:    userRepo,
+		profileRepo: profileRepo,
+		blogRepo:    blogRepo,
+		tagRepo:     tagRepo,
+		site:        site,
+	}
+}
+
+// latest public blogs of an author
+func (s *SyndicationService) AuthorFeed(username string) (*model.Feed, error) {
+	user, err := s.userRepo.GetUserByUsername(username)
+
+	if err != nil {
+		return nil, ErrUserNotExists
+	}
+
+	author, err := s.profileRepo.GetUserDetails(user.Id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	blogs, err := s.blogRepo.GetAllPublicBlog(user.Id, 1, feedItemLimit)
+
+	if err != nil {
+		return nil, err
+	}
+
+	authorName := author.FullName
+	if authorName == "" {
+		authorName = author.Username
+	}
+
+	for i := range blogs.Data {
+		blogs.Data[i].AuthorUsername = author.Username
+		blogs.Data[i].AuthorName = authorName
+	}
+
+	description := author.Bio
+	if description == "" {
+		description = "Latest posts by " + authorName
+	}
+
+	feed := &model.Feed{
+		Id:          "urn:vibewriter:user:" + user.Id.String(),
+		Title:       authorName + " on " + s.site.Title,
+		Link:        s.authorLink(author.Username),
+		Description: description,
+		AuthorName:  authorName,
+		AuthorLink:  s.authorLink(author.Username),
+		Image:       author.AvatarUrl,
+		Updated:     user.CreatedAt,
+	}
+
+	s.addItems(feed, blogs.Data)
+
+	return feed, nil
+}
+
+// latest public blogs carrying a tag
+func (s *SyndicationService) TagFeed(tag string) (*model.Feed, error) {
+	found, err := s.tagRepo.GetTagBySlug(utils.Slugify(tag))
+
+	if err != nil {
+		return nil, ErrTagNotExists
+	}
+
+	blogs, err := s.blogRepo.GetPublicBlogsByTag(found.Id, 1, feedItemLimit)
+
+	if err != nil {
+		return nil, err
+	}
+
+	feed := &model.Feed{
+		Id:          "urn:vibewriter:tag:" + found.Slug,
+		Title:       "#" + found.Name + " on " + s.site.Title,
+		Link:        s.site.URL + "/tags/" + found.Slug,
+		Description: "Latest posts tagged " + found.Name,
+	}
+
+	s.addItems(feed, blogs.Data)
+
+	return feed, nil
+}
+
+// turn blogs into feed items and move the feed update time to the newest change
+func (s *SyndicationService) addItems(feed *model.Feed, blogs []model.BlogSummary) {
+	for _, blog := range blogs {
+		published := blog.CreatedAt
+		if blog.PublishAt != nil {
+			published = *blog.PublishAt
+		}
+
+		updated := published
+		if blog.UpdatedAt != nil && blog.UpdatedAt.After(updated) {
+			updated = *blog.UpdatedAt
+		}
+
+		if updated.After(feed.Updated) {
+			feed.Updated = updated
+		}
+
+		var categories []string
+		for _, tag := range blog.Tags {
+			categories = append(categories, tag.Name)
+		}
+
+		authorName := blog.AuthorName
+		if authorName == "" {
+			authorName = blog.AuthorUsername
+		}
+
+		feed.Items = append(feed.Items, model.FeedItem{
+			Id:         "urn:vibewriter:blog:" + strconv.FormatInt(blog.Id, 10),
+			Title:      blog.Title,
+			Link:       s.authorLink(blog.AuthorUsername) + "/blogs/" + blog.Slug,
+			Summary:    summarize(blog.Content, feedSummarySize),
+			AuthorName: authorName,
+			Categories: categories,
+			Published:  published,
+			Updated:    updated,
+		})
+	}
+
+	// an empty feed has nothing to date it by, keep it stable for caching
+	if feed.Updated.IsZero() {
+		feed.Updated = time.Unix(0, 0)
+	}
+}
+
+func (s *SyndicationService) authorLink(username string) string {
+	return s.site.URL + "/users/" + username
+}
+
+// first size characters of the text on a single line
+func summarize(content string, size int) string {
+	text := []rune(strings.Join(strings.Fields(content), " "))
+
+	if len(text) <= size {
+		return string(text)
+	}
+
+	return strings.TrimSpace(string(text[:size])) + "…"
+}
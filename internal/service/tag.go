@@ -0,0 +1,76 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/harry713j/vibe_writer/internal/model"
+	"github.com/harry713j/vibe_writer/internal/repo"
+	"github.com/harry713j/vibe_writer/internal/utils"
+)
+
+const (
+	maxBlogTags  = 5
+	maxTagLength = 32
+)
+
+var (
+	ErrInvalidTag   = errors.New("a tag needs at least one letter or number and can be up to 32 characters")
+	ErrTooManyTags  = errors.New("a blog can have at most 5 tags")
+	ErrTagNotExists = errors.New("no tag exists with this name")
+)
+
+type TagService struct {
+	tagRepo  *repo.TagRepository
+	blogRepo *repo.BlogRepository
+}
+
+func NewTagService(tagRepo *repo.TagRepository, blogRepo *repo.BlogRepository) *TagService {
+	return &TagService{
+		tagRepo:  tagRepo,
+		blogRepo: blogRepo,
+	}
+}
+
+func (s *TagService) GetAllTags(page, limit int) (*model.PaginatedResponse[model.TagWithCount], error) {
+	return s.tagRepo.GetAllTags(page, limit)
+}
+
+func (s *TagService) GetBlogsByTag(tag string, page, limit int) (*model.PaginatedResponse[model.BlogSummary], error) {
+	found, err := s.tagRepo.GetTagBySlug(utils.Slugify(tag))
+
+	if err != nil {
+		return nil, ErrTagNotExists
+	}
+
+	return s.blogRepo.GetPublicBlogsByTag(found.Id, page, limit)
+}
+
+// trimmed, de-duplicated tags with their slugs, never nil so an empty list clears the tags
+func normalizeTags(tags []string) ([]model.Tag, error) {
+	seen := make(map[string]bool)
+	normalized := []model.Tag{}
+
+	for _, name := range tags {
+		name = strings.Join(strings.Fields(name), " ")
+		slug := utils.Slugify(name)
+
+		if slug == "" || utf8.RuneCountInString(name) > maxTagLength {
+			return nil, ErrInvalidTag
+		}
+
+		if seen[slug] {
+			continue
+		}
+
+		seen[slug] = true
+		normalized = append(normalized, model.Tag{Name: name, Slug: slug})
+	}
+
+	if len(normalized) > maxBlogTags {
+		return nil, ErrTooManyTags
+	}
+
+	return normalized, nil
+}
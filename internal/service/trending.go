@@ -0,0 +1,58 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/harry713j/vibe_writer/internal/config"
+	"github.com/harry713j/vibe_writer/internal/model"
+	"github.com/harry713j/vibe_writer/internal/repo"
+)
+
+// bookmarks and comments take more effort than a like, so they count more
+var trendingWeights = repo.TrendingWeights{
+	Like:     1,
+	Dislike:  1,
+	Comment:  2,
+	Bookmark: 3,
+}
+
+type TrendingService struct {
+	trendingRepo *repo.TrendingRepository
+	config       *config.TrendingConfig
+}
+
+func NewTrendingService(trendingRepo *repo.TrendingRepository, config *config.TrendingConfig) *TrendingService {
+	return &TrendingService{
+		trendingRepo: trendingRepo,
+		config:       config,
+	}
+}
+
+func (s *TrendingService) GetTrendingBlogs(page, limit int) (*model.PaginatedResponse[model.BlogSummary], error) {
+	return s.trendingRepo.GetTrending(page, limit)
+}
+
+func (s *TrendingService) RefreshScores() error {
+	now := time.Now()
+	return s.trendingRepo.RefreshScores(now, now.Add(-s.config.Window), s.config.HalfLife, trendingWeights)
+}
+
+// recompute the scores right away and then on every refresh interval until ctx is done
+func (s *TrendingService) RunRefresher(ctx context.Context) {
+	ticker := time.NewTicker(s.config.RefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.RefreshScores(); err != nil {
+			log.Println("Failed to refresh trending scores: ", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,235 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/harry713j/vibe_writer/internal/model"
+	"github.com/harry713j/vibe_writer/internal/utils"
+)
+
+const (
+	mfaChallengeTTL   = 5 * time.Minute
+	recoveryCodeCount = 10
+)
+
+var (
+	ErrTwoFactorEnabled     = errors.New("two factor authentication is already enabled")
+	ErrTwoFactorNotEnabled  = errors.New("two factor authentication is not enabled")
+	ErrTwoFactorNotPending  = errors.New("start two factor enrollment first")
+	ErrInvalidTwoFactorCode = errors.New("invalid two factor code")
+	ErrInvalidMfaToken      = errors.New("invalid or expired mfa token")
+)
+
+// new secret for the authenticator app, 2FA stays off until ConfirmTOTP gets a code from it
+func (service *AuthService) EnrollTOTP(userId uuid.UUID) (*model.TOTPEnrollment, error) {
+	user, err := service.userRepo.GetUserById(userId)
+
+	if err != nil {
+		return nil, ErrUserNotExists
+	}
+
+	if totp, err := service.twoFactorRepo.GetTOTP(userId); err == nil && totp.ConfirmedAt != nil {
+		return nil, ErrTwoFactorEnabled
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := service.twoFactorRepo.UpsertPendingTOTP(userId, secret); err != nil {
+		return nil, err
+	}
+
+	return &model.TOTPEnrollment{
+		Secret:     secret,
+		OtpauthUri: utils.TOTPURI(service.site.Title, user.Username, secret),
+	}, nil
+}
+
+// turn 2FA on with the first code from the app, returns the recovery codes, they are shown only this once
+func (service *AuthService) ConfirmTOTP(userId uuid.UUID, code string) ([]string, error) {
+	totp, err := service.twoFactorRepo.GetTOTP(userId)
+
+	if err != nil {
+		return nil, ErrTwoFactorNotPending
+	}
+
+	if totp.ConfirmedAt != nil {
+		return nil, ErrTwoFactorEnabled
+	}
+
+	if err := service.checkTOTP(totp, code); err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+
+	for range recoveryCodeCount {
+		// 10 random bytes, in groups of five hex digits
+		code, err := utils.RandomHex(20)
+
+		if err != nil {
+			return nil, err
+		}
+
+		code = code[:5] + "-" + code[5:10] + "-" + code[10:15] + "-" + code[15:]
+		codes = append(codes, code)
+		hashes = append(hashes, hashRecoveryCode(code))
+	}
+
+	if err := service.twoFactorRepo.ConfirmTOTP(userId, hashes, time.Now()); err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// turn 2FA off, a code from the app proves the request comes from its owner
+func (service *AuthService) DisableTOTP(userId uuid.UUID, code string) error {
+	totp, err := service.twoFactorRepo.GetTOTP(userId)
+
+	if err != nil || totp.ConfirmedAt == nil {
+		return ErrTwoFactorNotEnabled
+	}
+
+	if err := service.checkTOTP(totp, code); err != nil {
+		return err
+	}
+
+	return service.twoFactorRepo.DeleteTOTP(userId)
+}
+
+// second login step, trade the challenge from the password step and a code from the app,
+// or a recovery code, for the token pair
+func (service *AuthService) VerifyLoginCode(mfaToken, code, userAgent, ipAddress string) (*model.LoginResult, error) {
+	tokenHash := utils.HashToken(mfaToken)
+	userId, err := service.twoFactorRepo.GetMfaChallengeUser(tokenHash, time.Now())
+
+	if err != nil {
+		return nil, ErrInvalidMfaToken
+	}
+
+	user, err := service.userRepo.GetUserById(userId)
+
+	if err != nil {
+		return nil, ErrUserNotExists
+	}
+
+	if user.SuspendedAt != nil {
+		return nil, ErrUserSuspended
+	}
+
+	totp, err := service.twoFactorRepo.GetTOTP(userId)
+
+	// 2FA was turned off in the meantime
+	if err != nil || totp.ConfirmedAt == nil {
+		return nil, ErrInvalidMfaToken
+	}
+
+	attempts := service.codeAttempts(userId)
+
+	if err := service.reserveAttempts(attempts); err != nil {
+		return nil, err
+	}
+
+	code = strings.TrimSpace(code)
+
+	if len(code) == 6 {
+		err = service.checkTOTP(totp, code)
+	} else {
+		err = service.useRecoveryCode(userId, code)
+	}
+
+	if err != nil {
+		// only a wrong code is a failure
+		if !errors.Is(err, ErrInvalidTwoFactorCode) {
+			service.releaseAttempts(attempts)
+		}
+
+		return nil, err
+	}
+
+	service.resetAttempts(attempts)
+
+	// a second request with the same token lost the race
+	used, err := service.twoFactorRepo.UseMfaChallenge(tokenHash)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !used {
+		return nil, ErrInvalidMfaToken
+	}
+
+	return service.startSession(user, userAgent, ipAddress)
+}
+
+// short lived token proving the password step passed, only its hash is kept and it works once
+func (service *AuthService) generateMfaToken(user *model.User) (string, error) {
+	token, err := utils.RandomHex(64)
+
+	if err != nil {
+		return "", err
+	}
+
+	if err := service.twoFactorRepo.CreateMfaChallenge(user.Id, utils.HashToken(token), time.Now().Add(mfaChallengeTTL)); err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+func (service *AuthService) hasTwoFactor(userId uuid.UUID) bool {
+	totp, err := service.twoFactorRepo.GetTOTP(userId)
+
+	return err == nil && totp.ConfirmedAt != nil
+}
+
+func (service *AuthService) checkTOTP(totp *model.UserTOTP, code string) error {
+	step, ok := utils.ValidateTOTP(totp.Secret, strings.TrimSpace(code), time.Now())
+
+	if !ok {
+		return ErrInvalidTwoFactorCode
+	}
+
+	// a code seen before, maybe by someone looking over a shoulder
+	used, err := service.twoFactorRepo.UseStep(totp.UserId, step)
+
+	if err != nil {
+		return err
+	}
+
+	if !used {
+		return ErrInvalidTwoFactorCode
+	}
+
+	return nil
+}
+
+func (service *AuthService) useRecoveryCode(userId uuid.UUID, code string) error {
+	used, err := service.twoFactorRepo.UseRecoveryCode(userId, hashRecoveryCode(code), time.Now())
+
+	if err != nil {
+		return err
+	}
+
+	if !used {
+		return ErrInvalidTwoFactorCode
+	}
+
+	return nil
+}
+
+// recovery codes are compared without dashes, spaces or case
+func hashRecoveryCode(code string) string {
+	code = strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+
+	return utils.HashToken(code)
+}
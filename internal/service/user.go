@@ -14,21 +14,26 @@ var (
 )
 
 type UserProfileService struct {
-	userRepo    *repo.UserRepository
-	profileRepo *repo.UserProfileRepository
-	blogRepo    *repo.BlogRepository
-	commentRepo *repo.CommentRepository
-	followRepo  *repo.FollowRepository
+	userRepo            *repo.UserRepository
+	profileRepo         *repo.UserProfileRepository
+	blogRepo            *repo.BlogRepository
+	commentRepo         *repo.CommentRepository
+	followRepo          *repo.FollowRepository
+	mentionService      *MentionService
+	notificationService *NotificationService
 }
 
 func NewUserProfileService(profile *repo.UserProfileRepository, user *repo.UserRepository,
-	blog *repo.BlogRepository, comment *repo.CommentRepository, followRepo *repo.FollowRepository) *UserProfileService {
+	blog *repo.BlogRepository, comment *repo.CommentRepository, followRepo *repo.FollowRepository,
+	mentionService *MentionService, notificationService *NotificationService) *UserProfileService {
 	return &UserProfileService{
-		profileRepo: profile,
-		userRepo:    user,
-		blogRepo:    blog,
-		commentRepo: comment,
-		followRepo:  followRepo,
+		profileRepo:         profile,
+		userRepo:            user,
+		blogRepo:            blog,
+		commentRepo:         comment,
+		followRepo:          followRepo,
+		mentionService:      mentionService,
+		notificationService: notificationService,
 	}
 }
 
@@ -128,7 +133,39 @@ func (p *UserProfileService) RemoveAvatar(userId uuid.UUID) error {
 	return nil
 }
 
-func (s *UserProfileService) GetAllCommentsOfBlog(username, slug string) ([]model.CommentWithStat, error) {
+// a page of the comment threads of a public blog, cursor is empty for the first page
+func (s *UserProfileService) GetAllCommentsOfBlog(viewerId uuid.UUID, username, slug, cursor string,
+	limit int) (*model.CursorResponse[model.CommentNode], error) {
+	blog, err := s.getPublicBlog(username, slug)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return loadCommentThreads(s.commentRepo, s.mentionService, viewerId, blog.Id, blog.PinnedCommentId, cursor, limit)
+}
+
+// more replies of a comment, with the cursor from its replies_cursor
+func (s *UserProfileService) GetCommentReplies(viewerId uuid.UUID, username, slug string, commentId int64, cursor string,
+	limit int) (*model.CursorResponse[model.CommentNode], error) {
+	blog, err := s.getPublicBlog(username, slug)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// replies under a hidden comment are still shown, so it doesn't have to be visible itself
+	ownership, err := s.commentRepo.GetCommentOwnership(commentId)
+
+	if err != nil || ownership.BlogId != blog.Id {
+		return nil, ErrCommentNotExists
+	}
+
+	return loadCommentReplies(s.commentRepo, s.mentionService, viewerId, commentId, cursor, limit)
+}
+
+// comments are as visible as their blog
+func (s *UserProfileService) getPublicBlog(username, slug string) (*model.BlogResponse, error) {
 	user, err := s.userRepo.GetUserByUsername(username)
 
 	if err != nil {
@@ -141,13 +178,11 @@ func (s *UserProfileService) GetAllCommentsOfBlog(username, slug string) ([]mode
 		return nil, ErrBlogNotExists
 	}
 
-	comments, err := s.commentRepo.GetCommentsByBlogId(blog.Id)
-
-	if err != nil {
-		return nil, err
+	if blog.Status != model.PUBLISHED && blog.Status != model.UNLISTED || blog.HiddenAt != nil {
+		return nil, ErrBlogNotExists
 	}
 
-	return comments, nil
+	return blog, nil
 }
 
 func (s *UserProfileService) FetchBookmarks(userId uuid.UUID) ([]model.BlogSummary, error) {
@@ -175,8 +210,13 @@ func (s *UserProfileService) CreateFollow(userId uuid.UUID, followingUsername st
 		return ErrInvalidFollowingUser
 	}
 
-	err = s.followRepo.Create(userId, followingUser.Id)
-	return err
+	if err := s.followRepo.Create(userId, followingUser.Id); err != nil {
+		return err
+	}
+
+	s.notificationService.Notify(followingUser.Id, userId, model.NOTIFY_FOLLOW, nil, nil)
+
+	return nil
 }
 
 func (s *UserProfileService) RemoveFollow(userId uuid.UUID, followingUsername string) error {
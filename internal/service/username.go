@@ -0,0 +1,91 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/harry713j/vibe_writer/internal/model"
+	"github.com/harry713j/vibe_writer/internal/utils"
+)
+
+// users can't hop between usernames to reserve a pile of them
+const usernameChangeCooldown = 30 * 24 * time.Hour
+
+var (
+	ErrUsernameHeld          = errors.New("this username was released recently and can't be taken yet")
+	ErrSameUsername          = errors.New("this is already your username")
+	ErrUsernameChangeTooSoon = errors.New("you can change your username once every 30 days")
+)
+
+// rename the user, the old username keeps redirecting to the new one and nobody else
+// can register it while it's held
+func (s *AccountService) ChangeUsername(userId uuid.UUID, username string) (*model.User, error) {
+	user, err := s.userRepo.GetUserById(userId)
+
+	if err != nil {
+		return nil, ErrUserNotExists
+	}
+
+	if username == user.Username {
+		return nil, ErrSameUsername
+	}
+
+	if err := utils.ValidateUsername(username); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	lastChange, err := s.userRepo.GetLastUsernameChange(userId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if lastChange != nil && now.Sub(*lastChange) < usernameChangeCooldown {
+		return nil, ErrUsernameChangeTooSoon
+	}
+
+	if _, err := s.userRepo.GetUserByUsername(username); err == nil {
+		return nil, ErrUsernameExists
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	held, err := s.userRepo.IsUsernameHeld(username, userId, now)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if held {
+		return nil, ErrUsernameHeld
+	}
+
+	if err := s.userRepo.ChangeUsername(userId, user.Username, username, now, now.Add(s.config.UsernameHold)); err != nil {
+		// the unique index catches a username taken between the check and the update
+		if _, lookupErr := s.userRepo.GetUserByUsername(username); lookupErr == nil {
+			return nil, ErrUsernameExists
+		}
+
+		return nil, err
+	}
+
+	user.Username = username
+	user.UpdatedAt = now
+
+	return user, nil
+}
+
+// the username that replaced a released one, ErrUserNotExists when the username was never
+// released or its hold is over
+func (s *AccountService) GetRenamedUsername(username string) (string, error) {
+	current, err := s.userRepo.GetRenamedUsername(username, time.Now())
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrUserNotExists
+	}
+
+	return current, err
+}
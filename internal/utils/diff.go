@@ -0,0 +1,132 @@
+package utils
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/harry713j/vibe_writer/internal/model"
+)
+
+// the trace keeps every round of the search, so its memory grows with the square of the edits
+const (
+	maxDiffLines = 10000
+	maxDiffEdits = 1000
+)
+
+var (
+	ErrDiffTooLarge = errors.New("the texts are too large or too different to diff")
+)
+
+// line level diff of a and b using the Myers algorithm
+func DiffLines(a, b string) ([]model.DiffLine, error) {
+	oldLines := splitLines(a)
+	newLines := splitLines(b)
+
+	if len(oldLines) > maxDiffLines || len(newLines) > maxDiffLines {
+		return nil, ErrDiffTooLarge
+	}
+
+	trace, ok := myersTrace(oldLines, newLines, maxDiffEdits)
+
+	if !ok {
+		return nil, ErrDiffTooLarge
+	}
+
+	return backtrackDiff(trace, oldLines, newLines), nil
+}
+
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	return strings.Split(text, "\n")
+}
+
+// furthest reaching paths for every edit distance until both ends meet, false when that takes
+// more than maxEdits. Round d only looks at diagonals -d-1..d+1, so only that part of v is kept for it
+func myersTrace(a, b []string, maxEdits int) ([][]int, bool) {
+	n, m := len(a), len(b)
+	maxD := min(n+m, maxEdits)
+	offset := maxD + 1
+	v := make([]int, 2*maxD+3)
+
+	var trace [][]int
+
+	for d := 0; d <= maxD; d++ {
+		snapshot := make([]int, 2*d+3)
+		copy(snapshot, v[offset-d-1:offset+d+2])
+		trace = append(trace, snapshot)
+
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+
+			v[offset+k] = x
+
+			if x >= n && y >= m {
+				return trace, true
+			}
+		}
+	}
+
+	return nil, false
+}
+
+func backtrackDiff(trace [][]int, a, b []string) []model.DiffLine {
+	x, y := len(a), len(b)
+
+	var reversed []model.DiffLine
+
+	for d := len(trace) - 1; d >= 0; d-- {
+		// the snapshot of round d starts at diagonal -d-1
+		v, offset := trace[d], d+1
+		k := x - y
+
+		var prevK int
+		if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+
+		prevX := v[offset+prevK]
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			reversed = append(reversed, model.DiffLine{Op: model.UNCHANGED, Content: a[x-1], OldLine: x, NewLine: y})
+			x--
+			y--
+		}
+
+		if d == 0 {
+			break
+		}
+
+		if x == prevX {
+			reversed = append(reversed, model.DiffLine{Op: model.ADDED, Content: b[y-1], NewLine: y})
+			y--
+		} else {
+			reversed = append(reversed, model.DiffLine{Op: model.REMOVED, Content: a[x-1], OldLine: x})
+			x--
+		}
+	}
+
+	lines := make([]model.DiffLine, 0, len(reversed))
+	for i := len(reversed) - 1; i >= 0; i-- {
+		lines = append(lines, reversed[i])
+	}
+
+	return lines
+}
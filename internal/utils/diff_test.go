@@ -0,0 +1,162 @@
+package utils
+
+import (
+	"errors"
+	"math/rand"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/harry713j/vibe_writer/internal/model"
+)
+
+func TestDiffLines(t *testing.T) {
+	tests := []struct {
+		name string
+		a    string
+		b    string
+		want []model.DiffLine
+	}{
+		{name: "both empty", a: "", b: "", want: []model.DiffLine{}},
+		{name: "all added", a: "", b: "one\ntwo", want: []model.DiffLine{
+			{Op: model.ADDED, Content: "one", NewLine: 1},
+			{Op: model.ADDED, Content: "two", NewLine: 2},
+		}},
+		{name: "all removed", a: "one\ntwo", b: "", want: []model.DiffLine{
+			{Op: model.REMOVED, Content: "one", OldLine: 1},
+			{Op: model.REMOVED, Content: "two", OldLine: 2},
+		}},
+		{name: "unchanged", a: "one\ntwo", b: "one\ntwo", want: []model.DiffLine{
+			{Op: model.UNCHANGED, Content: "one", OldLine: 1, NewLine: 1},
+			{Op: model.UNCHANGED, Content: "two", OldLine: 2, NewLine: 2},
+		}},
+		{name: "changed line", a: "one\ntwo\nthree", b: "one\n2\nthree", want: []model.DiffLine{
+			{Op: model.UNCHANGED, Content: "one", OldLine: 1, NewLine: 1},
+			{Op: model.REMOVED, Content: "two", OldLine: 2},
+			{Op: model.ADDED, Content: "2", NewLine: 2},
+			{Op: model.UNCHANGED, Content: "three", OldLine: 3, NewLine: 3},
+		}},
+		{name: "inserted in the middle", a: "one\nthree", b: "one\ntwo\nthree", want: []model.DiffLine{
+			{Op: model.UNCHANGED, Content: "one", OldLine: 1, NewLine: 1},
+			{Op: model.ADDED, Content: "two", NewLine: 2},
+			{Op: model.UNCHANGED, Content: "three", OldLine: 2, NewLine: 3},
+		}},
+		{name: "crlf line endings", a: "one\r\ntwo", b: "one\ntwo", want: []model.DiffLine{
+			{Op: model.UNCHANGED, Content: "one", OldLine: 1, NewLine: 1},
+			{Op: model.UNCHANGED, Content: "two", OldLine: 2, NewLine: 2},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DiffLines(tt.a, tt.b)
+
+			if err != nil {
+				t.Fatalf("DiffLines() error = %v", err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DiffLines() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// the diff has to rebuild both texts with as few edits as the longest common subsequence allows
+func TestDiffLinesIsMinimal(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 200; i++ {
+		a := randomLines(rng)
+		b := randomLines(rng)
+
+		lines, err := DiffLines(strings.Join(a, "\n"), strings.Join(b, "\n"))
+
+		if err != nil {
+			t.Fatalf("DiffLines() error = %v", err)
+		}
+
+		var oldLines, newLines []string
+		edits := 0
+
+		for _, line := range lines {
+			if line.Op != model.ADDED {
+				oldLines = append(oldLines, line.Content)
+			}
+
+			if line.Op != model.REMOVED {
+				newLines = append(newLines, line.Content)
+			}
+
+			if line.Op != model.UNCHANGED {
+				edits++
+			}
+		}
+
+		if strings.Join(oldLines, "\n") != strings.Join(a, "\n") || strings.Join(newLines, "\n") != strings.Join(b, "\n") {
+			t.Fatalf("diff of %q and %q does not rebuild them: %+v", a, b, lines)
+		}
+
+		if want := len(a) + len(b) - 2*lcsLength(a, b); edits != want {
+			t.Fatalf("diff of %q and %q has %d edits, want %d", a, b, edits, want)
+		}
+	}
+}
+
+func TestDiffLinesTooLarge(t *testing.T) {
+	many := func(n int, prefix string) string {
+		lines := make([]string, n)
+		for i := range lines {
+			lines[i] = prefix + strconv.Itoa(i)
+		}
+		return strings.Join(lines, "\n")
+	}
+
+	tests := []struct {
+		name    string
+		a       string
+		b       string
+		wantErr error
+	}{
+		{name: "too many lines", a: many(maxDiffLines+1, "a"), b: "", wantErr: ErrDiffTooLarge},
+		{name: "too many edits", a: many(maxDiffEdits, "a"), b: many(maxDiffEdits, "b"), wantErr: ErrDiffTooLarge},
+		{name: "long but similar", a: many(maxDiffLines, "a"), b: many(maxDiffLines-1, "a") + "\nend", wantErr: nil},
+		{name: "edits at the limit", a: many(maxDiffEdits/2, "a"), b: many(maxDiffEdits/2, "b"), wantErr: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := DiffLines(tt.a, tt.b); !errors.Is(err, tt.wantErr) {
+				t.Errorf("DiffLines() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func randomLines(rng *rand.Rand) []string {
+	lines := make([]string, rng.Intn(12))
+	for i := range lines {
+		lines[i] = string(rune('a' + rng.Intn(4)))
+	}
+	return lines
+}
+
+func lcsLength(a, b []string) int {
+	table := make([][]int, len(a)+1)
+	for i := range table {
+		table[i] = make([]int, len(b)+1)
+	}
+
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				table[i][j] = table[i+1][j+1] + 1
+			} else {
+				table[i][j] = max(table[i+1][j], table[i][j+1])
+			}
+		}
+	}
+
+	return table[0][0]
+}
@@ -0,0 +1,58 @@
+package utils
+
+import (
+	"bytes"
+	"regexp"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+)
+
+// CommonMark with GFM (tables, strikethrough, autolinks, task lists) and footnotes,
+// raw html in the source is dropped by goldmark since it isn't built with html.WithUnsafe
+var markdown = goldmark.New(
+	goldmark.WithExtensions(
+		// align attribute instead of inline style, styles don't survive sanitizing
+		extension.NewTable(extension.WithTableCellAlignMethod(extension.TableCellAlignAttribute)),
+		extension.Strikethrough,
+		extension.Linkify,
+		extension.TaskList,
+		extension.Footnote,
+	),
+)
+
+// user generated content policy, it strips scripts, event handlers, styles and
+// javascript:/data: urls, the extra rules keep what the markdown renderer emits
+var htmlPolicy = newHTMLPolicy()
+
+func newHTMLPolicy() *bluemonday.Policy {
+	policy := bluemonday.UGCPolicy()
+
+	// fenced code language, used by client side highlighters
+	policy.AllowAttrs("class").Matching(regexp.MustCompile(`^language-[\w+#-]+$`)).OnElements("code")
+
+	// footnote links and lists
+	policy.AllowAttrs("class").Matching(regexp.MustCompile(`^footnotes?(-ref|-backref)?$`)).OnElements("a", "div", "sup")
+	policy.AllowAttrs("role").Matching(regexp.MustCompile(`^doc-(noteref|backlink|endnotes)$`)).OnElements("a", "div")
+
+	// GFM task lists render disabled checkboxes
+	policy.AllowAttrs("type").Matching(regexp.MustCompile(`^checkbox$`)).OnElements("input")
+	policy.AllowAttrs("checked", "disabled").OnElements("input")
+
+	// table cell alignment
+	policy.AllowAttrs("align").Matching(regexp.MustCompile(`^(left|center|right)$`)).OnElements("th", "td")
+
+	return policy
+}
+
+// render markdown into html that is safe to inject into a page
+func RenderMarkdown(source string) (string, error) {
+	var buf bytes.Buffer
+
+	if err := markdown.Convert([]byte(source), &buf); err != nil {
+		return "", err
+	}
+
+	return htmlPolicy.Sanitize(buf.String()), nil
+}
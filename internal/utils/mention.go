@@ -0,0 +1,55 @@
+package utils
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// an @username written in some content, offsets count unicode code points
+type MentionToken struct {
+	Start    int
+	End      int
+	Username string // lower cased, usernames are
+}
+
+// the @ must not follow a word, so emails and paths aren't mentions
+var mentionPattern = regexp.MustCompile(`(?:^|[^A-Za-z0-9_.@/])(@([A-Za-z0-9]+))`)
+
+// every mention in the content in order, names that can't be usernames are left out
+func ParseMentions(content string) []MentionToken {
+	var tokens []MentionToken
+
+	// code points up to lastByte, counted as we go so long content isn't scanned again per match
+	lastByte, lastRune := 0, 0
+
+	for _, match := range mentionPattern.FindAllStringSubmatchIndex(content, -1) {
+		start, end := match[2], match[3]
+		username := strings.ToLower(content[match[4]:match[5]])
+
+		// @alice_b or @alice-b is someone else, not alice
+		if end < len(content) && isUsernameByte(content[end]) {
+			continue
+		}
+
+		if ValidateUsername(username) != nil {
+			continue
+		}
+
+		lastRune += utf8.RuneCountInString(content[lastByte:start])
+		lastByte = start
+
+		tokens = append(tokens, MentionToken{
+			Start:    lastRune,
+			End:      lastRune + utf8.RuneCountInString(content[start:end]),
+			Username: username,
+		})
+	}
+
+	return tokens
+}
+
+// bytes a name can go on with, including the ones usernames don't allow
+func isUsernameByte(b byte) bool {
+	return b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z' || b >= '0' && b <= '9' || b == '_' || b == '-'
+}
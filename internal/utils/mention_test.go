@@ -0,0 +1,50 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseMentions(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []MentionToken
+	}{
+		{name: "no mentions", content: "just some text", want: nil},
+		{name: "at the start", content: "@alice hi", want: []MentionToken{{Start: 0, End: 6, Username: "alice"}}},
+		{name: "after a space", content: "hi @alice", want: []MentionToken{{Start: 3, End: 9, Username: "alice"}}},
+		{name: "lower cased", content: "hi @Alice", want: []MentionToken{{Start: 3, End: 9, Username: "alice"}}},
+		{name: "followed by punctuation", content: "thanks @alice!", want: []MentionToken{{Start: 7, End: 13, Username: "alice"}}},
+		{name: "end of sentence", content: "ask @alice.", want: []MentionToken{{Start: 4, End: 10, Username: "alice"}}},
+		{name: "in parentheses", content: "(@alice)", want: []MentionToken{{Start: 1, End: 7, Username: "alice"}}},
+		{name: "after a newline", content: "hi\n@alice", want: []MentionToken{{Start: 3, End: 9, Username: "alice"}}},
+		{name: "several", content: "@alice and @bobby", want: []MentionToken{
+			{Start: 0, End: 6, Username: "alice"},
+			{Start: 11, End: 17, Username: "bobby"},
+		}},
+		{name: "repeated", content: "@alice @alice", want: []MentionToken{
+			{Start: 0, End: 6, Username: "alice"},
+			{Start: 7, End: 13, Username: "alice"},
+		}},
+		{name: "email", content: "mail alice@example.com", want: nil},
+		{name: "path", content: "see /users/@alice", want: nil},
+		{name: "double at", content: "@@alice", want: nil},
+		{name: "too short", content: "@bob", want: nil},
+		{name: "runs on with an underscore", content: "@alice_b", want: nil},
+		{name: "runs on with a dash", content: "@alice-b", want: nil},
+		{name: "code points not bytes", content: "héllo @alice", want: []MentionToken{{Start: 6, End: 12, Username: "alice"}}},
+		{name: "after emoji", content: "🎉 @alice 🎉 @bobby", want: []MentionToken{
+			{Start: 2, End: 8, Username: "alice"},
+			{Start: 11, End: 17, Username: "bobby"},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseMentions(tt.content); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseMentions(%q) = %+v, want %+v", tt.content, got, tt.want)
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package utils
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
 )
 
@@ -14,3 +15,10 @@ func RandomHex(size int) (string, error) {
 
 	return hex.EncodeToString(bytes), nil
 }
+
+// sha256 of a secret token, what we store instead of the token itself
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+
+	return hex.EncodeToString(sum[:])
+}
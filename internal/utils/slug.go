@@ -0,0 +1,27 @@
+package utils
+
+import (
+	"strings"
+	"unicode"
+)
+
+// lower case words joined by dashes, everything else is dropped
+func Slugify(value string) string {
+	var builder strings.Builder
+	lastDash := true
+
+	for _, ch := range strings.ToLower(value) {
+		if unicode.IsLetter(ch) || unicode.IsDigit(ch) {
+			builder.WriteRune(ch)
+			lastDash = false
+			continue
+		}
+
+		if !lastDash {
+			builder.WriteRune('-')
+			lastDash = true
+		}
+	}
+
+	return strings.TrimSuffix(builder.String(), "-")
+}